@@ -0,0 +1,258 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PrintFormat is a format of AST string representation.
+type PrintFormat int
+
+const (
+	// FormatVerbose is the multi-line format returned by Print().
+	FormatVerbose PrintFormat = iota
+
+	// FormatCompact is the single line format returned by PrintCompact().
+	FormatCompact
+
+	// FormatCompactPositions is the single line format returned by PrintCompact(), with the line and byte position of
+	// each node.
+	FormatCompactPositions
+)
+
+// compactVisitor implements the Visitor interface to print a AST on a single line.
+type compactVisitor struct {
+	buf       []string
+	positions bool
+}
+
+// PrintCompact returns a single line representation of given AST, that is easier to diff than the one returned by Print().
+//
+// Statements and expressions are printed as s-expressions, like:
+//
+//	(program (content "Hello ") (mustache (link user.name class="big")) (block (if admin) (program (content "!"))))
+//
+// Missing nodes, for example in an AST built by hand, are printed as <nil>.
+func PrintCompact(node Node) string {
+	return PrintFormatted(node, FormatCompact)
+}
+
+// PrintFormatted returns a string representation of given AST, with given format.
+//
+// With the FormatCompactPositions format, each node is followed by its [line:pos] position, except expressions and
+// hashes that are respectively positioned by their path and their first pair.
+func PrintFormatted(node Node, format PrintFormat) string {
+	if format == FormatVerbose {
+		return Print(node)
+	}
+
+	visitor := &compactVisitor{positions: format == FormatCompactPositions}
+	visitor.accept(node)
+	return strings.Join(visitor.buf, "")
+}
+
+func (v *compactVisitor) str(val string) {
+	v.buf = append(v.buf, val)
+}
+
+// head prints given opening string of node, followed by its position if needed
+func (v *compactVisitor) head(node Node, val string) {
+	v.str(val)
+
+	if v.positions {
+		loc := node.Location()
+		v.str("[" + strconv.Itoa(loc.Line) + ":" + strconv.Itoa(loc.Pos) + "]")
+	}
+}
+
+// accept visits given node, or prints it as missing
+func (v *compactVisitor) accept(node Node) {
+	if isNil(node) {
+		v.str(nilNode)
+	} else {
+		node.Accept(v)
+	}
+}
+
+// args prints given nodes, each one prefixed by a space
+func (v *compactVisitor) args(nodes []Node) {
+	for _, n := range nodes {
+		v.str(" ")
+		v.accept(n)
+	}
+}
+
+// hash prints given hash prefixed by a space, if not empty
+func (v *compactVisitor) hash(node *Hash) {
+	if (node != nil) && (len(node.Pairs) > 0) {
+		v.str(" ")
+		node.Accept(v)
+	}
+}
+
+//
+// Visitor interface
+//
+
+// Statements
+
+// VisitProgram implements corresponding Visitor interface method
+func (v *compactVisitor) VisitProgram(node *Program) interface{} {
+	v.head(node, "(program")
+
+	if len(node.BlockParams) > 0 {
+		v.str(" |" + strings.Join(node.BlockParams, " ") + "|")
+	}
+
+	v.args(node.Body)
+	v.str(")")
+
+	return nil
+}
+
+// VisitMustache implements corresponding Visitor interface method
+func (v *compactVisitor) VisitMustache(node *MustacheStatement) interface{} {
+	if node.Unescaped {
+		v.head(node, "(mustache&")
+	} else {
+		v.head(node, "(mustache")
+	}
+
+	v.str(" ")
+	v.accept(node.Expression)
+	v.str(")")
+
+	return nil
+}
+
+// VisitBlock implements corresponding Visitor interface method
+func (v *compactVisitor) VisitBlock(node *BlockStatement) interface{} {
+	v.head(node, "(block")
+	v.str(" ")
+	v.accept(node.Expression)
+
+	if node.Program != nil {
+		v.str(" ")
+		node.Program.Accept(v)
+	}
+
+	if node.Inverse != nil {
+		v.str(" (else ")
+		node.Inverse.Accept(v)
+		v.str(")")
+	}
+
+	v.str(")")
+
+	return nil
+}
+
+// VisitPartial implements corresponding Visitor interface method
+func (v *compactVisitor) VisitPartial(node *PartialStatement) interface{} {
+	v.head(node, "(partial")
+	v.str(" ")
+	v.accept(node.Name)
+	v.args(node.Params)
+	v.hash(node.Hash)
+	v.str(")")
+
+	return nil
+}
+
+// VisitContent implements corresponding Visitor interface method
+func (v *compactVisitor) VisitContent(node *ContentStatement) interface{} {
+	v.head(node, "(content")
+	v.str(" " + strconv.Quote(node.Value) + ")")
+
+	return nil
+}
+
+// VisitComment implements corresponding Visitor interface method
+func (v *compactVisitor) VisitComment(node *CommentStatement) interface{} {
+	v.head(node, "(comment")
+	v.str(" " + strconv.Quote(node.Value) + ")")
+
+	return nil
+}
+
+// Expressions
+
+// VisitExpression implements corresponding Visitor interface method
+func (v *compactVisitor) VisitExpression(node *Expression) interface{} {
+	v.str("(")
+	v.accept(node.Path)
+	v.args(node.Params)
+	v.hash(node.Hash)
+	v.str(")")
+
+	return nil
+}
+
+// VisitSubExpression implements corresponding Visitor interface method
+func (v *compactVisitor) VisitSubExpression(node *SubExpression) interface{} {
+	v.accept(node.Expression)
+
+	return nil
+}
+
+// VisitPath implements corresponding Visitor interface method
+func (v *compactVisitor) VisitPath(node *PathExpression) interface{} {
+	path := node.Original
+	if path == "" {
+		// AST built by hand
+		path = strings.Join(node.Parts, ".")
+		if node.Data {
+			path = "@" + path
+		}
+	}
+
+	v.head(node, path)
+
+	return nil
+}
+
+// Literals
+
+// VisitString implements corresponding Visitor interface method
+func (v *compactVisitor) VisitString(node *StringLiteral) interface{} {
+	v.head(node, strconv.Quote(node.Value))
+
+	return nil
+}
+
+// VisitBoolean implements corresponding Visitor interface method
+func (v *compactVisitor) VisitBoolean(node *BooleanLiteral) interface{} {
+	v.head(node, node.Canonical())
+
+	return nil
+}
+
+// VisitNumber implements corresponding Visitor interface method
+func (v *compactVisitor) VisitNumber(node *NumberLiteral) interface{} {
+	v.head(node, node.Canonical())
+
+	return nil
+}
+
+// Miscellaneous
+
+// VisitHash implements corresponding Visitor interface method
+func (v *compactVisitor) VisitHash(node *Hash) interface{} {
+	for i, p := range node.Pairs {
+		if i > 0 {
+			v.str(" ")
+		}
+		v.accept(p)
+	}
+
+	return nil
+}
+
+// VisitHashPair implements corresponding Visitor interface method
+func (v *compactVisitor) VisitHashPair(node *HashPair) interface{} {
+	v.head(node, node.Key)
+	v.str("=")
+	v.accept(node.Val)
+
+	return nil
+}
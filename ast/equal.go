@@ -0,0 +1,129 @@
+package ast
+
+// Equal returns true if given nodes are structurally equal.
+//
+// Node types, paths, literals, parameters, hashes, block parameters and nested programs are compared. Positions and
+// whitespace management data are ignored, but the content left by whitespace control is compared.
+func Equal(a, b Node) bool {
+	if (a == nil) || (b == nil) {
+		return (a == nil) && (b == nil)
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Program:
+		return equalProgram(a, b.(*Program))
+	case *MustacheStatement:
+		b := b.(*MustacheStatement)
+		return (a.Unescaped == b.Unescaped) && equalExpression(a.Expression, b.Expression)
+	case *BlockStatement:
+		b := b.(*BlockStatement)
+		return equalExpression(a.Expression, b.Expression) &&
+			equalProgram(a.Program, b.Program) &&
+			equalProgram(a.Inverse, b.Inverse)
+	case *PartialStatement:
+		b := b.(*PartialStatement)
+		return Equal(a.Name, b.Name) &&
+			equalNodes(a.Params, b.Params) &&
+			equalHash(a.Hash, b.Hash) &&
+			(a.Indent == b.Indent)
+	case *ContentStatement:
+		return a.Value == b.(*ContentStatement).Value
+	case *CommentStatement:
+		return a.Value == b.(*CommentStatement).Value
+	case *Expression:
+		return equalExpression(a, b.(*Expression))
+	case *SubExpression:
+		return equalExpression(a.Expression, b.(*SubExpression).Expression)
+	case *PathExpression:
+		b := b.(*PathExpression)
+		return (a.Data == b.Data) &&
+			(a.Depth == b.Depth) &&
+			(a.Scoped == b.Scoped) &&
+			equalStrings(a.Parts, b.Parts)
+	case *StringLiteral:
+		return a.Value == b.(*StringLiteral).Value
+	case *BooleanLiteral:
+		return a.Value == b.(*BooleanLiteral).Value
+	case *NumberLiteral:
+		b := b.(*NumberLiteral)
+		return (a.Value == b.Value) && (a.IsInt == b.IsInt) && (a.Int == b.Int)
+	case *Hash:
+		return equalHash(a, b.(*Hash))
+	case *HashPair:
+		b := b.(*HashPair)
+		return (a.Key == b.Key) && Equal(a.Val, b.Val)
+	}
+
+	return false
+}
+
+// equalProgram returns true if given programs are structurally equal
+func equalProgram(a, b *Program) bool {
+	if (a == nil) || (b == nil) {
+		return a == b
+	}
+
+	return equalStrings(a.BlockParams, b.BlockParams) && equalNodes(a.Body, b.Body)
+}
+
+// equalExpression returns true if given expressions are structurally equal
+func equalExpression(a, b *Expression) bool {
+	if (a == nil) || (b == nil) {
+		return a == b
+	}
+
+	return Equal(a.Path, b.Path) && equalNodes(a.Params, b.Params) && equalHash(a.Hash, b.Hash)
+}
+
+// equalHash returns true if given hashes are structurally equal
+func equalHash(a, b *Hash) bool {
+	if (a == nil) || (b == nil) {
+		return a == b
+	}
+
+	if len(a.Pairs) != len(b.Pairs) {
+		return false
+	}
+
+	for i, pair := range a.Pairs {
+		if !Equal(pair, b.Pairs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalNodes returns true if given node lists are structurally equal
+func equalNodes(a, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, node := range a {
+		if !Equal(node, b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalStrings returns true if given string lists are equal
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, str := range a {
+		if str != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
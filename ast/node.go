@@ -671,12 +671,13 @@ type NumberLiteral struct {
 
 	Value    float64
 	IsInt    bool
+	Int      int64 // exact value of an integer
 	Original string
 }
 
 // NewNumberLiteral instanciates a new number node.
 func NewNumberLiteral(pos int, line int, val float64, isInt bool, original string) *NumberLiteral {
-	return &NumberLiteral{
+	result := &NumberLiteral{
 		NodeType: NodeNumber,
 		Loc:      Loc{pos, line},
 
@@ -684,6 +685,26 @@ func NewNumberLiteral(pos int, line int, val float64, isInt bool, original strin
 		IsInt:    isInt,
 		Original: original,
 	}
+
+	if isInt {
+		result.Int = int64(val)
+	}
+
+	return result
+}
+
+// NewIntegerLiteral instanciates a new number node for an integer, that keeps its exact value even if it can't be
+// represented exactly as a float64.
+func NewIntegerLiteral(pos int, line int, val int64, original string) *NumberLiteral {
+	return &NumberLiteral{
+		NodeType: NodeNumber,
+		Loc:      Loc{pos, line},
+
+		Value:    float64(val),
+		IsInt:    true,
+		Int:      val,
+		Original: original,
+	}
 }
 
 // String returns a string representation of receiver that can be used for debugging.
@@ -698,17 +719,22 @@ func (node *NumberLiteral) Accept(visitor Visitor) interface{} {
 
 // Canonical returns the canonical form of number node as a string (eg: "12", "-1.51").
 func (node *NumberLiteral) Canonical() string {
-	prec := -1
 	if node.IsInt {
-		prec = 0
+		return strconv.FormatInt(node.Int, 10)
 	}
-	return strconv.FormatFloat(node.Value, 'f', prec, 64)
+	return strconv.FormatFloat(node.Value, 'f', -1, 64)
 }
 
 // Number returns an integer or a float.
+//
+// Integers that overflow an int, on 32-bit platforms, are returned as an int64.
 func (node *NumberLiteral) Number() interface{} {
 	if node.IsInt {
-		return int(node.Value)
+		if int64(int(node.Int)) == node.Int {
+			return int(node.Int)
+		}
+
+		return node.Int
 	}
 
 	return node.Value
@@ -2,9 +2,13 @@ package ast
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 )
 
+// nilNode is the string representation of a missing node
+const nilNode = "<nil>"
+
 // printVisitor implements the Visitor interface to print a AST.
 type printVisitor struct {
 	buf   string
@@ -19,12 +23,24 @@ func newPrintVisitor() *printVisitor {
 }
 
 // Print returns a string representation of given AST, that can be used for debugging purpose.
+//
+// Missing nodes, for example in an AST built by hand, are printed as <nil>.
 func Print(node Node) string {
 	visitor := newPrintVisitor()
-	node.Accept(visitor)
+	visitor.accept(node)
 	return visitor.output()
 }
 
+// isNil returns true if given node is missing
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	val := reflect.ValueOf(node)
+	return (val.Kind() == reflect.Ptr) && val.IsNil()
+}
+
 func (v *printVisitor) output() string {
 	return v.buf
 }
@@ -50,6 +66,15 @@ func (v *printVisitor) line(val string) {
 	v.nl()
 }
 
+// accept visits given node, or prints it as missing
+func (v *printVisitor) accept(node Node) {
+	if isNil(node) {
+		v.str(nilNode)
+	} else {
+		node.Accept(v)
+	}
+}
+
 //
 // Visitor interface
 //
@@ -63,7 +88,11 @@ func (v *printVisitor) VisitProgram(node *Program) interface{} {
 	}
 
 	for _, n := range node.Body {
-		n.Accept(v)
+		if isNil(n) {
+			v.line(nilNode)
+		} else {
+			n.Accept(v)
+		}
 	}
 
 	return nil
@@ -74,7 +103,7 @@ func (v *printVisitor) VisitMustache(node *MustacheStatement) interface{} {
 	v.indent()
 	v.str("{{ ")
 
-	node.Expression.Accept(v)
+	v.accept(node.Expression)
 
 	v.str(" }}")
 	v.nl()
@@ -89,7 +118,11 @@ func (v *printVisitor) VisitBlock(node *BlockStatement) interface{} {
 	v.line("BLOCK:")
 	v.depth++
 
-	node.Expression.Accept(v)
+	if node.Expression == nil {
+		v.line(nilNode)
+	} else {
+		node.Expression.Accept(v)
+	}
 
 	if node.Program != nil {
 		v.line("PROGRAM:")
@@ -124,12 +157,12 @@ func (v *printVisitor) VisitPartial(node *PartialStatement) interface{} {
 	v.str("{{> PARTIAL:")
 
 	v.original = true
-	node.Name.Accept(v)
+	v.accept(node.Name)
 	v.original = false
 
 	if len(node.Params) > 0 {
 		v.str(" ")
-		node.Params[0].Accept(v)
+		v.accept(node.Params[0])
 	}
 
 	// hash
@@ -167,7 +200,7 @@ func (v *printVisitor) VisitExpression(node *Expression) interface{} {
 	}
 
 	// path
-	node.Path.Accept(v)
+	v.accept(node.Path)
 
 	// params
 	v.str(" [")
@@ -175,7 +208,7 @@ func (v *printVisitor) VisitExpression(node *Expression) interface{} {
 		if i > 0 {
 			v.str(", ")
 		}
-		n.Accept(v)
+		v.accept(n)
 	}
 	v.str("]")
 
@@ -194,7 +227,7 @@ func (v *printVisitor) VisitExpression(node *Expression) interface{} {
 
 // VisitSubExpression implements corresponding Visitor interface method
 func (v *printVisitor) VisitSubExpression(node *SubExpression) interface{} {
-	node.Expression.Accept(v)
+	v.accept(node.Expression)
 
 	return nil
 }
@@ -262,7 +295,7 @@ func (v *printVisitor) VisitHash(node *Hash) interface{} {
 		if i > 0 {
 			v.str(", ")
 		}
-		p.Accept(v)
+		v.accept(p)
 	}
 
 	v.str("}")
@@ -273,7 +306,7 @@ func (v *printVisitor) VisitHash(node *Hash) interface{} {
 // VisitHashPair implements corresponding Visitor interface method
 func (v *printVisitor) VisitHashPair(node *HashPair) interface{} {
 	v.str(node.Key + "=")
-	node.Val.Accept(v)
+	v.accept(node.Val)
 
 	return nil
 }
@@ -0,0 +1,101 @@
+package ast
+
+import "testing"
+
+type printTest struct {
+	name     string
+	node     Node
+	verbose  string
+	compact  string
+	position string
+}
+
+func path(original string, parts ...string) *PathExpression {
+	return &PathExpression{Loc: Loc{Pos: 2, Line: 1}, Original: original, Parts: parts}
+}
+
+var printTests = []printTest{
+	{
+		"mustache",
+		&Program{Body: []Node{
+			&ContentStatement{Loc: Loc{Pos: 0, Line: 1}, Value: "Hi "},
+			&MustacheStatement{Loc: Loc{Pos: 3, Line: 1}, Expression: &Expression{
+				Path:   path("link", "link"),
+				Params: []Node{&StringLiteral{Loc: Loc{Pos: 10, Line: 1}, Value: "a"}},
+				Hash: &Hash{Pairs: []*HashPair{
+					{Loc: Loc{Pos: 14, Line: 1}, Key: "n", Val: &NumberLiteral{Loc: Loc{Pos: 16, Line: 1}, Value: 1, IsInt: true, Int: 1, Original: "1"}},
+				}},
+			}},
+		}},
+		"CONTENT[ 'Hi ' ]\n{{ PATH:link [\"a\"] HASH{n=NUMBER{1}} }}\n",
+		`(program (content "Hi ") (mustache (link "a" n=1)))`,
+		`(program[0:0] (content[1:0] "Hi ") (mustache[1:3] (link[1:2] "a"[1:10] n[1:14]=1[1:16])))`,
+	},
+	{
+		"unescaped mustache with sub-expression",
+		&MustacheStatement{Unescaped: true, Expression: &Expression{
+			Path:   path("", "foo", "bar"),
+			Params: []Node{&SubExpression{Expression: &Expression{Path: path("@index", "index"), Params: []Node{&BooleanLiteral{Value: true, Original: "true"}}}}},
+		}},
+		"{{ PATH:foo/bar [PATH:index [BOOLEAN{true}]] }}\n",
+		`(mustache& (foo.bar (@index true)))`,
+		`(mustache&[0:0] (foo.bar[1:2] (@index[1:2] true[0:0])))`,
+	},
+	{
+		"block with inverse",
+		&BlockStatement{
+			Expression: &Expression{Path: path("each", "each"), Params: []Node{path("items", "items")}},
+			Program:    &Program{BlockParams: []string{"item", "index"}, Body: []Node{&CommentStatement{Value: "c"}}},
+			Inverse:    &Program{},
+		},
+		"BLOCK:\n  PATH:each [PATH:items]\n  PROGRAM:\n    BLOCK PARAMS: [ item index ]\n    {{! 'c' }}\n  {{^}}\n",
+		`(block (each items) (program |item index| (comment "c")) (else (program)))`,
+		`(block[0:0] (each[1:2] items[1:2]) (program[0:0] |item index| (comment[0:0] "c")) (else (program[0:0])))`,
+	},
+	{
+		"partial",
+		&PartialStatement{Name: path("footer", "footer"), Params: []Node{path("user", "user")}, Hash: &Hash{Pairs: []*HashPair{{Key: "a", Val: path("b", "b")}}}},
+		"{{> PARTIAL:footer PATH:user HASH{a=PATH:b} }}\n",
+		`(partial footer user a=b)`,
+		`(partial[0:0] footer[1:2] user[1:2] a[0:0]=b[1:2])`,
+	},
+	{
+		"missing children",
+		&Program{Body: []Node{
+			nil,
+			&MustacheStatement{},
+			&MustacheStatement{Expression: &Expression{Params: []Node{nil}, Hash: &Hash{Pairs: []*HashPair{nil, {Key: "a"}}}}},
+			&BlockStatement{},
+			&PartialStatement{Params: []Node{(*PathExpression)(nil)}},
+			&MustacheStatement{Expression: &Expression{Path: &SubExpression{}}},
+		}},
+		"<nil>\n{{ <nil> }}\n{{ <nil> [<nil>] HASH{<nil>, a=<nil>} }}\nBLOCK:\n  <nil>\n  {{> PARTIAL:<nil> <nil> }}\n  {{ <nil> [] }}\n",
+		`(program <nil> (mustache <nil>) (mustache (<nil> <nil> <nil> a=<nil>)) (block <nil>) (partial <nil> <nil>) (mustache (<nil>)))`,
+		`(program[0:0] <nil> (mustache[0:0] <nil>) (mustache[0:0] (<nil> <nil> <nil> a[0:0]=<nil>)) (block[0:0] <nil>) (partial[0:0] <nil> <nil>) (mustache[0:0] (<nil>)))`,
+	},
+	{
+		"nil node",
+		nil,
+		"<nil>",
+		"<nil>",
+		"<nil>",
+	},
+}
+
+func TestPrint(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range printTests {
+		if output := Print(test.node); output != test.verbose {
+			t.Errorf("Test '%s' failed with verbose format\nexpected\n\t%q\ngot\n\t%q", test.name, test.verbose, output)
+		}
+
+		if output := PrintCompact(test.node); output != test.compact {
+			t.Errorf("Test '%s' failed with compact format\nexpected\n\t%q\ngot\n\t%q", test.name, test.compact, output)
+		}
+
+		if output := PrintFormatted(test.node, FormatCompactPositions); output != test.position {
+			t.Errorf("Test '%s' failed with compact format and positions\nexpected\n\t%q\ngot\n\t%q", test.name, test.position, output)
+		}
+	}
+}
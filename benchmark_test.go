@@ -1,6 +1,9 @@
 package raymond
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 //
 // Those tests come from:
@@ -10,7 +13,7 @@ import "testing"
 //
 
 func BenchmarkArguments(b *testing.B) {
-	source := `{{foo person "person" 1 true foo=bar foo="person" foo=1 foo=true}}`
+	source := `{{foo person "person" 1 true foo=bar bar="person" baz=1 qux=true}}`
 
 	ctx := map[string]bool{
 		"bar": true,
@@ -100,6 +103,41 @@ func BenchmarkComplex(b *testing.B) {
 	}
 }
 
+func BenchmarkComplexCompiled(b *testing.B) {
+	source := `<h1>{{header}}</h1>
+{{#if items}}
+  <ul>
+    {{#each items}}
+      {{#if current}}
+        <li><strong>{{name}}</strong></li>
+      {{^}}
+        <li><a href="{{url}}">{{name}}</a></li>
+      {{/if}}
+    {{/each}}
+  </ul>
+{{^}}
+  <p>The list is empty.</p>
+{{/if}}
+`
+
+	ctx := map[string]interface{}{
+		"header":   func() string { return "Colors" },
+		"hasItems": true,
+		"items": []map[string]interface{}{
+			{"name": "red", "current": true, "url": "#Red"},
+			{"name": "green", "current": false, "url": "#Green"},
+			{"name": "blue", "current": false, "url": "#Blue"},
+		},
+	}
+
+	tpl := MustParse(source).MustCompile()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
 func BenchmarkData(b *testing.B) {
 	source := `{{#each names}}{{@index}}{{name}}{{/each}}`
 
@@ -314,3 +352,265 @@ func BenchmarkVariables(b *testing.B) {
 		tpl.MustExec(ctx)
 	}
 }
+
+func BenchmarkExecParallel(b *testing.B) {
+	source := `<div class="entry">
+  <h1>{{title}}</h1>
+  {{#each comments}}
+  <div class="comment">{{#if author}}{{author}}{{else}}Anonymous{{/if}}: {{body}}</div>
+  {{/each}}
+</div>`
+
+	ctx := map[string]interface{}{
+		"title": "My New Post",
+		"comments": []map[string]string{
+			{"author": "Moe", "body": "Nyuk"},
+			{"author": "Larry", "body": "Nyuk nyuk"},
+			{"body": "Nyuk nyuk nyuk"},
+		},
+	}
+
+	tpl := MustParse(source)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tpl.MustExec(ctx)
+		}
+	})
+}
+
+type benchRecord struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Email     string
+	Company   string
+	City      string
+	Country   string
+	Phone     string
+	Age       int
+	Score     float64
+	Active    bool
+	Notes     string `handlebars:"remarks"`
+}
+
+func BenchmarkStructFields(b *testing.B) {
+	source := `{{#each records}}{{id}} {{firstName}} {{lastName}} {{email}} {{company}} {{city}} {{country}} {{phone}} {{age}} {{score}} {{#if active}}active{{/if}} {{remarks}}
+{{/each}}`
+
+	records := make([]benchRecord, 10000)
+	for i := range records {
+		records[i] = benchRecord{
+			ID:        i,
+			FirstName: "Jean",
+			LastName:  "Valjean",
+			Email:     "jean@valjean.fr",
+			Company:   "Montreuil",
+			City:      "Paris",
+			Country:   "France",
+			Phone:     "24601",
+			Age:       42,
+			Score:     3.14,
+			Active:    i%2 == 0,
+			Notes:     "none",
+		}
+	}
+
+	ctx := map[string]interface{}{"records": records}
+
+	tpl := MustParse(source)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkMapFields(b *testing.B) {
+	source := `{{#each records}}{{id}} {{firstName}} {{lastName}} {{email}} {{company}} {{city}} {{country}} {{phone}} {{age}} {{score}} {{#if active}}active{{/if}} {{remarks}}
+{{/each}}`
+
+	records := make([]map[string]interface{}, 10000)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"id":        i,
+			"firstName": "Jean",
+			"lastName":  "Valjean",
+			"email":     "jean@valjean.fr",
+			"company":   "Montreuil",
+			"city":      "Paris",
+			"country":   "France",
+			"phone":     "24601",
+			"age":       42,
+			"score":     3.14,
+			"active":    i%2 == 0,
+			"remarks":   "none",
+		}
+	}
+
+	ctx := map[string]interface{}{"records": records}
+
+	tpl := MustParse(source)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkSharedPartials(b *testing.B) {
+	partials := map[string]string{
+		"header":  `<header><h1>{{title}}</h1>{{#if subtitle}}<h2>{{subtitle}}</h2>{{/if}}</header>`,
+		"nav":     `<nav>{{#each links}}<a href="{{url}}">{{name}}</a>{{/each}}</nav>`,
+		"sidebar": `<aside>{{#with author}}{{firstName}} {{lastName}}{{/with}}</aside>`,
+		"footer":  `<footer>{{#if copyright}}&copy; {{copyright}}{{else}}No copyright{{/if}}</footer>`,
+		"meta":    `<meta name="description" content="{{description}}">`,
+	}
+
+	source := `{{> meta}}{{> header}}{{> nav}}<main>{{body}}</main>{{> sidebar}}{{> footer}}`
+
+	ctx := map[string]interface{}{
+		"title": "Title",
+		"links": []map[string]string{{"url": "/", "name": "Home"}},
+		"body":  "Body",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ClearPartialCache()
+
+		for j := 0; j < 500; j++ {
+			tpl := MustParse(source)
+			tpl.RegisterPartials(partials)
+			tpl.MustExec(ctx)
+		}
+	}
+}
+
+func BenchmarkEscapeClean(b *testing.B) {
+	tpl := MustParse(`{{id}} {{name}} {{count}}`)
+
+	ctx := map[string]interface{}{
+		"id":    "a8f5f167f44f4964e6c998dee827110c",
+		"name":  "Jean Valjean",
+		"count": 24601,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkEscapeDirty(b *testing.B) {
+	tpl := MustParse(`{{title}} {{quote}}`)
+
+	ctx := map[string]interface{}{
+		"title": `Tom & Jerry <3`,
+		"quote": `"It's a trap!" said <b>Ackbar</b>`,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkEscapeLongContent(b *testing.B) {
+	content := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 200)
+
+	tpl := MustParse(`<article>{{content}}</article><article>{{content}} &amp; {{more}}</article>`)
+
+	ctx := map[string]interface{}{
+		"content": content,
+		"more":    content + "<end>",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkStaticContent(b *testing.B) {
+	tpl := MustParse(staticTemplate(1024 * 1024))
+
+	ctx := map[string]string{"title": "foo"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkContentOnly(b *testing.B) {
+	source := strings.Repeat("<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>\n", 16*1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		MustParse(source).MustExec(nil)
+	}
+}
+
+func BenchmarkHelperRows(b *testing.B) {
+	source := `{{#each rows}}<tr><td>{{upper name}}</td><td>{{id}}</td></tr>{{/each}}`
+
+	rows := make([]map[string]interface{}, 10000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "row"}
+	}
+
+	ctx := map[string]interface{}{"rows": rows}
+
+	tpl := MustParse(source)
+	tpl.RegisterHelper("upper", func(str string) string {
+		return strings.ToUpper(str)
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
+
+func BenchmarkLargePage(b *testing.B) {
+	source := `<table>{{#each rows}}<tr><td>{{id}}</td><td>{{name}}</td><td>{{email}}</td></tr>
+{{/each}}</table>`
+
+	rows := make([]map[string]interface{}, 5000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "Jean Valjean", "email": "jean@valjean.fr"}
+	}
+
+	ctx := map[string]interface{}{"rows": rows}
+
+	tpl := MustParse(source)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tpl.MustExec(ctx)
+	}
+}
@@ -0,0 +1,251 @@
+package raymond
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// compiledStatement evaluates a statement and writes its result to given buffer
+type compiledStatement func(v *evalVisitor, buf *bytes.Buffer)
+
+// compiledProgram is a program lowered to a list of compiled statements
+type compiledProgram []compiledStatement
+
+// compiledPrograms stores all compiled programs of a template, so that nested programs evaluated by helpers are compiled too
+type compiledPrograms map[*ast.Program]compiledProgram
+
+// compile lowers given program, and all its nested programs, to compiled programs
+func compile(program *ast.Program) compiledPrograms {
+	result := make(compiledPrograms)
+
+	result.compileProgram(program)
+
+	return result
+}
+
+// exec evaluates all statements of that program
+func (prog compiledProgram) exec(v *evalVisitor, buf *bytes.Buffer) {
+	for _, stmt := range prog {
+		stmt(v, buf)
+	}
+}
+
+// compileProgram compiles given program
+func (progs compiledPrograms) compileProgram(node *ast.Program) {
+	if node == nil {
+		return
+	}
+
+	prog := make(compiledProgram, 0, len(node.Body))
+
+	for _, n := range node.Body {
+		if stmt := progs.compileStatement(n); stmt != nil {
+			prog = append(prog, stmt)
+		}
+	}
+
+	progs[node] = prog
+}
+
+// compileStatement compiles given statement, or returns nil if that statement outputs nothing
+func (progs compiledPrograms) compileStatement(node ast.Node) compiledStatement {
+	switch node := node.(type) {
+	case *ast.ContentStatement:
+		return compileContent(node)
+	case *ast.CommentStatement:
+		return nil
+	case *ast.MustacheStatement:
+		return compileMustache(node)
+	case *ast.BlockStatement:
+		progs.compileProgram(node.Program)
+		progs.compileProgram(node.Inverse)
+
+		return progs.compileBlock(node)
+	}
+
+	// partials are evaluated by the interpreter
+	return compileNode(node)
+}
+
+// compileNode returns a statement that evaluates given node with the interpreter
+func compileNode(node ast.Node) compiledStatement {
+	return func(v *evalVisitor, buf *bytes.Buffer) {
+		if str := v.str(node.Accept(v)); str != "" {
+			writeString(v, buf, str)
+		}
+	}
+}
+
+// compileContent compiles a content statement
+func compileContent(node *ast.ContentStatement) compiledStatement {
+	if node.Value == "" {
+		return nil
+	}
+
+	return func(v *evalVisitor, buf *bytes.Buffer) {
+		writeString(v, buf, node.Value)
+	}
+}
+
+// compileMustache compiles a mustache statement
+//
+// A mustache with a single path, like `{{foo.bar}}`, is resolved without the expression evaluation machinery unless a
+// helper with that name exists at execution time, or unless evaluation is traced or intercepted.
+func compileMustache(node *ast.MustacheStatement) compiledStatement {
+	expr := node.Expression
+
+	path := expr.FieldPath()
+	if (path == nil) || (len(expr.Params) > 0) || (expr.Hash != nil) {
+		return func(v *evalVisitor, buf *bytes.Buffer) {
+			v.writeMustache(buf, node)
+		}
+	}
+
+	return func(v *evalVisitor, buf *bytes.Buffer) {
+		v.at(expr)
+
+		if (v.trace != nil) || (v.interceptor != nil) || (v.exprHelper(expr) != zero) {
+			v.writeMustache(buf, node)
+			return
+		}
+
+		v.pushExpr(expr)
+		val := v.evalPathExpression(path, true)
+		v.popExpr()
+
+		v.writeValue(buf, v.missingValue(node, val), node.Unescaped)
+	}
+}
+
+// compiledBlock is a compiled block statement
+type compiledBlock struct {
+	node    *ast.BlockStatement
+	builtin reflect.Value
+}
+
+// bufferedHelpers stores builtin block helpers that return the output of their block unchanged, so that a compiled
+// block lets them execute that block directly in the output buffer
+var bufferedHelpers = map[string]reflect.Value{
+	"if":     reflect.ValueOf(ifHelper),
+	"unless": reflect.ValueOf(unlessHelper),
+	"with":   reflect.ValueOf(withHelper),
+	"each":   reflect.ValueOf(eachHelper),
+}
+
+// compileBlock compiles a block statement
+//
+// Block programs are executed directly in the output buffer, instead of being evaluated to intermediate strings.
+func (progs compiledPrograms) compileBlock(node *ast.BlockStatement) compiledStatement {
+	block := &compiledBlock{node: node}
+
+	if len(node.Expression.Params) == 1 {
+		block.builtin = bufferedHelpers[node.Expression.HelperName()]
+	}
+
+	return block.exec
+}
+
+// exec evaluates that block
+func (block *compiledBlock) exec(v *evalVisitor, buf *bytes.Buffer) {
+	node := block.node
+
+	if v.interceptor != nil {
+		// intercepted blocks are evaluated by the interpreter
+		writeString(v, buf, v.str(v.VisitBlock(node)))
+		return
+	}
+
+	v.at(node)
+
+	if v.trace != nil {
+		defer v.traceExit(v.traceEnter(node, node.Expression.Canonical()))
+	}
+
+	v.pushBlock(node)
+
+	if block.builtinHelper(v) {
+		// the helper executes its block in output buffer
+		v.pushExpr(node.Expression)
+		writeString(v, buf, v.str(v.callHelper(node.Expression.HelperName(), block.builtin, node.Expression, buf)))
+		v.popExpr()
+	} else {
+		// evaluate expression
+		expr := node.Expression.Accept(v)
+
+		if v.wasFuncCall(node.Expression) {
+			// it is the responsibility of the helper/function to evaluate block
+			writeString(v, buf, v.str(expr))
+		} else {
+			val := reflect.ValueOf(expr)
+
+			truth, _ := isTrueValue(val)
+			if truth {
+				switch kindOf(val) {
+				case reflect.Array, reflect.Slice:
+					v.checkIterations(val.Len())
+
+					// Array context
+					for i := 0; i < val.Len(); i++ {
+						// Computes new private data frame
+						frame := v.dataFrame.newIterDataFrame(val.Len(), i, nil)
+
+						// Execute program
+						v.execProgram(buf, node.Program, val.Index(i).Interface(), frame, i, nil)
+					}
+				default:
+					// NOT array
+					v.execProgram(buf, node.Program, expr, nil, nil, nil)
+				}
+			} else {
+				v.execInverse(buf, node.Inverse)
+			}
+		}
+	}
+
+	v.popBlock()
+}
+
+// builtinHelper returns true if the block expression calls a builtin helper that executes its block in output buffer
+func (block *compiledBlock) builtinHelper(v *evalVisitor) bool {
+	if !block.builtin.IsValid() || !v.helperFirst(block.node.Expression) {
+		return false
+	}
+
+	helper := v.exprHelper(block.node.Expression)
+
+	return (helper != zero) && (helper.Pointer() == block.builtin.Pointer())
+}
+
+// execProgram executes given compiled block program in given buffer, with given context, private data frame, key and
+// block params values, as evalProgram() does
+func (v *evalVisitor) execProgram(buf *bytes.Buffer, program *ast.Program, ctx interface{}, data *DataFrame, key interface{}, params []interface{}) {
+	if program == nil {
+		return
+	}
+
+	scope := v.enterProgram(program, ctx, data, key, params)
+
+	v.at(program)
+	v.compiled[program].exec(v, buf)
+
+	v.leaveProgram(scope)
+}
+
+// execInverse executes given compiled "else block" in given buffer, as Options.Inverse() does
+func (v *evalVisitor) execInverse(buf *bytes.Buffer, inverse *ast.Program) {
+	if inverse == nil {
+		return
+	}
+
+	v.at(inverse)
+	v.compiled[inverse].exec(v, buf)
+}
+
+// writeString writes given string to buffer
+func writeString(v *evalVisitor, buf *bytes.Buffer, str string) {
+	if _, err := buf.WriteString(str); err != nil {
+		v.errPanic(err)
+	}
+}
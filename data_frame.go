@@ -1,49 +1,116 @@
 package raymond
 
-import "reflect"
+import (
+	"reflect"
+	"sort"
+)
 
 // DataFrame represents a private data frame.
 //
 // Cf. private variables documentation at: http://handlebarsjs.com/block_helpers.html
+//
+// A data frame only stores its own values, and looks up its parents for values it does not have.
 type DataFrame struct {
 	parent *DataFrame
 	data   map[string]interface{}
+
+	// iteration data (@index, @key, @first, @last), stored without a map
+	iter  bool
+	index int
+	key   interface{}
+	first bool
+	last  bool
+
+	// @last is unknown when iterating over a channel
+	noLast bool
 }
 
 // NewDataFrame instanciates a new private data frame.
 func NewDataFrame() *DataFrame {
-	return &DataFrame{
-		data: make(map[string]interface{}),
-	}
+	return &DataFrame{}
 }
 
-// Copy instanciates a new private data frame with receiver as parent.
-func (p *DataFrame) Copy() *DataFrame {
+// NewDataFrameFromMap instanciates a new private data frame with given values.
+//
+// Values are copied shallowly, so that setting values on given map afterwards does not modify the frame.
+func NewDataFrameFromMap(data map[string]interface{}) *DataFrame {
 	result := NewDataFrame()
 
-	for k, v := range p.data {
-		result.data[k] = v
+	for key, val := range data {
+		result.Set(key, val)
 	}
 
-	result.parent = p
-
 	return result
 }
 
+// Copy instanciates a new private data frame with receiver as parent.
+//
+// The new frame inherits all parent values, and setting a value on it never modifies the parent.
+func (p *DataFrame) Copy() *DataFrame {
+	return &DataFrame{
+		parent: p,
+	}
+}
+
 // newIterDataFrame instanciates a new private data frame with receiver as parent and with iteration data set (@index, @key, @first, @last)
 func (p *DataFrame) newIterDataFrame(length int, i int, key interface{}) *DataFrame {
-	result := p.Copy()
+	return &DataFrame{
+		parent: p,
+		iter:   true,
+		index:  i,
+		key:    key,
+		first:  i == 0,
+		last:   i == length-1,
+	}
+}
+
+// Parent returns the frame that receiver was copied from, or nil if receiver is a root frame.
+func (p *DataFrame) Parent() *DataFrame {
+	return p.parent
+}
+
+// Keys returns sorted keys of values set on that frame, including iteration data (index, key, first, last) on a frame
+// created by an iteration helper. Keys of parent frames are not included.
+func (p *DataFrame) Keys() []string {
+	result := make([]string, 0, len(p.data)+4)
+
+	for key := range p.data {
+		result = append(result, key)
+	}
+
+	if p.iter {
+		iterKeys := []string{"index", "key", "first", "last"}
+		if p.noLast {
+			iterKeys = iterKeys[:3]
+		}
+
+		for _, key := range iterKeys {
+			if _, ok := p.data[key]; !ok {
+				result = append(result, key)
+			}
+		}
+	}
 
-	result.Set("index", i)
-	result.Set("key", key)
-	result.Set("first", i == 0)
-	result.Set("last", i == length-1)
+	sort.Strings(result)
+
+	return result
+}
+
+// newStreamDataFrame instanciates a new private data frame with receiver as parent and with iteration data set for
+// an iteration of unknown length, so that @last is not set
+func (p *DataFrame) newStreamDataFrame(i int) *DataFrame {
+	result := p.newIterDataFrame(0, i, nil)
+	result.noLast = true
 
 	return result
 }
 
 // Set sets a data value.
 func (p *DataFrame) Set(key string, val interface{}) {
+	if p.data == nil {
+		p.data = make(map[string]interface{})
+	}
+
 	p.data[key] = val
 }
 
@@ -52,44 +119,68 @@ func (p *DataFrame) Get(key string) interface{} {
 	return p.find([]string{key})
 }
 
-// find gets a deep data value
-//
-// @todo This is NOT consistent with the way we resolve data in template (cf. `evalDataPathExpression()`) ! FIX THAT !
-func (p *DataFrame) find(parts []string) interface{} {
-	data := p.data
+// own returns a value set on that frame, with a boolean set to false if that frame does not have that value
+func (p *DataFrame) own(key string) (interface{}, bool) {
+	if val, ok := p.data[key]; ok {
+		return val, true
+	}
 
-	for i, part := range parts {
-		val := data[part]
-		if val == nil {
-			return nil
+	if p.iter {
+		switch key {
+		case "index":
+			return p.index, true
+		case "key":
+			return p.key, true
+		case "first":
+			return p.first, true
+		case "last":
+			if p.noLast {
+				return nil, true
+			}
+			return p.last, true
 		}
+	}
 
-		if i == len(parts)-1 {
-			// found
-			return val
-		}
+	return nil, false
+}
 
-		valValue := reflect.ValueOf(val)
-		if valValue.Kind() != reflect.Map {
-			// not found
-			return nil
+// lookup returns a value set on that frame or on its nearest parent that has it, with a boolean set to false if not found
+func (p *DataFrame) lookup(key string) (interface{}, bool) {
+	for frame := p; frame != nil; frame = frame.parent {
+		if val, ok := frame.own(key); ok {
+			return val, true
 		}
-
-		// continue
-		data = mapStringInterface(valValue)
 	}
 
-	// not found
-	return nil
+	return nil, false
 }
 
-// mapStringInterface converts any `map` to `map[string]interface{}`
-func mapStringInterface(value reflect.Value) map[string]interface{} {
-	result := make(map[string]interface{})
+// find gets a deep data value
+//
+// Path parts after the first one are resolved as in templates, on struct fields, map keys and array items. Methods and
+// functions are not called.
+func (p *DataFrame) find(parts []string) interface{} {
+	if len(parts) == 0 {
+		return nil
+	}
 
-	for _, key := range value.MapKeys() {
-		result[strValue(key)] = value.MapIndex(key).Interface()
+	val, ok := p.lookup(parts[0])
+	if !ok || (len(parts) == 1) {
+		return val
 	}
 
-	return result
+	result, _ := indirect(reflect.ValueOf(val))
+	for _, part := range parts[1:] {
+		if !result.IsValid() {
+			return nil
+		}
+
+		result = fieldValue(result, pathPart(part), StrictFieldMatching)
+	}
+
+	if !result.IsValid() {
+		return nil
+	}
+
+	return result.Interface()
 }
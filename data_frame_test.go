@@ -0,0 +1,199 @@
+package raymond
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDataFrameShadowing(t *testing.T) {
+	t.Parallel()
+
+	parent := NewDataFrame()
+	parent.Set("foo", "parent foo")
+	parent.Set("bar", map[string]interface{}{"baz": "parent baz"})
+
+	child := parent.Copy()
+	child.Set("foo", "child foo")
+
+	if val := child.Get("foo"); val != "child foo" {
+		t.Errorf("Child value must shadow parent value: %v", val)
+	}
+
+	if val := parent.Get("foo"); val != "parent foo" {
+		t.Errorf("Setting a child value must not modify parent: %v", val)
+	}
+
+	if val := child.find([]string{"bar", "baz"}); val != "parent baz" {
+		t.Errorf("Child must resolve dotted paths through parent: %v", val)
+	}
+
+	child.Set("foo", nil)
+
+	if val := child.Get("foo"); val != nil {
+		t.Errorf("Child nil value must shadow parent value: %v", val)
+	}
+
+	parent.Set("new", "value")
+
+	if val := child.Get("new"); val != "value" {
+		t.Errorf("Child must see values set on parent after its creation: %v", val)
+	}
+}
+
+func TestNewDataFrameFromMap(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]interface{}{
+		"site": map[string]interface{}{"name": "My Site"},
+		"year": 2016,
+	}
+
+	frame := NewDataFrameFromMap(data)
+
+	data["year"] = 2017
+	data["new"] = "value"
+
+	if (frame.Get("year") != 2016) || (frame.Get("new") != nil) {
+		t.Errorf("Data frame must not be modified by a later mutation of its initial map: %v %v", frame.Get("year"), frame.Get("new"))
+	}
+
+	if val := frame.find([]string{"site", "name"}); val != "My Site" {
+		t.Errorf("Failed to find nested map value: %v", val)
+	}
+
+	tpl := MustParse(`{{title}} - {{@site.name}} {{@year}}`)
+	if result, err := tpl.ExecWithData(map[string]string{"title": "Home"}, data); (err != nil) || (result != "Home - My Site 2017") {
+		t.Errorf("Failed to evaluate template with private data: %q %v", result, err)
+	}
+}
+
+func TestDataFrameIteration(t *testing.T) {
+	t.Parallel()
+
+	parent := NewDataFrame()
+	parent.Set("index", 42)
+
+	frame := parent.newIterDataFrame(3, 2, "foo")
+
+	if (frame.Get("index") != 2) || (frame.Get("key") != "foo") || (frame.Get("first") != false) || (frame.Get("last") != true) {
+		t.Errorf("Failed to get iteration data: %v %v %v %v", frame.Get("index"), frame.Get("key"), frame.Get("first"), frame.Get("last"))
+	}
+
+	frame.Set("index", 7)
+
+	if (frame.Get("index") != 7) || (parent.Get("index") != 42) {
+		t.Errorf("Setting iteration data must not modify parent: %v %v", frame.Get("index"), parent.Get("index"))
+	}
+}
+
+func TestDataFrameChain(t *testing.T) {
+	t.Parallel()
+
+	var frames []string
+
+	tpl := MustParse(`{{#each outer}}{{#each this}}{{dump}}{{/each}}{{/each}}`)
+	tpl.RegisterHelper("dump", func(options *Options) string {
+		var levels []string
+		for frame := options.DataFrame(); frame != nil; frame = frame.Parent() {
+			var values []string
+			for _, key := range frame.Keys() {
+				values = append(values, fmt.Sprintf("%s=%v", key, frame.Get(key)))
+			}
+			levels = append(levels, strings.Join(values, ","))
+		}
+
+		frames = append(frames, strings.Join(levels, " | "))
+
+		return ""
+	})
+
+	privData := NewDataFrame()
+	privData.Set("user", "jon")
+
+	if _, err := tpl.ExecWith(map[string]interface{}{"outer": [][]string{{"a"}, {"b", "c"}}}, privData); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"first=true,index=0,key=<nil>,last=true | first=true,index=0,key=<nil>,last=false | user=jon",
+		"first=true,index=0,key=<nil>,last=false | first=false,index=1,key=<nil>,last=true | user=jon",
+		"first=false,index=1,key=<nil>,last=true | first=false,index=1,key=<nil>,last=true | user=jon",
+	}
+
+	if fmt.Sprint(frames) != fmt.Sprint(expected) {
+		t.Errorf("Failed to walk data frames chain\nexpected\n\t%q\ngot\n\t%q", expected, frames)
+	}
+}
+
+type dataFrameAddress struct {
+	City string
+}
+
+type dataFrameUser struct {
+	Name    string
+	Address *dataFrameAddress
+	Tags    []string
+}
+
+func TestDataFrameFind(t *testing.T) {
+	t.Parallel()
+
+	frame := NewDataFrame()
+	frame.Set("user", &dataFrameUser{Name: "Jon", Address: &dataFrameAddress{City: "Paris"}, Tags: []string{"a", "b"}})
+	frame.Set("items", []map[string]string{{"name": "foo"}, {"name": "bar"}})
+
+	if val := frame.find([]string{"user", "Name"}); val != "Jon" {
+		t.Errorf("Failed to find struct field: %v", val)
+	}
+
+	if val := frame.find([]string{"user", "Address", "City"}); val != "Paris" {
+		t.Errorf("Failed to find field through struct pointer: %v", val)
+	}
+
+	if val := frame.find([]string{"user", "Tags", "1"}); val != "b" {
+		t.Errorf("Failed to find slice item: %v", val)
+	}
+
+	if val := frame.find([]string{"items", "[1]", "name"}); val != "bar" {
+		t.Errorf("Failed to find map value in slice: %v", val)
+	}
+
+	if val := frame.find([]string{"user", "Unknown", "City"}); val != nil {
+		t.Errorf("Unknown path must not be found: %v", val)
+	}
+}
+
+var dataFrameTests = []Test{
+	{
+		"deep data frames chain created by nested each",
+		`{{#each a}}{{#each b}}{{#each c}}{{@index}}{{@../index}}{{@../../index}}{{@root.foo}}{{@foo}} {{/each}}{{/each}}{{/each}}`,
+		map[string]interface{}{
+			"foo": "R",
+			"a": []map[string]interface{}{
+				{"b": []map[string]interface{}{
+					{"c": []int{1, 2}},
+				}},
+				{"b": []map[string]interface{}{
+					{"c": []int{1}},
+					{"c": []int{1}},
+				}},
+			},
+		},
+		map[string]interface{}{"foo": "D"},
+		nil, nil,
+		`000RD 100RD 001RD 011RD `,
+	},
+	{
+		"data paths over structs and slices",
+		`{{@user.Name}} {{@user.Address.City}} {{@user.Tags.[1]}} {{#with @user}}{{@user.Tags.0}}{{/with}}`,
+		nil,
+		map[string]interface{}{"user": &dataFrameUser{Name: "Jon", Address: &dataFrameAddress{City: "Paris"}, Tags: []string{"a", "b"}}},
+		nil, nil,
+		`Jon Paris b a`,
+	},
+}
+
+func TestDataFrameEval(t *testing.T) {
+	launchTests(t, dataFrameTests)
+}
@@ -63,3 +63,16 @@ func Escape(s string) string {
 	escape(&buf, s)
 	return buf.String()
 }
+
+// EscapeValue returns the string representation of given value, with special HTML characters escaped unless value is a
+// SafeString.
+//
+// It can be used by helpers that combine their parameters into a SafeString, so that SafeString parameters are not
+// escaped twice.
+func EscapeValue(value interface{}) string {
+	if str, ok := value.(SafeString); ok {
+		return string(str)
+	}
+
+	return Escape(Str(value))
+}
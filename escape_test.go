@@ -1,6 +1,9 @@
 package raymond
 
-import "fmt"
+import (
+	"fmt"
+	"testing"
+)
 
 func ExampleEscape() {
 	tpl := MustParse("{{link url text}}")
@@ -18,3 +21,129 @@ func ExampleEscape() {
 	fmt.Print(result)
 	// Output: <a href='http://www.aymerick.com/'>This is a &lt;em&gt;cool&lt;/em&gt; website</a>
 }
+
+func concatHelper(a, b interface{}) SafeString {
+	return SafeString(EscapeValue(a) + EscapeValue(b))
+}
+
+var escapingTests = []Test{
+	{
+		"special characters are escaped, but not with triple-stash and ampersand",
+		`{{val}} {{{val}}} {{&val}}`,
+		map[string]interface{}{"val": `a&<>"'b`},
+		nil, nil, nil,
+		`a&amp;&lt;&gt;&quot;&apos;b a&<>"'b a&<>"'b`,
+	},
+	{
+		"multi-byte characters are preserved",
+		`{{val}} {{{val}}}`,
+		map[string]interface{}{"val": "é😀<ü>日本"},
+		nil, nil, nil,
+		`é😀&lt;ü&gt;日本 é😀<ü>日本`,
+	},
+	{
+		"raw bytes are output verbatim with triple-stash",
+		`{{{val}}}|{{val}}`,
+		map[string]interface{}{"val": "\xff\x00&\t\r\n"},
+		nil, nil, nil,
+		"\xff\x00&\t\r\n|\xff\x00&amp;\t\r\n",
+	},
+	{
+		"SafeString returned by a helper is not escaped",
+		`{{safe}} {{{safe}}} {{dirty}} {{{dirty}}}`,
+		nil, nil,
+		map[string]interface{}{
+			"safe":  func() SafeString { return SafeString(`a&<>"'b`) },
+			"dirty": func() string { return `a&<>"'b` },
+		},
+		nil,
+		`a&<>"'b a&<>"'b a&amp;&lt;&gt;&quot;&apos;b a&<>"'b`,
+	},
+	{
+		"SafeString value from context is not escaped",
+		`{{val}} {{#with val}}{{this}}{{/with}}`,
+		map[string]interface{}{"val": SafeString("<b>é</b>")},
+		nil, nil, nil,
+		`<b>é</b> <b>é</b>`,
+	},
+	{
+		"helper combining a SafeString and a dirty string",
+		`{{concat (safe) dirty}} {{concat dirty safeVal}}`,
+		map[string]interface{}{"dirty": "<i>&", "safeVal": SafeString("<b>")},
+		nil,
+		map[string]interface{}{"concat": concatHelper, "safe": func() SafeString { return SafeString("<b>") }},
+		nil,
+		`<b>&lt;i&gt;&amp; &lt;i&gt;&amp;<b>`,
+	},
+	{
+		"nested helpers escape only once",
+		`{{concat (concat safeVal dirty) dirty}} {{{concat (concat safeVal dirty) dirty}}}`,
+		map[string]interface{}{"dirty": "<i>", "safeVal": SafeString("<b>")},
+		nil,
+		map[string]interface{}{"concat": concatHelper},
+		nil,
+		`<b>&lt;i&gt;&lt;i&gt; <b>&lt;i&gt;&lt;i&gt;`,
+	},
+	{
+		"helper parameters are not escaped",
+		`{{len (dirty)}} {{len dirtyVal}}`,
+		map[string]interface{}{"dirtyVal": "<i>"},
+		nil,
+		map[string]interface{}{
+			"len":   func(str string) int { return len(str) },
+			"dirty": func() string { return "<i>" },
+		},
+		nil,
+		`3 3`,
+	},
+	{
+		"partial output inserted by a helper",
+		`{{render}} {{#wrap}}{{> item}}{{/wrap}}`,
+		map[string]interface{}{"dirty": "<i>&"},
+		nil,
+		map[string]interface{}{
+			"render": func(options *Options) SafeString {
+				result, _ := options.RenderString("{{> item}}")
+				return result
+			},
+			"wrap": func(options *Options) string {
+				return "<div>" + options.Fn() + "</div>"
+			},
+		},
+		map[string]string{"item": "<p>{{dirty}}</p>"},
+		`<p>&lt;i&gt;&amp;</p> <div><p>&lt;i&gt;&amp;</p></div>`,
+	},
+	{
+		"#join with SafeString items",
+		`{{join items "<br>"}} {{join dirty "<br>"}} {{join safeVal ","}}`,
+		map[string]interface{}{
+			"items":   []interface{}{SafeString("<b>"), "<i>"},
+			"dirty":   []string{"<i>", "<u>"},
+			"safeVal": SafeString("<b>"),
+		},
+		nil, nil, nil,
+		`<b>&lt;br&gt;&lt;i&gt; &lt;i&gt;&lt;br&gt;&lt;u&gt; <b>`,
+	},
+}
+
+func TestEscaping(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, escapingTests)
+}
+
+func TestEscapeValue(t *testing.T) {
+	t.Parallel()
+
+	if str := EscapeValue("<i>"); str != "&lt;i&gt;" {
+		t.Errorf("String must be escaped: %q", str)
+	}
+
+	if str := EscapeValue(SafeString("<i>")); str != "<i>" {
+		t.Errorf("SafeString must not be escaped: %q", str)
+	}
+
+	if str := EscapeValue(12); str != "12" {
+		t.Errorf("Number must be stringified: %q", str)
+	}
+}
@@ -2,12 +2,17 @@ package raymond
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/lexer"
 )
 
 var (
@@ -15,6 +20,10 @@ var (
 	errorType       = reflect.TypeOf((*error)(nil)).Elem()
 	fmtStringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
 
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonNumberType     = reflect.TypeOf(json.Number(""))
+	variadicHelperType = reflect.TypeOf(VariadicHelper(nil))
+
 	zero reflect.Value
 )
 
@@ -40,8 +49,54 @@ type evalVisitor struct {
 	// memoize expressions that were function calls
 	exprFunc map[*ast.Expression]bool
 
+	// helpers found during evaluation
+	helpers map[string]reflect.Value
+
 	// used for info on panic
 	curNode ast.Node
+
+	// last error raised by evaluation, so that it is not mistaken for a helper panic
+	failure error
+
+	// name and source of the template or partial being evaluated
+	srcName string
+	src     string
+
+	// compiled programs, if template was compiled
+	compiled compiledPrograms
+
+	// safe mode limits, nil if not in safe mode
+	safe *SafeLimits
+
+	// if true, builtin helpers fail when a block declares more block params than they provide
+	strictBlockParams bool
+
+	// partials nesting
+	partialDepth int
+
+	// trace hook, nil if tracing is disabled
+	trace      TraceHook
+	traceDepth int
+
+	// statements interceptor, nil if disabled
+	interceptor *Interceptor
+
+	// output buffers, reused by nested programs
+	bufs     []*bytes.Buffer
+	bufDepth int
+}
+
+// maxPooledBufferSize is the maximum capacity of an output buffer kept by a pooled visitor
+const maxPooledBufferSize = 64 * 1024
+
+// evalVisitorPool recycles evaluation visitors, and their internal stacks
+var evalVisitorPool = sync.Pool{
+	New: func() interface{} {
+		return &evalVisitor{
+			exprFunc: make(map[*ast.Expression]bool),
+			helpers:  make(map[string]reflect.Value),
+		}
+	},
 }
 
 // NewEvalVisitor instanciate a new evaluation visitor with given context and initial private data frame
@@ -53,12 +108,79 @@ func newEvalVisitor(tpl *Template, ctx interface{}, privData *DataFrame) *evalVi
 		frame = NewDataFrame()
 	}
 
-	return &evalVisitor{
-		tpl:       tpl,
-		ctx:       []reflect.Value{reflect.ValueOf(ctx)},
-		dataFrame: frame,
-		exprFunc:  make(map[*ast.Expression]bool),
+	v := evalVisitorPool.Get().(*evalVisitor)
+
+	v.tpl = tpl
+
+	tpl.mutex.RLock()
+	v.compiled = tpl.compiled
+	tpl.mutex.RUnlock()
+
+	v.trace = tpl.traceHook
+	v.interceptor = tpl.interceptor
+	v.srcName = tpl.name
+	v.src = tpl.source
+	v.ctx = append(v.ctx, reflect.ValueOf(ctx))
+	v.dataFrame = frame
+
+	return v
+}
+
+// release resets visitor and puts it back in the pool
+//
+// WARNING: visitor must not be used after that call.
+func (v *evalVisitor) release() {
+	v.tpl = nil
+	v.compiled = nil
+	v.safe = nil
+	v.strictBlockParams = false
+	v.partialDepth = 0
+	v.trace = nil
+	v.traceDepth = 0
+	v.interceptor = nil
+	v.dataFrame = nil
+	v.curNode = nil
+	v.failure = nil
+	v.srcName = ""
+	v.src = ""
+
+	for i := range v.ctx {
+		v.ctx[i] = zero
+	}
+	v.ctx = v.ctx[:0]
+
+	for i := range v.blockParams {
+		v.blockParams[i] = nil
+	}
+	v.blockParams = v.blockParams[:0]
+
+	for i := range v.blocks {
+		v.blocks[i] = nil
+	}
+	v.blocks = v.blocks[:0]
+
+	for i := range v.exprs {
+		v.exprs[i] = nil
+	}
+	v.exprs = v.exprs[:0]
+
+	for expr := range v.exprFunc {
+		delete(v.exprFunc, expr)
 	}
+
+	for name := range v.helpers {
+		delete(v.helpers, name)
+	}
+
+	// don't keep huge buffers around
+	for i, buf := range v.bufs {
+		if buf.Cap() > maxPooledBufferSize {
+			v.bufs[i] = new(bytes.Buffer)
+		}
+	}
+	v.bufDepth = 0
+
+	evalVisitorPool.Put(v)
 }
 
 // at sets current node
@@ -107,6 +229,33 @@ func (v *evalVisitor) ancestorCtx(depth int) reflect.Value {
 	return v.ctx[index]
 }
 
+//
+// Output buffers
+//
+
+// pushBuffer returns an empty output buffer for a new program evaluation
+func (v *evalVisitor) pushBuffer() *bytes.Buffer {
+	if v.bufDepth == len(v.bufs) {
+		v.bufs = append(v.bufs, new(bytes.Buffer))
+	}
+
+	result := v.bufs[v.bufDepth]
+	result.Reset()
+
+	if v.bufDepth == 0 {
+		result.Grow(v.tpl.outputSize())
+	}
+
+	v.bufDepth++
+
+	return result
+}
+
+// popBuffer releases last output buffer
+func (v *evalVisitor) popBuffer() {
+	v.bufDepth--
+}
+
 //
 // Private data frame
 //
@@ -215,13 +364,41 @@ func (v *evalVisitor) curExpr() *ast.Expression {
 	return v.exprs[len(v.exprs)-1]
 }
 
+// stackDepths records the state of all evaluation stacks
+type stackDepths struct {
+	ctx         int
+	blockParams int
+	blocks      int
+	exprs       int
+	bufs        int
+	dataFrame   *DataFrame
+}
+
+// stackDepths returns the current state of evaluation stacks
+func (v *evalVisitor) stackDepths() stackDepths {
+	return stackDepths{
+		ctx:         len(v.ctx),
+		blockParams: len(v.blockParams),
+		blocks:      len(v.blocks),
+		exprs:       len(v.exprs),
+		bufs:        v.bufDepth,
+		dataFrame:   v.dataFrame,
+	}
+}
+
 //
 // Error functions
 //
 
 // errPanic panics
 func (v *evalVisitor) errPanic(err error) {
-	panic(fmt.Errorf("Evaluation error: %s\nCurrent node:\n\t%s", err, v.curNode))
+	v.fail(fmt.Errorf("Evaluation error: %s\nCurrent node:\n\t%s", err, v.curNode))
+}
+
+// fail panics with given evaluation error
+func (v *evalVisitor) fail(err error) {
+	v.failure = err
+	panic(err)
 }
 
 // errorf panics with a custom message
@@ -229,54 +406,119 @@ func (v *evalVisitor) errorf(format string, args ...interface{}) {
 	v.errPanic(fmt.Errorf(format, args...))
 }
 
+// str returns string representation of given value, as Str() does
+//
+// A value that can't be printed fails evaluation, and in safe mode the methods of given value are never called.
+func (v *evalVisitor) str(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	val := reflect.ValueOf(value)
+
+	v.checkPrint(val)
+
+	defer v.strRecover()
+
+	return strValue(val)
+}
+
+// strRecover recovers a string conversion panic, and panics again with an evaluation error
+func (v *evalVisitor) strRecover() {
+	e := recover()
+	if e == nil {
+		return
+	}
+
+	switch err := e.(type) {
+	case runtime.Error:
+		// not wrapped
+	case error:
+		v.errPanic(err)
+	}
+
+	panic(e)
+}
+
 //
 // Evaluation
 //
 
 // evalProgram eEvaluates program with given context and returns string result
-func (v *evalVisitor) evalProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}) string {
-	blockParams := make(map[string]interface{})
+func (v *evalVisitor) evalProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}, params []interface{}) string {
+	scope := v.enterProgram(program, ctx, data, key, params)
+
+	// evaluate program
+	result, _ := program.Accept(v).(string)
+
+	v.leaveProgram(scope)
+
+	return result
+}
+
+// programScope records what was pushed on stacks when entering a program
+type programScope struct {
+	blockParams bool
+	ctx         bool
+	data        bool
+}
+
+// enterProgram pushes block params, context and data frame before evaluating given program
+//
+// Block params are set with given params values, or with context and key if params is nil.
+func (v *evalVisitor) enterProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}, params []interface{}) programScope {
+	var scope programScope
 
 	// compute block params
 	if len(program.BlockParams) > 0 {
-		blockParams[program.BlockParams[0]] = ctx
-	}
+		blockParams := make(map[string]interface{})
 
-	if (len(program.BlockParams) > 1) && (key != nil) {
-		blockParams[program.BlockParams[1]] = key
-	}
+		if params != nil {
+			for i, name := range program.BlockParams {
+				if i < len(params) {
+					blockParams[name] = params[i]
+				}
+			}
+		} else {
+			blockParams[program.BlockParams[0]] = ctx
+
+			if (len(program.BlockParams) > 1) && (key != nil) {
+				blockParams[program.BlockParams[1]] = key
+			}
+		}
 
-	// push contexts
-	if len(blockParams) > 0 {
 		v.pushBlockParams(blockParams)
+		scope.blockParams = true
 	}
 
+	// push contexts
 	ctxVal := reflect.ValueOf(ctx)
 	if ctxVal.IsValid() {
 		v.pushCtx(ctxVal)
+		scope.ctx = true
 	}
 
 	if data != nil {
 		v.setDataFrame(data)
+		scope.data = true
 	}
 
-	// evaluate program
-	result, _ := program.Accept(v).(string)
+	return scope
+}
 
-	// pop contexts
-	if data != nil {
+// leaveProgram pops what was pushed by enterProgram
+func (v *evalVisitor) leaveProgram(scope programScope) {
+	if scope.data {
 		v.popDataFrame()
 	}
 
-	if ctxVal.IsValid() {
+	if scope.ctx {
 		v.popCtx()
 	}
 
-	if len(blockParams) > 0 {
+	if scope.blockParams {
 		v.popBlockParams()
 	}
-
-	return result
 }
 
 // evalPath evaluates all path parts with given context
@@ -284,14 +526,7 @@ func (v *evalVisitor) evalPath(ctx reflect.Value, parts []string, exprRoot bool)
 	partResolved := false
 
 	for i := 0; i < len(parts); i++ {
-		part := parts[i]
-
-		// "[foo bar]"" => "foo bar"
-		if (len(part) >= 2) && (part[0] == '[') && (part[len(part)-1] == ']') {
-			part = part[1 : len(part)-1]
-		}
-
-		ctx = v.evalField(ctx, part, exprRoot)
+		ctx = v.evalField(ctx, pathPart(parts[i]), exprRoot)
 		if !ctx.IsValid() {
 			break
 		}
@@ -303,6 +538,17 @@ func (v *evalVisitor) evalPath(ctx reflect.Value, parts []string, exprRoot bool)
 	return ctx, partResolved
 }
 
+// pathPart returns given path part without its enclosing brackets
+//
+// example: "[foo bar]" => "foo bar"
+func pathPart(part string) string {
+	if (len(part) >= 2) && (part[0] == '[') && (part[len(part)-1] == ']') {
+		return part[1 : len(part)-1]
+	}
+
+	return part
+}
+
 // evalField evaluates field with given context
 func (v *evalVisitor) evalField(ctx reflect.Value, fieldName string, exprRoot bool) reflect.Value {
 	result := zero
@@ -314,41 +560,87 @@ func (v *evalVisitor) evalField(ctx reflect.Value, fieldName string, exprRoot bo
 
 	// check if this is a method call
 	result, isMeth := v.evalMethod(ctx, fieldName, exprRoot)
-	if !isMeth {
-		switch ctx.Kind() {
-		case reflect.Struct:
-			// example: firstName => FirstName
-			expFieldName := strings.Title(fieldName)
-
-			// check if struct have this field and that it is exported
-			if tField, ok := ctx.Type().FieldByName(expFieldName); ok && (tField.PkgPath == "") {
-				// struct field
-				result = ctx.FieldByIndex(tField.Index)
-				break
-			}
+	if isMeth {
+		result, _ = indirect(result)
+		result = orderedMapAddr(result)
+	} else {
+		result = fieldValue(ctx, fieldName, v.tpl.fieldMatching)
+	}
+
+	// check if result is a function
+	if result.Kind() == reflect.Func {
+		result = v.evalFieldFunc(fieldName, result, exprRoot)
+	}
+
+	return result
+}
+
+// fieldValue returns the struct field, map value or array item of given indirected context that matches given field
+// name, without calling methods nor functions
+func fieldValue(ctx reflect.Value, fieldName string, matching FieldMatching) reflect.Value {
+	result := zero
 
-			// attempts to find template variable name as a struct tag
-			result = v.evalStructTag(ctx, fieldName)
-		case reflect.Map:
-			nameVal := reflect.ValueOf(fieldName)
-			if nameVal.Type().AssignableTo(ctx.Type().Key()) {
-				// map key
-				result = ctx.MapIndex(nameVal)
+	switch kindOf(ctx) {
+	case reflect.Struct:
+		if index := structFieldIndex(ctx.Type(), fieldName); index != nil {
+			// struct field
+			result = fieldByIndex(ctx, index)
+		} else if matching == LooseFieldMatching {
+			if index := looseStructFieldIndex(ctx.Type(), fieldName); index != nil {
+				// struct field matched ignoring case and underscores
+				result = fieldByIndex(ctx, index)
 			}
-		case reflect.Array, reflect.Slice:
-			if i, err := strconv.Atoi(fieldName); (err == nil) && (i < ctx.Len()) {
-				result = ctx.Index(i)
+		}
+	case reflect.Map:
+		if m, ok := ctx.Interface().(map[string]interface{}); ok {
+			// fast path for the most common context type, without reflection
+			if val, ok := m[fieldName]; ok && (val != nil) {
+				result = reflect.ValueOf(val)
+			} else if ok {
+				result = ctx.MapIndex(reflect.ValueOf(fieldName))
 			}
+		} else if val, ok := orderedMapGet(ctx, fieldName); ok {
+			// ordered map key
+			result = val
+		} else if key, ok := mapKey(ctx.Type().Key(), fieldName); ok {
+			// map key
+			result = ctx.MapIndex(key)
+		}
+	case reflect.Array, reflect.Slice:
+		if i, err := strconv.Atoi(fieldName); (err == nil) && (i < ctx.Len()) {
+			result = ctx.Index(i)
 		}
 	}
 
-	// check if result is a function
 	result, _ = indirect(result)
-	if result.Kind() == reflect.Func {
-		result = v.evalFieldFunc(fieldName, result, exprRoot)
+
+	return orderedMapAddr(result)
+}
+
+// mapKey converts given field name to a key of given map key type, with a boolean set to false if conversion is not possible
+//
+// example: "2" => 2 for a map[int]string
+func mapKey(keyType reflect.Type, fieldName string) (reflect.Value, bool) {
+	nameVal := reflect.ValueOf(fieldName)
+
+	switch keyType.Kind() {
+	case reflect.String:
+		return nameVal.Convert(keyType), true
+	case reflect.Interface:
+		if nameVal.Type().AssignableTo(keyType) {
+			return nameVal, true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(fieldName, 10, keyType.Bits()); err == nil {
+			return reflect.ValueOf(i).Convert(keyType), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, err := strconv.ParseUint(fieldName, 10, keyType.Bits()); err == nil {
+			return reflect.ValueOf(i).Convert(keyType), true
+		}
 	}
 
-	return result
+	return zero, false
 }
 
 // evalFieldFunc tries to evaluate given method name, and a boolean to indicate if this was a method call
@@ -357,57 +649,40 @@ func (v *evalVisitor) evalMethod(ctx reflect.Value, name string, exprRoot bool)
 		ctx = ctx.Addr()
 	}
 
-	method := ctx.MethodByName(name)
-	if !method.IsValid() {
-		// example: subject() => Subject()
-		method = ctx.MethodByName(strings.Title(name))
-	}
-
-	if !method.IsValid() {
+	// example: subject() => Subject()
+	index := methodIndex(ctx.Type(), name)
+	if index == -1 {
 		return zero, false
 	}
 
-	return v.evalFieldFunc(name, method, exprRoot), true
+	return v.evalFieldFunc(name, ctx.Method(index), exprRoot), true
 }
 
 // evalFieldFunc evaluates given function
 func (v *evalVisitor) evalFieldFunc(name string, funcVal reflect.Value, exprRoot bool) reflect.Value {
+	v.checkFuncCall(name)
+
+	defer v.helperRecover(name, v.curNode)
+
 	ensureValidHelper(name, funcVal)
 
 	var options *Options
 	if exprRoot {
 		// create function arg with all params/hash
 		expr := v.curExpr()
-		options = v.helperOptions(expr)
+		options = v.helperOptions(name, expr)
 
 		// ok, that expression was a function call
 		v.exprFunc[expr] = true
 	} else {
 		// we are not at root of expression, so we are a parameter... and we don't like
 		// infinite loops caused by trying to parse ourself forever
-		options = newEmptyOptions(v)
+		options = newEmptyOptions(v, name)
 	}
 
 	return v.callFunc(name, funcVal, options)
 }
 
-// evalStructTag checks for the existence of a struct tag containing the
-// name of the variable in the template. This allows for a template variable to
-// be separated from the field in the struct.
-func (v *evalVisitor) evalStructTag(ctx reflect.Value, name string) reflect.Value {
-	val := reflect.ValueOf(ctx.Interface())
-
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Type().Field(i)
-		tag := field.Tag.Get("handlebars")
-		if tag == name {
-			return val.Field(i)
-		}
-	}
-
-	return zero
-}
-
 // findBlockParam returns node's block parameter
 func (v *evalVisitor) findBlockParam(node *ast.PathExpression) (string, interface{}) {
 	if len(node.Parts) > 0 {
@@ -479,10 +754,34 @@ func (v *evalVisitor) evalDataPathExpression(node *ast.PathExpression, exprRoot
 		frame = frame.parent
 	}
 
-	// resolve data
-	// @note Can be changed to v.evalCtx() as context can't be an array
-	result, _ := v.evalCtxPath(reflect.ValueOf(frame.data), node.Parts, exprRoot)
-	return result
+	if len(node.Parts) == 0 {
+		return nil
+	}
+
+	// resolve first part in data frames chain
+	name := pathPart(node.Parts[0])
+
+	data, ok := frame.lookup(name)
+	if !ok {
+		return nil
+	}
+
+	// check if data is a function
+	result, _ := indirect(reflect.ValueOf(data))
+	if result.Kind() == reflect.Func {
+		result = v.evalFieldFunc(name, result, exprRoot)
+	}
+
+	// resolve remaining parts
+	if result.IsValid() && (len(node.Parts) > 1) {
+		result, _ = v.evalPath(result, node.Parts[1:], exprRoot)
+	}
+
+	if !result.IsValid() {
+		return nil
+	}
+
+	return result.Interface()
 }
 
 // evalCtxPathExpression evaluates a context path expression
@@ -520,6 +819,11 @@ func (v *evalVisitor) evalDepthPath(depth int, parts []string, exprRoot bool) in
 		}
 	}
 
+	if (result == nil) && !partResolved && (v.tpl.defaults != nil) {
+		// try with template defaults
+		result, _ = v.evalCtxPath(reflect.ValueOf(v.tpl.defaults), parts, exprRoot)
+	}
+
 	return result
 }
 
@@ -528,7 +832,7 @@ func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []string, exprRoot bo
 	var result interface{}
 	partResolved := false
 
-	switch ctx.Kind() {
+	switch kindOf(ctx) {
 	case reflect.Array, reflect.Slice:
 		// Array context
 		var results []interface{}
@@ -540,7 +844,10 @@ func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []string, exprRoot bo
 			}
 		}
 
-		result = results
+		if results != nil {
+			// an array context that does not resolve the path must not hide parent contexts, nor helpers
+			result = results
+		}
 	default:
 		// NOT array context
 		var value reflect.Value
@@ -558,23 +865,61 @@ func (v *evalVisitor) evalCtxPath(ctx reflect.Value, parts []string, exprRoot bo
 // Helpers
 //
 
-// isHelperCall returns true if given expression is a helper call
-func (v *evalVisitor) isHelperCall(node *ast.Expression) bool {
-	if helperName := node.HelperName(); helperName != "" {
-		return v.findHelper(helperName) != zero
+// exprHelper returns the helper that given expression may call, or zero if that expression can't be a helper call
+func (v *evalVisitor) exprHelper(node *ast.Expression) reflect.Value {
+	if helperName := exprHelperName(node); helperName != "" {
+		return v.findHelper(helperName)
 	}
-	return false
+	return zero
+}
+
+// exprHelperName returns the name of the helper that given expression may call, or an empty string if it can't be a
+// helper call
+//
+// A dotted path like `str.upper` may call a helper registered in a namespace.
+func exprHelperName(node *ast.Expression) string {
+	if name := node.HelperName(); name != "" {
+		return name
+	}
+
+	path := node.FieldPath()
+	if (path == nil) || path.Data || (len(path.Parts) < 2) || (path.Depth > 0) || path.Scoped {
+		return ""
+	}
+
+	if path.Original != strings.Join(path.Parts, ".") {
+		// `str/upper` or `[str].upper`
+		return ""
+	}
+
+	return path.Original
+}
+
+// helperFirst returns true if given expression must be resolved as a helper call before a context field lookup
+//
+// An expression with parameters or hash arguments is always a helper call, whatever the helper precedence.
+func (v *evalVisitor) helperFirst(node *ast.Expression) bool {
+	return (v.tpl.helperPrecedence == HelperFirst) || (len(node.Params) > 0) || (node.Hash != nil)
 }
 
 // findHelper finds given helper
+//
+// Lookups are cached for the whole evaluation, including failed ones.
 func (v *evalVisitor) findHelper(name string) reflect.Value {
-	// check template helpers
-	if h := v.tpl.findHelper(name); h != zero {
+	if h, ok := v.helpers[name]; ok {
 		return h
 	}
 
-	// check global helpers
-	return findHelper(name)
+	// check template helpers
+	h := v.tpl.findHelper(name)
+	if h == zero {
+		// check global helpers
+		h = findHelper(name)
+	}
+
+	v.helpers[name] = h
+
+	return h
 }
 
 // callFunc calls function with given options
@@ -598,8 +943,22 @@ func (v *evalVisitor) callFunc(name string, funcVal reflect.Value, options *Opti
 		}
 	}
 
-	if !addOptions && (len(params) != numIn) {
-		v.errorf("Helper '%s' called with wrong number of arguments, needed %d but got %d", name, numIn, len(params))
+	// a variadic helper accepts any number of parameters, available with options.Params()
+	if !addOptions && (funcType == variadicHelperType) {
+		addOptions = true
+		params = nil
+	}
+
+	if !addOptions {
+		needed := numIn
+		if (numIn > 0) && (funcType.In(numIn-1) == reflect.TypeOf(options)) {
+			// options argument is not a parameter
+			needed--
+		}
+
+		if len(params) != needed {
+			v.errorf("Helper '%s' called with wrong number of arguments, needed %d but got %d", name, needed, len(params))
+		}
 	}
 
 	// check and collect arguments
@@ -622,7 +981,7 @@ func (v *evalVisitor) callFunc(name string, funcVal reflect.Value, options *Opti
 		if !arg.Type().AssignableTo(argType) {
 			if strType.AssignableTo(argType) {
 				// convert parameter to string
-				arg = reflect.ValueOf(strValue(arg))
+				arg = reflect.ValueOf(v.str(param))
 			} else if boolType.AssignableTo(argType) {
 				// convert parameter to bool
 				val, _ := isTrueValue(arg)
@@ -645,8 +1004,26 @@ func (v *evalVisitor) callFunc(name string, funcVal reflect.Value, options *Opti
 }
 
 // callHelper invoqs helper function for given expression node
-func (v *evalVisitor) callHelper(name string, helper reflect.Value, node *ast.Expression) interface{} {
-	result := v.callFunc(name, helper, v.helperOptions(node))
+//
+// If out is not nil, the helper block is executed in that output buffer.
+func (v *evalVisitor) callHelper(name string, helper reflect.Value, node *ast.Expression, out *bytes.Buffer) interface{} {
+	// that expression is a function call
+	v.exprFunc[node] = true
+
+	defer v.helperRecover(name, node)
+
+	depths := v.stackDepths()
+
+	options := v.helperOptions(name, node)
+	options.out = out
+
+	result := v.callFunc(name, helper, options)
+
+	// a helper that recovered a panic raised during block evaluation leaves stacks in an inconsistent state
+	if v.stackDepths() != depths {
+		v.errorf("Helper '%s' left the evaluation stacks unbalanced", name)
+	}
+
 	if !result.IsValid() {
 		return nil
 	}
@@ -655,8 +1032,42 @@ func (v *evalVisitor) callHelper(name string, helper reflect.Value, node *ast.Ex
 	return result.Interface()
 }
 
-// helperOptions computes helper options argument from an expression
-func (v *evalVisitor) helperOptions(node *ast.Expression) *Options {
+// helperRecover recovers a panic raised by given helper, and panics again with a *HelperError
+//
+// Evaluation errors raised while the helper evaluates its block are not wrapped.
+func (v *evalVisitor) helperRecover(name string, node ast.Node) {
+	e := recover()
+	if e == nil {
+		return
+	}
+
+	var err error
+
+	switch e := e.(type) {
+	case *HelperError, *SafeModeError:
+		panic(e)
+	case error:
+		if e == v.failure {
+			panic(e)
+		}
+		err = e
+	default:
+		err = fmt.Errorf("%v", e)
+	}
+
+	loc := node.Location()
+
+	panic(&HelperError{
+		Helper:   name,
+		Template: v.srcName,
+		Line:     loc.Line,
+		Column:   lexer.Column(v.src, loc.Pos),
+		Err:      err,
+	})
+}
+
+// helperOptions computes options argument of given helper or context function from an expression
+func (v *evalVisitor) helperOptions(name string, node *ast.Expression) *Options {
 	var params []interface{}
 	var hash map[string]interface{}
 
@@ -669,7 +1080,7 @@ func (v *evalVisitor) helperOptions(node *ast.Expression) *Options {
 		hash, _ = node.Hash.Accept(v).(map[string]interface{})
 	}
 
-	return newOptions(v, params, hash)
+	return newOptions(v, name, params, hash)
 }
 
 //
@@ -687,26 +1098,37 @@ func (v *evalVisitor) findPartial(name string) *partial {
 	return findPartial(name)
 }
 
-// partialContext computes partial context
-func (v *evalVisitor) partialContext(node *ast.PartialStatement) reflect.Value {
+// partialContext computes partial context, and returns true if partial must be evaluated with that context only
+//
+// When both a context parameter and named parameters are given, named parameters are returned as a second context that
+// is pushed on top of the first one, so that they are resolved first.
+func (v *evalVisitor) partialContext(node *ast.PartialStatement) (reflect.Value, reflect.Value, bool) {
 	if nb := len(node.Params); nb > 1 {
 		v.errorf("Unsupported number of partial arguments: %d", nb)
 	}
 
-	if (len(node.Params) > 0) && (node.Hash != nil) {
-		v.errorf("Passing both context and named parameters to a partial is not allowed")
+	var hash map[string]interface{}
+	if node.Hash != nil {
+		hash, _ = node.Hash.Accept(v).(map[string]interface{})
 	}
 
-	if len(node.Params) == 1 {
-		return reflect.ValueOf(node.Params[0].Accept(v))
+	// `isolate` is an option, not a named parameter
+	isolate := false
+	if val, ok := hash["isolate"]; ok {
+		isolate = IsTrue(val)
+		delete(hash, "isolate")
 	}
 
-	if node.Hash != nil {
-		hash, _ := node.Hash.Accept(v).(map[string]interface{})
-		return reflect.ValueOf(hash)
+	var hashCtx reflect.Value
+	if len(hash) > 0 {
+		hashCtx = reflect.ValueOf(hash)
 	}
 
-	return zero
+	if len(node.Params) == 1 {
+		return reflect.ValueOf(node.Params[0].Accept(v)), hashCtx, isolate
+	}
+
+	return hashCtx, zero, isolate
 }
 
 // evalPartial evaluates a partial
@@ -717,10 +1139,38 @@ func (v *evalVisitor) evalPartial(p *partial, node *ast.PartialStatement) string
 		v.errPanic(err)
 	}
 
+	v.partialDepth++
+	v.checkPartialDepth(node)
+
+	srcName, src := v.srcName, v.src
+	v.srcName, v.src = p.name, partialTpl.source
+
 	// push partial context
-	ctx := v.partialContext(node)
-	if ctx.IsValid() {
-		v.pushCtx(ctx)
+	ctx, hashCtx, isolate := v.partialContext(node)
+	if isolate {
+		if !ctx.IsValid() {
+			ctx = v.curCtx()
+		}
+
+		// hide ancestor contexts and block parameters
+		ctxs, blockParams := v.ctx, v.blockParams
+		v.ctx, v.blockParams = []reflect.Value{ctx}, nil
+
+		if hashCtx.IsValid() {
+			v.ctx = append(v.ctx, hashCtx)
+		}
+
+		defer func() {
+			v.ctx, v.blockParams = ctxs, blockParams
+		}()
+	} else {
+		if ctx.IsValid() {
+			v.pushCtx(ctx)
+		}
+
+		if hashCtx.IsValid() {
+			v.pushCtx(hashCtx)
+		}
 	}
 
 	// evaluate partial template
@@ -729,10 +1179,37 @@ func (v *evalVisitor) evalPartial(p *partial, node *ast.PartialStatement) string
 	// ident partial
 	result = indentLines(result, node.Indent)
 
-	if ctx.IsValid() {
-		v.popCtx()
+	if !isolate {
+		if hashCtx.IsValid() {
+			v.popCtx()
+		}
+
+		if ctx.IsValid() {
+			v.popCtx()
+		}
 	}
 
+	v.srcName, v.src = srcName, src
+
+	v.partialDepth--
+
+	return result
+}
+
+// evalSubTemplate evaluates given template with current context, as a partial without parameters
+func (v *evalVisitor) evalSubTemplate(tpl *Template) string {
+	v.partialDepth++
+	v.checkPartialDepth(v.curNode)
+
+	srcName, src := v.srcName, v.src
+	v.srcName, v.src = "", tpl.source
+
+	result, _ := tpl.program.Accept(v).(string)
+
+	v.srcName, v.src = srcName, src
+
+	v.partialDepth--
+
 	return result
 }
 
@@ -777,31 +1254,66 @@ func (v *evalVisitor) wasFuncCall(node *ast.Expression) bool {
 func (v *evalVisitor) VisitProgram(node *ast.Program) interface{} {
 	v.at(node)
 
-	buf := new(bytes.Buffer)
+	buf := v.pushBuffer()
 
-	for _, n := range node.Body {
-		if str := Str(n.Accept(v)); str != "" {
-			if _, err := buf.Write([]byte(str)); err != nil {
-				v.errPanic(err)
+	if prog, ok := v.compiled[node]; ok {
+		// template was compiled
+		prog.exec(v, buf)
+	} else {
+		for _, n := range node.Body {
+			switch n := n.(type) {
+			case *ast.ContentStatement:
+				// content is written as is
+				if _, err := buf.WriteString(n.Value); err != nil {
+					v.errPanic(err)
+				}
+				continue
+			case *ast.MustacheStatement:
+				// mustache result is escaped directly in output buffer
+				v.writeMustache(buf, n)
+				continue
+			}
+
+			if str := v.str(n.Accept(v)); str != "" {
+				if _, err := buf.WriteString(str); err != nil {
+					v.errPanic(err)
+				}
 			}
 		}
 	}
 
-	return buf.String()
+	result := buf.String()
+
+	v.popBuffer()
+
+	return result
 }
 
 // VisitMustache implements corresponding Visitor interface method
 func (v *evalVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
+	if v.interceptor != nil {
+		return v.intercept(node, func() interface{} { return v.evalMustache(node) })
+	}
+
+	return v.evalMustache(node)
+}
+
+// evalMustache evaluates a mustache statement
+func (v *evalVisitor) evalMustache(node *ast.MustacheStatement) interface{} {
 	v.at(node)
 
+	if v.trace != nil {
+		defer v.traceExit(v.traceEnter(node, node.Expression.Canonical()))
+	}
+
 	// evaluate expression
-	expr := node.Expression.Accept(v)
+	expr := v.missingValue(node, node.Expression.Accept(v))
 
 	// check if this is a safe string
 	isSafe := isSafeString(expr)
 
 	// get string value
-	str := Str(expr)
+	str := v.str(expr)
 	if !isSafe && !node.Unescaped {
 		// escape html
 		str = Escape(str)
@@ -810,10 +1322,91 @@ func (v *evalVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
 	return str
 }
 
+// writeMustache evaluates a mustache statement and writes its result to given buffer
+func (v *evalVisitor) writeMustache(buf *bytes.Buffer, node *ast.MustacheStatement) {
+	if v.interceptor != nil {
+		writeString(v, buf, v.str(v.VisitMustache(node)))
+		return
+	}
+
+	v.at(node)
+
+	if v.trace != nil {
+		defer v.traceExit(v.traceEnter(node, node.Expression.Canonical()))
+	}
+
+	v.writeValue(buf, v.missingValue(node, node.Expression.Accept(v)), node.Unescaped)
+}
+
+// missingValue returns given value evaluated by given mustache, or the value returned by the template missing function
+// if that value is nil and that mustache is a single path that is not a helper call
+func (v *evalVisitor) missingValue(node *ast.MustacheStatement, value interface{}) interface{} {
+	if (value != nil) || (v.tpl.missingFunc == nil) {
+		return value
+	}
+
+	expr := node.Expression
+
+	path := expr.FieldPath()
+	if (path == nil) || (len(expr.Params) > 0) || (expr.Hash != nil) || (v.exprHelper(expr) != zero) {
+		return value
+	}
+
+	return v.tpl.missingFunc(path.Original)
+}
+
+// writeValue writes the string representation of given value to buffer, with html escaped if it is not a safe string
+//
+// Strings without special characters and numbers are written as is, without any intermediate string.
+func (v *evalVisitor) writeValue(buf *bytes.Buffer, value interface{}, unescaped bool) {
+	var err error
+	var scratch [24]byte
+
+	switch val := value.(type) {
+	case nil:
+		return
+	case string:
+		if unescaped {
+			_, err = buf.WriteString(val)
+		} else {
+			err = escape(buf, val)
+		}
+	case SafeString:
+		_, err = buf.WriteString(string(val))
+	case int:
+		_, err = buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int64:
+		_, err = buf.Write(strconv.AppendInt(scratch[:0], val, 10))
+	default:
+		if unescaped {
+			_, err = buf.WriteString(v.str(val))
+		} else {
+			err = escape(buf, v.str(val))
+		}
+	}
+
+	if err != nil {
+		v.errPanic(err)
+	}
+}
+
 // VisitBlock implements corresponding Visitor interface method
 func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
+	if v.interceptor != nil {
+		return v.intercept(node, func() interface{} { return v.evalBlock(node) })
+	}
+
+	return v.evalBlock(node)
+}
+
+// evalBlock evaluates a block statement
+func (v *evalVisitor) evalBlock(node *ast.BlockStatement) interface{} {
 	v.at(node)
 
+	if v.trace != nil {
+		defer v.traceExit(v.traceEnter(node, node.Expression.Canonical()))
+	}
+
 	v.pushBlock(node)
 
 	var result interface{}
@@ -821,7 +1414,7 @@ func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
 	// evaluate expression
 	expr := node.Expression.Accept(v)
 
-	if v.isHelperCall(node.Expression) || v.wasFuncCall(node.Expression) {
+	if v.wasFuncCall(node.Expression) {
 		// it is the responsibility of the helper/function to evaluate block
 		result = expr
 	} else {
@@ -830,9 +1423,11 @@ func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
 		truth, _ := isTrueValue(val)
 		if truth {
 			if node.Program != nil {
-				switch val.Kind() {
+				switch kindOf(val) {
 				case reflect.Array, reflect.Slice:
-					concat := ""
+					v.checkIterations(val.Len())
+
+					var concat strings.Builder
 
 					// Array context
 					for i := 0; i < val.Len(); i++ {
@@ -840,13 +1435,13 @@ func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
 						frame := v.dataFrame.newIterDataFrame(val.Len(), i, nil)
 
 						// Evaluate program
-						concat += v.evalProgram(node.Program, val.Index(i).Interface(), frame, i)
+						concat.WriteString(v.evalProgram(node.Program, val.Index(i).Interface(), frame, i, nil))
 					}
 
-					result = concat
+					result = concat.String()
 				default:
 					// NOT array
-					result = v.evalProgram(node.Program, expr, nil, nil)
+					result = v.evalProgram(node.Program, expr, nil, nil, nil)
 				}
 			}
 		} else if node.Inverse != nil {
@@ -861,6 +1456,15 @@ func (v *evalVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
 
 // VisitPartial implements corresponding Visitor interface method
 func (v *evalVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
+	if v.interceptor != nil {
+		return v.intercept(node, func() interface{} { return v.evalPartialStatement(node) })
+	}
+
+	return v.evalPartialStatement(node)
+}
+
+// evalPartialStatement evaluates a partial statement
+func (v *evalVisitor) evalPartialStatement(node *ast.PartialStatement) interface{} {
 	v.at(node)
 
 	// partialName: helperName | sexpr
@@ -880,6 +1484,10 @@ func (v *evalVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
 		v.errorf("Partial not found: %s", name)
 	}
 
+	if v.trace != nil {
+		defer v.traceExit(v.traceEnter(node, name))
+	}
+
 	return v.evalPartial(partial, node)
 }
 
@@ -910,12 +1518,14 @@ func (v *evalVisitor) VisitExpression(node *ast.Expression) interface{} {
 
 	v.pushExpr(node)
 
+	// that expression may have been a function call in a previous evaluation
+	delete(v.exprFunc, node)
+
 	// helper call
-	if helperName := node.HelperName(); helperName != "" {
-		if helper := v.findHelper(helperName); helper != zero {
-			result = v.callHelper(helperName, helper, node)
-			done = true
-		}
+	helper := v.exprHelper(node)
+	if (helper != zero) && v.helperFirst(node) {
+		result = v.callHelper(exprHelperName(node), helper, node, nil)
+		done = true
 	}
 
 	if !done {
@@ -936,22 +1546,69 @@ func (v *evalVisitor) VisitExpression(node *ast.Expression) interface{} {
 			// that this path is at root of current expression
 			if val := v.evalPathExpression(path, true); val != nil {
 				result = val
+				done = true
 			}
 		}
 	}
 
+	if !done && (helper != zero) {
+		// no field found, so that is a helper call
+		result = v.callHelper(exprHelperName(node), helper, node, nil)
+	}
+
 	v.popExpr()
 
 	return result
 }
 
+// SubExpressionError is the error returned when the evaluation of a sub-expression fails.
+type SubExpressionError struct {
+	// failing sub-expression, as written in template
+	Expression string
+
+	// line of the sub-expression in template source
+	Line int
+
+	// evaluation error, like a *HelperError
+	Err error
+}
+
+// Error implements the error interface.
+func (err *SubExpressionError) Error() string {
+	return fmt.Sprintf("Sub-expression (%s) failed on line %d: %s", err.Expression, err.Line, err.Err)
+}
+
+// Unwrap returns the evaluation error, so that errors.As() finds a *HelperError raised by the sub-expression.
+func (err *SubExpressionError) Unwrap() error {
+	return err.Err
+}
+
 // VisitSubExpression implements corresponding Visitor interface method
 func (v *evalVisitor) VisitSubExpression(node *ast.SubExpression) interface{} {
 	v.at(node)
 
+	defer v.subExprRecover(node)
+
 	return node.Expression.Accept(v)
 }
 
+// subExprRecover recovers a sub-expression evaluation panic, and panics again with the failing sub-expression infos
+func (v *evalVisitor) subExprRecover(node *ast.SubExpression) {
+	e := recover()
+	if e == nil {
+		return
+	}
+
+	switch err := e.(type) {
+	case runtime.Error, *SafeModeError:
+		// not wrapped
+	case error:
+		v.fail(&SubExpressionError{Expression: node.Expression.Canonical(), Line: node.Loc.Line, Err: err})
+	}
+
+	panic(e)
+}
+
 // VisitPath implements corresponding Visitor interface method
 func (v *evalVisitor) VisitPath(node *ast.PathExpression) interface{} {
 	return v.evalPathExpression(node, false)
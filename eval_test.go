@@ -1,6 +1,13 @@
 package raymond
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
 
 var evalTests = []Test{
 	{
@@ -72,6 +79,142 @@ var evalTests = []Test{
 		nil, nil, nil,
 		"C",
 	},
+	{
+		"map with integer keys",
+		"{{data.2}} {{data.[3]}} [{{data.4}}] {{#each data}}{{@key}}{{/each}}",
+		map[string]interface{}{"data": map[int]string{2: "two", 3: "three"}},
+		nil, nil, nil,
+		"two three [] 23",
+	},
+	{
+		"slice with numeric path segment",
+		"{{items.1.name}}",
+		map[string]interface{}{"items": []map[string]string{{"name": "foo"}, {"name": "bar"}}},
+		nil, nil, nil,
+		"bar",
+	},
+	{
+		"partial with whitespace control",
+		"a {{~> p ~}} b",
+		nil, nil, nil,
+		map[string]string{"p": "X"},
+		"aXb",
+	},
+	{
+		"partial with left whitespace control",
+		"a\n  {{~> p}} b",
+		nil, nil, nil,
+		map[string]string{"p": "X"},
+		"aX b",
+	},
+	{
+		"nested hash sub-expressions are evaluated bottom-up",
+		"{{wrap val=(wrap val=(wrap val=(inc)))}}",
+		nil, nil,
+		map[string]interface{}{
+			"wrap": func(options *Options) string {
+				return "[" + options.HashStr("val") + "]"
+			},
+			"inc": func() int {
+				return 1
+			},
+		},
+		nil,
+		"[[[1]]]",
+	},
+	{
+		"nested hash sub-expressions with parameters",
+		"{{wrap val=(concat (wrap val=(concat a (wrap val=b))) c)}}",
+		map[string]string{"a": "A", "b": "B", "c": "C"},
+		nil,
+		map[string]interface{}{
+			"wrap": func(options *Options) string {
+				return "[" + options.HashStr("val") + "]"
+			},
+			"concat": func(a, b string) string {
+				return a + b
+			},
+		},
+		nil,
+		"[[A[B]]C]",
+	},
+	{
+		"escaped and unescaped mustaches",
+		`{{foo}} {{{foo}}} {{&foo}} {{~&foo~}} {{& foo }} {{{ foo }}}`,
+		map[string]string{"foo": "<b>&"},
+		nil, nil, nil,
+		`&lt;b&gt;&amp; <b>& <b>&<b>&<b>& <b>&`,
+	},
+	{
+		"inverted sections over plain paths",
+		`{{^missing}}a{{/missing}} {{^no}}b{{/no}} {{^empty}}c{{/empty}} {{^zero}}d{{/zero}} {{^yes}}e{{/yes}} {{^list}}f{{/list}} {{^person}}g{{/person}}`,
+		map[string]interface{}{
+			"no":     false,
+			"empty":  []string{},
+			"zero":   0,
+			"yes":    true,
+			"list":   []string{"foo"},
+			"person": map[string]string{"name": "foo"},
+		},
+		nil, nil, nil,
+		`a b c d   `,
+	},
+	{
+		"inverted sections over plain paths with else block",
+		`{{^list}}none{{else}}{{this}}{{/list}} {{^person}}none{{else}}{{name}}{{/person}} {{^missing}}none{{else}}some{{/missing}}`,
+		map[string]interface{}{
+			"list":   []string{"foo", "bar"},
+			"person": map[string]string{"name": "baz"},
+		},
+		nil, nil, nil,
+		`foobar baz none`,
+	},
+	{
+		"comments containing mustaches",
+		`{{!-- {{foo}} {{#if bar}}}}{{/if}} --}}a{{! {{foo}} }}b`,
+		map[string]string{"foo": "foo"},
+		nil, nil, nil,
+		`a }}b`,
+	},
+	{
+		"implicit block iteration over plain paths",
+		`{{#items}}{{@index}}:{{name}}{{#if @first}}^{{/if}}{{#if @last}}${{/if}} {{/items}}|{{#person}}{{name}}{{/person}}|{{#missing}}x{{else}}none{{/missing}}|{{#empty}}x{{else}}empty{{/empty}}`,
+		map[string]interface{}{
+			"items":  []map[string]string{{"name": "foo"}, {"name": "bar"}},
+			"person": struct{ Name string }{"baz"},
+			"empty":  map[string]string{},
+		},
+		nil, nil, nil,
+		`0:foo^ 1:bar$ |baz|none|empty`,
+	},
+	{
+		"parent paths in nested each blocks",
+		`{{#each groups}}{{#each items}}{{../name}}:{{this}}:{{../../name}} {{/each}}{{/each}}`,
+		map[string]interface{}{
+			"name": "root",
+			"groups": []map[string]interface{}{
+				{"name": "g1", "items": []string{"a", "b"}},
+				{"name": "g2", "items": []string{"c"}},
+			},
+		},
+		nil, nil, nil,
+		`g1:a:root g1:b:root g2:c:root `,
+	},
+	{
+		"parent paths in nested each blocks over structs, through blocks that keep the same context",
+		`{{#each groups}}{{#each Items}}{{#if this}}{{../Name}}/{{this}}{{/if}}{{#with this}}[{{../Name}}]{{/with}} {{/each}}{{/each}}`,
+		map[string]interface{}{
+			"groups": []struct {
+				Name  string
+				Items []string
+			}{
+				{"g1", []string{"a", ""}},
+				{"g2", []string{"c"}},
+			},
+		},
+		nil, nil, nil,
+		`g1/a[g1]  g2/c[g2] `,
+	},
 
 	// @todo Test with a "../../path" (depth 2 path) while context is only depth 1
 }
@@ -104,12 +247,209 @@ var evalErrors = []Test{
 		nil, nil, nil,
 		"Helper function must return a string or a SafeString",
 	},
+	{
+		"sub-expression helper error",
+		"{{outer (inner)}}",
+		nil, nil,
+		map[string]interface{}{
+			"outer": func(str string) string { return str },
+			"inner": func() string { panic(errors.New("inner failure")) },
+		},
+		nil,
+		"Sub-expression (inner) failed on line 1: Helper 'inner' panicked on line 1, column 9: inner failure",
+	},
+	{
+		"nested sub-expressions helper error",
+		"{{outer (outer (inner))}}",
+		nil, nil,
+		map[string]interface{}{
+			"outer": func(str string) string { return str },
+			"inner": func() string { panic(errors.New("inner failure")) },
+		},
+		nil,
+		"Sub-expression (outer) failed on line 1: Sub-expression (inner) failed on line 1:",
+	},
+	{
+		"helper recovering a block evaluation panic",
+		"{{#outer}}{{#each items}}{{inner}}{{/each}}{{/outer}}",
+		map[string]interface{}{"items": []string{"foo"}},
+		nil,
+		map[string]interface{}{
+			"outer": func(options *Options) (result string) {
+				defer func() {
+					if r := recover(); r != nil {
+						result = "recovered"
+					}
+				}()
+
+				return options.Fn()
+			},
+			"inner": func() string { panic(errors.New("inner failure")) },
+		},
+		nil,
+		"Helper 'outer' left the evaluation stacks unbalanced",
+	},
+	{
+		"helper panicking with a string",
+		"foo\n  {{fail}}",
+		nil, nil,
+		map[string]interface{}{"fail": func() string { panic("oops") }},
+		nil,
+		"Helper 'fail' panicked on line 2, column 3: oops",
+	},
+	{
+		"helper panicking with an error",
+		"{{#fail}}foo{{/fail}}",
+		nil, nil,
+		map[string]interface{}{"fail": func(options *Options) string { panic(errors.New("oops")) }},
+		nil,
+		"Helper 'fail' panicked on line 1, column 1: oops",
+	},
+	{
+		"helper panicking with an index out of range",
+		"{{first items}}",
+		map[string]interface{}{"items": []string{}},
+		nil,
+		map[string]interface{}{"first": func(items []string) string { return items[0] }},
+		nil,
+		"Helper 'first' panicked on line 1, column 1: runtime error: index out of range",
+	},
+	{
+		"context function panicking",
+		"{{foo.bar}}",
+		map[string]interface{}{"foo": map[string]interface{}{"bar": func() string { panic("oops") }}},
+		nil, nil, nil,
+		"Helper 'bar' panicked on line 1, column 3: oops",
+	},
 }
 
 func TestEvalErrors(t *testing.T) {
 	launchErrorTests(t, evalErrors)
 }
 
+func TestEvalHelperPanic(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{> item}}")
+	tpl.RegisterPartial("item", "foo\n{{#each items}}{{first this}}{{/each}}")
+	tpl.RegisterHelper("first", func(str string) string { return str[:3] })
+
+	ctx := map[string]interface{}{"items": []string{"abcd", "a"}}
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		_, err := tpl.Exec(ctx)
+
+		helperErr, ok := err.(*HelperError)
+		if !ok {
+			t.Errorf("Expected a *HelperError, got: %v", err)
+			continue
+		}
+
+		if (helperErr.Helper != "first") || (helperErr.Template != "item") || (helperErr.Line != 2) || (helperErr.Column != 16) {
+			t.Errorf("Wrong helper error: %+v", helperErr)
+		}
+
+		if _, ok := helperErr.Err.(runtime.Error); !ok {
+			t.Errorf("Expected a runtime error, got: %v", helperErr.Err)
+		}
+	}
+}
+
+func TestEvalSubExpressionHelperPanic(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{> item}}")
+	tpl.RegisterPartial("item", "foo\n{{outer (inner)}}")
+	tpl.RegisterHelper("outer", func(str string) string { return str })
+	tpl.RegisterHelper("inner", func() string { panic(errors.New("inner failure")) })
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		_, err := tpl.Exec(nil)
+
+		subErr, ok := err.(*SubExpressionError)
+		if !ok {
+			t.Errorf("Expected a *SubExpressionError, got: %v", err)
+			continue
+		}
+
+		if (subErr.Expression != "inner") || (subErr.Line != 2) {
+			t.Errorf("Wrong sub-expression error: %+v", subErr)
+		}
+
+		helperErr, ok := subErr.Err.(*HelperError)
+		if !ok {
+			t.Errorf("Expected a *HelperError, got: %v", subErr.Err)
+			continue
+		}
+
+		if (helperErr.Helper != "inner") || (helperErr.Template != "item") || (helperErr.Line != 2) || (helperErr.Column != 9) {
+			t.Errorf("Wrong helper error: %+v", helperErr)
+		}
+	}
+}
+
+func TestEvalBlockHelperEvaluationError(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{#each items}}{{this}}{{/each}}")
+
+	ctx := map[string]interface{}{"items": []interface{}{"foo", make(chan int)}}
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		_, err := tpl.Exec(ctx)
+		if err == nil {
+			t.Errorf("Expected an evaluation error")
+			continue
+		}
+
+		if _, ok := err.(*HelperError); ok {
+			t.Errorf("Evaluation error must not be reported as a helper error: %s", err)
+		}
+
+		if !strings.Contains(err.Error(), "Evaluation error: Can't print value") {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}
+}
+
+func TestEvalArrayContextMissingPath(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]interface{}{"outer": [][]string{{"a"}, {"b", "c"}}}
+
+	tpl := MustParse(`{{#each outer}}{{#each this}}[{{dump}}]{{/each}}{{/each}}`)
+	tpl.RegisterHelper("dump", func() string { return "helper" })
+	tpl.SetHelperPrecedence(FieldFirst)
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if output := tpl.MustExec(ctx); output != "[helper][helper][helper]" {
+			t.Errorf("Helper must be called when an array context does not resolve the path: %q", output)
+		}
+	}
+}
+
+func TestEvalJSONDecoded(t *testing.T) {
+	t.Parallel()
+
+	source := `{{name}} {{#if count}}{{count}} items{{else}}empty{{/if}} {{#unless zero}}zero{{/unless}} {{add count 1}} {{#equal price 12.5}}{{price}}{{/equal}} {{raw}}`
+
+	var ctx map[string]interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(`{"name": "<foo>", "count": 3, "zero": 0.0, "price": 12.50}`))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// text columns of database rows
+	ctx["raw"] = []byte("a & b")
+
+	if output := MustRender(source, ctx); output != "&lt;foo&gt; 3 items zero 4 12.50 a &amp; b" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+}
+
 func TestEvalStruct(t *testing.T) {
 	t.Parallel()
 
@@ -225,6 +565,237 @@ func TestEvalStructTag(t *testing.T) {
 	}
 }
 
+func TestEvalStructEmbedded(t *testing.T) {
+	t.Parallel()
+
+	type Timestamps struct {
+		CreatedAt string
+		UpdatedAt string `handlebars:"updated"`
+	}
+
+	type Audit struct {
+		CreatedAt string
+		CreatedBy string
+	}
+
+	type Base struct {
+		ID int `handlebars:"id"`
+		*Audit
+	}
+
+	type Post struct {
+		Base
+		Timestamps
+		Title string
+	}
+
+	source := `{{id}} {{title}} {{createdAt}} {{updated}} [{{createdBy}}]`
+
+	ctx := Post{
+		Base:       Base{ID: 42},
+		Timestamps: Timestamps{CreatedAt: "yesterday", UpdatedAt: "today"},
+		Title:      "Hello",
+	}
+
+	if output := MustRender(source, ctx); output != "42 Hello yesterday today []" {
+		t.Errorf("Failed to evaluate struct promoted fields: %s", output)
+	}
+
+	ctx.Audit = &Audit{CreatedAt: "never", CreatedBy: "jean"}
+
+	if output := MustRender(source, ctx); output != "42 Hello yesterday today [jean]" {
+		t.Errorf("Failed to evaluate struct promoted fields with the shallowest field: %s", output)
+	}
+}
+
+func TestEvalStructEmbeddedPromotion(t *testing.T) {
+	t.Parallel()
+
+	type Meta struct {
+		Title  string
+		Author string
+		Lang   string
+	}
+
+	type SEO struct {
+		Title string
+		Lang  string
+	}
+
+	type Layout struct {
+		*SEO
+		Theme string
+	}
+
+	type Page struct {
+		Meta
+		Layout
+		Body   string
+		Author string
+	}
+
+	// Title: Meta.Title is shallower than Layout.SEO.Title
+	// Author: Page.Author shadows Meta.Author
+	// Theme: promoted from a value embed
+	// Lang: Meta.Lang is shallower than Layout.SEO.Lang
+	source := `{{Title}} {{author}} {{theme}} {{lang}} {{body}}`
+
+	ctx := Page{
+		Meta:   Meta{Title: "Hello", Author: "meta", Lang: "en"},
+		Layout: Layout{Theme: "dark"},
+		Body:   "content",
+		Author: "jean",
+	}
+
+	if output := MustRender(source, ctx); output != "Hello jean dark en content" {
+		t.Errorf("Failed to evaluate promoted fields: %s", output)
+	}
+
+	type Left struct {
+		Name string
+	}
+
+	type Right struct {
+		Name string
+	}
+
+	type Deep struct {
+		Layout
+	}
+
+	type Both struct {
+		Left
+		*Right
+		Deep
+	}
+
+	// Name is ambiguous at the same depth, and nil embedded pointers resolve to nothing
+	source = `[{{name}}] [{{theme}}] [{{title}}]`
+
+	both := Both{Left{"left"}, &Right{"right"}, Deep{Layout{Theme: "light"}}}
+
+	if output := MustRender(source, both); output != "[] [light] []" {
+		t.Errorf("Failed to evaluate ambiguous and nil promoted fields: %s", output)
+	}
+
+	// two levels of nesting through a pointer embed
+	both.Deep.SEO = &SEO{Title: "deep"}
+
+	if output := MustRender(source, &both); output != "[] [light] [deep]" {
+		t.Errorf("Failed to evaluate promoted fields through a pointer embed: %s", output)
+	}
+}
+
+type recursiveNode struct {
+	*recursiveNode
+	Name  string `handlebars:"label"`
+	Child *recursiveNode
+}
+
+func TestEvalStructRecursiveEmbedded(t *testing.T) {
+	t.Parallel()
+
+	ctx := &recursiveNode{Name: "root", Child: &recursiveNode{Name: "child"}}
+
+	tpl := MustParse(`{{name}} {{label}} {{child.label}} {{missing}} {{child.missing}}`)
+	tpl.SetFieldMatching(LooseFieldMatching)
+
+	if output := tpl.MustExec(ctx); output != "root root child  " {
+		t.Errorf("Failed to evaluate fields of a recursive struct: %q", output)
+	}
+
+	// metadata is cached by type, whatever the names looked up
+	info := getTypeInfo(reflect.TypeOf(recursiveNode{}))
+	if (len(info.fields) != 2) || (len(info.tags) != 1) || (len(info.looseFields) != 2) {
+		t.Errorf("Unexpected struct metadata: %#v", info)
+	}
+}
+
+func TestEvalStructAmbiguousTag(t *testing.T) {
+	t.Parallel()
+
+	type Author struct {
+		Name string `handlebars:"label"`
+	}
+
+	type Editor struct {
+		Name string `handlebars:"label"`
+	}
+
+	type Publisher struct {
+		Name string `handlebars:"label"`
+	}
+
+	type Book struct {
+		Author
+		Editor
+	}
+
+	type Collection struct {
+		Book
+		Publisher
+		Title string `handlebars:"label"`
+	}
+
+	book := Book{Author{"author"}, Editor{"editor"}}
+
+	if output := MustRender(`[{{label}}]`, book); output != "[]" {
+		t.Errorf("Tags of embedded structs at the same depth must be ambiguous: %s", output)
+	}
+
+	collection := Collection{book, Publisher{"publisher"}, "title"}
+
+	if output := MustRender(`[{{label}}]`, collection); output != "[title]" {
+		t.Errorf("Shallowest tag must win: %s", output)
+	}
+}
+
+func TestEvalLooseFieldMatching(t *testing.T) {
+	t.Parallel()
+
+	type Audit struct {
+		CreatedBy string
+		UserID    int
+	}
+
+	type User struct {
+		Audit
+		FirstName string
+		LastName  string `handlebars:"surname"`
+		UserID    int
+		Foo_Bar   string
+		FooBar    string
+	}
+
+	source := `{{user.first_name}} {{user.FIRSTNAME}} {{user.surname}} {{user.created_by}} {{user.user_id}} [{{user.foo_bar}}] [{{user.unknown}}]`
+
+	ctx := map[string]interface{}{
+		"user": User{
+			Audit:     Audit{CreatedBy: "admin", UserID: 1},
+			FirstName: "Jean",
+			LastName:  "Valjean",
+			UserID:    24601,
+			Foo_Bar:   "foo",
+			FooBar:    "bar",
+		},
+	}
+
+	tpl := MustParse(source)
+
+	if output := tpl.MustExec(ctx); output != "  Valjean   [] []" {
+		t.Errorf("Loose field matching must be disabled by default: %q", output)
+	}
+
+	tpl.SetFieldMatching(LooseFieldMatching)
+
+	// the shallowest field wins, and fields matching at the same depth are ambiguous
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if output := tpl.MustExec(ctx); output != "Jean Jean Valjean admin 24601 [] []" {
+			t.Errorf("Failed to evaluate fields with loose matching: %q", output)
+		}
+	}
+}
+
 type TestFoo struct {
 }
 
@@ -270,3 +841,35 @@ func TestEvalMethodReturningFunc(t *testing.T) {
 		t.Errorf("Failed to evaluate struct method: %s", output)
 	}
 }
+
+// staticTemplate returns a template of given size, with static content and ten mustaches
+func staticTemplate(size int) string {
+	row := "<div class=\"row\">\n  <p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>\n</div>\n"
+	nb := size / len(row)
+
+	var b strings.Builder
+
+	for i := 0; i < nb; i++ {
+		b.WriteString(row)
+
+		if i%(nb/10) == 0 {
+			b.WriteString("  {{~title~}}  \n")
+		}
+	}
+
+	return b.String()
+}
+
+func TestEvalContentAllocs(t *testing.T) {
+	ctx := map[string]string{"title": "foo"}
+
+	small := MustParse(staticTemplate(10 * 1024))
+	large := MustParse(staticTemplate(1024 * 1024))
+
+	smallAllocs := testing.AllocsPerRun(10, func() { small.MustExec(ctx) })
+	largeAllocs := testing.AllocsPerRun(10, func() { large.MustExec(ctx) })
+
+	if largeAllocs > smallAllocs*2 {
+		t.Errorf("Rendering static content must not allocate per content size: %v allocations for 10KB, %v for 1MB", smallAllocs, largeAllocs)
+	}
+}
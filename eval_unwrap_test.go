@@ -0,0 +1,31 @@
+//go:build go1.13
+// +build go1.13
+
+package raymond
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvalSubExpressionErrorUnwrap(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{outer (outer (inner))}}")
+	tpl.RegisterHelper("outer", func(str string) string { return str })
+	tpl.RegisterHelper("inner", func() string { panic(errors.New("inner failure")) })
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		_, err := tpl.Exec(nil)
+
+		var helperErr *HelperError
+		if !errors.As(err, &helperErr) {
+			t.Errorf("Expected error to wrap a *HelperError, got: %v", err)
+			continue
+		}
+
+		if (helperErr.Helper != "inner") || (helperErr.Column != 16) {
+			t.Errorf("Wrong helper error: %+v", helperErr)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package raymond
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeInfos caches reflection metadata, built once for each type
+var typeInfos sync.Map // map[reflect.Type]*typeInfo
+
+// typeInfo holds the struct fields and methods indexes of a type
+type typeInfo struct {
+	// exported fields indexes, by field name, including promoted fields
+	fields map[string][]int
+
+	// fields indexes, by `handlebars` struct tag, with a nil value when several fields have that tag at the same depth
+	tags map[string][]int
+
+	// exported fields indexes, by loose name, with a nil value when several fields match at the same depth
+	looseFields map[string][]int
+
+	// methods indexes, by method name
+	methods map[string]int
+}
+
+// getTypeInfo returns the cached metadata of given type
+func getTypeInfo(typ reflect.Type) *typeInfo {
+	if info, ok := typeInfos.Load(typ); ok {
+		return info.(*typeInfo)
+	}
+
+	info, _ := typeInfos.LoadOrStore(typ, newTypeInfo(typ))
+
+	return info.(*typeInfo)
+}
+
+// newTypeInfo computes the metadata of given type
+//
+// Struct fields are collected one embedding level at a time, so that the shallowest field wins when several embedded
+// structs have a field with the same tag or loose name. Several fields with the same tag or loose name at the same
+// depth are ambiguous.
+func newTypeInfo(typ reflect.Type) *typeInfo {
+	info := &typeInfo{
+		methods: make(map[string]int, typ.NumMethod()),
+	}
+
+	for i := 0; i < typ.NumMethod(); i++ {
+		info.methods[typ.Method(i).Name] = i
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return info
+	}
+
+	info.fields = make(map[string][]int)
+	info.tags = make(map[string][]int)
+	info.looseFields = make(map[string][]int)
+
+	// embedded types already visited at a shallower level, as they can be recursive
+	visited := map[reflect.Type]bool{typ: true}
+
+	current := []structLevel{{typ, nil}}
+
+	for len(current) > 0 {
+		var next []structLevel
+		found := make(map[string][][]int)
+		foundTags := make(map[string][][]int)
+
+		for _, level := range current {
+			for i := 0; i < level.typ.NumField(); i++ {
+				tField := level.typ.Field(i)
+				index := append(append([]int{}, level.index...), i)
+
+				if tag := tField.Tag.Get("handlebars"); tag != "" {
+					foundTags[tag] = append(foundTags[tag], index)
+				}
+
+				if tField.PkgPath == "" {
+					if _, ok := info.fields[tField.Name]; !ok {
+						// Go resolves promoted fields, and their ambiguities
+						if promoted, ok := typ.FieldByName(tField.Name); ok && (promoted.PkgPath == "") {
+							info.fields[tField.Name] = promoted.Index
+						}
+					}
+
+					found[looseName(tField.Name)] = append(found[looseName(tField.Name)], index)
+				}
+
+				if embedded := embeddedStruct(tField); (embedded != nil) && !visited[embedded] {
+					next = append(next, structLevel{embedded, index})
+				}
+			}
+		}
+
+		addLevelIndexes(info.looseFields, found)
+		addLevelIndexes(info.tags, foundTags)
+
+		for _, level := range next {
+			visited[level.typ] = true
+		}
+
+		current = next
+	}
+
+	return info
+}
+
+// addLevelIndexes adds to given indexes the fields indexes found at an embedding level, by name, unless that name was
+// found at a shallower level
+//
+// Several fields found with the same name are ambiguous, so that name gets a nil index.
+func addLevelIndexes(indexes map[string][]int, found map[string][][]int) {
+	for name, levelIndexes := range found {
+		if _, ok := indexes[name]; ok {
+			// shadowed by a shallower field
+			continue
+		}
+
+		if len(levelIndexes) == 1 {
+			indexes[name] = levelIndexes[0]
+		} else {
+			// ambiguous
+			indexes[name] = nil
+		}
+	}
+}
+
+// structFieldIndex returns the index of the struct field that resolves given template variable name, or nil if not found
+//
+// The variable name is first resolved as an exported field name, eg: `firstName` => `FirstName`, and then as a
+// `handlebars` struct tag. Promoted fields of embedded structs are resolved too, and the shallowest field wins when
+// several embedded structs have a field with that tag. Several fields with that tag at the same depth are ambiguous, so
+// none of them is returned.
+func structFieldIndex(typ reflect.Type, name string) []int {
+	info := getTypeInfo(typ)
+
+	if index, ok := info.fields[strings.Title(name)]; ok {
+		return index
+	}
+
+	return info.tags[name]
+}
+
+// looseStructFieldIndex returns the index of the struct field whose name matches given template variable name ignoring
+// case and underscores, or nil if not found
+//
+// Like promoted fields in Go, the shallowest field wins, and several fields matching at the same depth are ambiguous so
+// none of them is returned.
+func looseStructFieldIndex(typ reflect.Type, name string) []int {
+	return getTypeInfo(typ).looseFields[looseName(name)]
+}
+
+// looseName returns given name in lower case and without underscores, eg: `first_name` => `firstname`
+func looseName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// structLevel is a struct type, embedded at given index
+type structLevel struct {
+	typ   reflect.Type
+	index []int
+}
+
+// embeddedStruct returns the struct type of given field if it is an embedded struct or pointer to struct, or nil otherwise
+func embeddedStruct(tField reflect.StructField) reflect.Type {
+	if !tField.Anonymous {
+		return nil
+	}
+
+	typ := tField.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return typ
+}
+
+// fieldByIndex returns the nested field corresponding to given index, or zero if an embedded struct pointer is nil
+func fieldByIndex(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if (i > 0) && (val.Kind() == reflect.Ptr) {
+			if val.IsNil() {
+				return zero
+			}
+
+			val = val.Elem()
+		}
+
+		val = val.Field(x)
+	}
+
+	return val
+}
+
+// methodIndex returns the index of the method that resolves given template variable name, or -1 if not found
+//
+// The variable name is resolved as is, and then with its first letter upper-cased, eg: `subject` => `Subject`.
+func methodIndex(typ reflect.Type, name string) int {
+	if typ.NumMethod() == 0 {
+		return -1
+	}
+
+	methods := getTypeInfo(typ).methods
+
+	if index, ok := methods[name]; ok {
+		return index
+	}
+
+	if index, ok := methods[strings.Title(name)]; ok {
+		return index
+	}
+
+	return -1
+}
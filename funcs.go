@@ -0,0 +1,92 @@
+package raymond
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// funcHelper adapts given `text/template` function to a helper
+//
+// The helper accepts any number of parameters, that are converted to function arguments types. A function returning a
+// value and an error panics with that error, so that it is returned by template execution as a *HelperError.
+func funcHelper(name string, fn interface{}) VariadicHelper {
+	funcVal := reflect.ValueOf(fn)
+	if funcVal.Kind() != reflect.Func {
+		panic(fmt.Errorf("Helper must be a function: %s", name))
+	}
+
+	funcType := funcVal.Type()
+
+	if !((funcType.NumOut() == 1) || ((funcType.NumOut() == 2) && (funcType.Out(1) == errorType))) {
+		panic(fmt.Errorf("Function must return a value, or a value and an error: %s", name))
+	}
+
+	return func(options *Options) interface{} {
+		results := funcVal.Call(funcArgs(name, funcType, options))
+
+		if (len(results) == 2) && !results[1].IsNil() {
+			panic(results[1].Interface().(error))
+		}
+
+		return results[0].Interface()
+	}
+}
+
+// funcArgs converts given parameters to the arguments of given function type
+func funcArgs(name string, funcType reflect.Type, options *Options) []reflect.Value {
+	params := options.Params()
+	numIn := funcType.NumIn()
+
+	if funcType.IsVariadic() {
+		if len(params) < numIn-1 {
+			panic(fmt.Errorf("Function %s called with wrong number of arguments, needed at least %d but got %d", name, numIn-1, len(params)))
+		}
+	} else if len(params) != numIn {
+		panic(fmt.Errorf("Function %s called with wrong number of arguments, needed %d but got %d", name, numIn, len(params)))
+	}
+
+	args := make([]reflect.Value, len(params))
+
+	for i, param := range params {
+		var argType reflect.Type
+		if funcType.IsVariadic() && (i >= numIn-1) {
+			argType = funcType.In(numIn - 1).Elem()
+		} else {
+			argType = funcType.In(i)
+		}
+
+		arg := reflect.ValueOf(param)
+
+		switch {
+		case !arg.IsValid():
+			arg = reflect.Zero(argType)
+		case arg.Type().AssignableTo(argType):
+			// as is
+		case isNumberKind(arg.Kind()) && isNumberKind(argType.Kind()):
+			arg = arg.Convert(argType)
+		case argType.Kind() == reflect.String:
+			arg = reflect.ValueOf(options.eval.str(param)).Convert(argType)
+		case argType.Kind() == reflect.Bool:
+			val, _ := isTrueValue(arg)
+			arg = reflect.ValueOf(val).Convert(argType)
+		default:
+			panic(fmt.Errorf("Function %s called with argument %d with type %s but it should be %s", name, i, arg.Type(), argType))
+		}
+
+		args[i] = arg
+	}
+
+	return args
+}
+
+// isNumberKind returns true if given kind is an integer or a float kind
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package raymond
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFuncs(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{add 1 2}} {{add count 3.0}} {{join "-" "a" "b" "c"}} {{join ","}} {{repeat name times}} {{#if (even count)}}even{{/if}}`)
+	tpl.Funcs(map[string]interface{}{
+		"add":  func(a int, b int) int { return a + b },
+		"join": func(sep string, items ...string) string { return strings.Join(items, sep) },
+		"repeat": func(s string, n int) (string, error) {
+			if n < 0 {
+				return "", errors.New("negative count")
+			}
+			return strings.Repeat(s, n), nil
+		},
+		"even": func(n int64) bool { return n%2 == 0 },
+	})
+
+	ctx := map[string]interface{}{"count": uint8(4), "name": "ab", "times": 2}
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if result, err := tpl.Exec(ctx); (err != nil) || (result != "3 7 a-b-c  abab even") {
+			t.Errorf("Failed to call functions: %q %v", result, err)
+		}
+	}
+
+	ctx["times"] = -1
+
+	_, err := tpl.Exec(ctx)
+	if helperErr, ok := err.(*HelperError); !ok || (helperErr.Helper != "repeat") || (helperErr.Err.Error() != "negative count") {
+		t.Errorf("Function error must be returned as a helper error: %v", err)
+	}
+
+	if _, err := MustParse(`{{add 1}}`).Funcs(map[string]interface{}{"add": func(a, b int) int { return a + b }}).Exec(nil); (err == nil) || !strings.Contains(err.Error(), "Function add called with wrong number of arguments, needed 2 but got 1") {
+		t.Errorf("Calling a function with wrong number of arguments must fail: %v", err)
+	}
+}
+
+func TestFuncsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, fn := range []interface{}{"foo", func() {}, func() (int, int) { return 0, 0 }} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Registering an invalid function must panic: %T", fn)
+				}
+			}()
+
+			MustParse("").Funcs(map[string]interface{}{"foo": fn})
+		}()
+	}
+}
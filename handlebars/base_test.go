@@ -58,14 +58,21 @@ func launchTests(t *testing.T, tests []Test) {
 			// setup private data frame
 			var privData *raymond.DataFrame
 			if test.privData != nil {
-				privData = raymond.NewDataFrame()
-				for k, v := range test.privData {
-					privData.Set(k, v)
-				}
+				privData = raymond.NewDataFrameFromMap(test.privData)
 			}
 
+			// render compiled template
+			compiled := tpl.Clone().MustCompile()
+			compiledOutput, compiledErr := compiled.ExecWith(test.data, privData)
+
 			// render template
 			output, err := tpl.ExecWith(test.data, privData)
+			if (err == nil) != (compiledErr == nil) {
+				t.Errorf("Test '%s' failed - Compiled template error differs\ninput:\n\t'%s'\ncompiled\n\t%v\ngot\n\t%v", test.name, test.input, compiledErr, err)
+			} else if (err == nil) && (compiledOutput != output) {
+				t.Errorf("Test '%s' failed - Compiled template output differs\ninput:\n\t'%s'\ncompiled\n\t%q\ngot\n\t%q", test.name, test.input, compiledOutput, output)
+			}
+
 			if err != nil {
 				t.Errorf("Test '%s' failed\ninput:\n\t'%s'\ndata:\n\t%s\nerror:\n\t%s\nAST:\n\t%s", test.name, test.input, raymond.Str(test.data), err, tpl.PrintAST())
 			} else {
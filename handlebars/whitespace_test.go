@@ -208,6 +208,33 @@ var whitespaceControlTests = []Test{
 		nil, nil, nil, nil,
 		"baz",
 	},
+	{
+		"should strip whitespace around chained inverse block calls (1)",
+		"{{#if foo~}} bar {{~else if baz~}} bat {{~else~}} qux {{~/if}}",
+		map[string]string{"foo": "bar<"},
+		nil, nil, nil,
+		"bar",
+	},
+	{
+		"should strip whitespace around chained inverse block calls (2)",
+		"{{#if foo~}} bar {{~else if baz~}} bat {{~else~}} qux {{~/if}}",
+		map[string]string{"baz": "bat<"},
+		nil, nil, nil,
+		"bat",
+	},
+	{
+		"should strip whitespace around chained inverse block calls (3)",
+		"{{#if foo}} bar {{~else if baz}} bat {{^~}} qux {{/if}}",
+		map[string]string{"foo": "bar<"},
+		nil, nil, nil,
+		" bar",
+	},
+	{
+		"should strip whitespace around chained inverse block calls (4)",
+		"{{#if foo}} bar {{~else if baz}} bat {{^~}} qux {{/if}}",
+		nil, nil, nil, nil,
+		"qux ",
+	},
 
 	{
 		"should strip whitespace around partials (1)",
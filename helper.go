@@ -1,10 +1,16 @@
 package raymond
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/aymerick/raymond/ast"
 )
 
 // Options represents the options argument provided to helpers and context functions.
@@ -12,14 +18,56 @@ type Options struct {
 	// evaluation visitor
 	eval *evalVisitor
 
+	// helper or context function name
+	name string
+
 	// params
 	params []interface{}
 	hash   map[string]interface{}
+
+	// output buffer of a compiled block, that block programs are executed in instead of being evaluated to strings
+	out *bytes.Buffer
+}
+
+// VariadicHelper is a helper that accepts any number of parameters, that it gets with the Options methods like
+// Params(), NumParams() or Require().
+//
+// Other helpers must be called with as many parameters as their function arguments, even when they only expect an
+// options argument.
+type VariadicHelper func(options *Options) interface{}
+
+// HelperError is the error returned when a helper or a context function panics during evaluation.
+type HelperError struct {
+	// helper or context function name
+	Helper string
+
+	// name of the partial or of the named template being evaluated, if any
+	Template string
+
+	// position of the helper call in template source
+	Line   int
+	Column int
+
+	// panic value
+	Err error
+}
+
+// Error implements the error interface.
+func (err *HelperError) Error() string {
+	in := ""
+	if err.Template != "" {
+		in = fmt.Sprintf(" in template '%s'", err.Template)
+	}
+
+	return fmt.Sprintf("Helper '%s' panicked%s on line %d, column %d: %s", err.Helper, in, err.Line, err.Column, err.Err)
 }
 
 // helpers stores all globally registered helpers
 var helpers = make(map[string]reflect.Value)
 
+// builtinHelpers stores names of builtin helpers that are still registered
+var builtinHelpers = make(map[string]bool)
+
 // protects global helpers
 var helpersMutex sync.RWMutex
 
@@ -29,9 +77,45 @@ func init() {
 	RegisterHelper("unless", unlessHelper)
 	RegisterHelper("with", withHelper)
 	RegisterHelper("each", eachHelper)
+	RegisterHelper("times", timesHelper)
+	RegisterHelper("range", rangeHelper)
 	RegisterHelper("log", logHelper)
 	RegisterHelper("lookup", lookupHelper)
 	RegisterHelper("equal", equalHelper)
+	RegisterHelper("default", VariadicHelper(defaultHelper))
+	RegisterHelper("json", jsonHelper)
+	RegisterHelper("join", joinHelper)
+	RegisterHelper("date", dateHelper)
+	RegisterHelper("t", translateHelper)
+
+	// register math helpers
+	RegisterHelper("add", addHelper)
+	RegisterHelper("sub", subHelper)
+	RegisterHelper("mul", mulHelper)
+	RegisterHelper("div", divHelper)
+	RegisterHelper("mod", modHelper)
+	RegisterHelper("round", roundHelper)
+	RegisterHelper("number", numberHelper)
+
+	// register string helpers
+	RegisterHelper("upper", upperHelper)
+	RegisterHelper("lower", lowerHelper)
+	RegisterHelper("trim", trimHelper)
+	RegisterHelper("capitalize", capitalizeHelper)
+
+	// register URL helpers
+	RegisterHelper("urlEncode", urlEncodeHelper)
+	RegisterHelper("pathEncode", pathEncodeHelper)
+	RegisterHelper("buildQuery", buildQueryHelper)
+
+	// register contextual escaping helpers
+	RegisterHelper("escapeJS", escapeJSHelper)
+	RegisterHelper("escapeCSS", escapeCSSHelper)
+	RegisterHelper("attr", attrHelper)
+
+	for name := range helpers {
+		builtinHelpers[name] = true
+	}
 }
 
 // RegisterHelper registers a global helper. That helper will be available to all templates.
@@ -56,12 +140,21 @@ func RegisterHelpers(helpers map[string]interface{}) {
 	}
 }
 
+// RegisterHelperNamespace registers several global helpers under given namespace. Those helpers are called with the
+// namespace as a prefix, like `{{str.upper name}}` for the `upper` helper of the `str` namespace.
+func RegisterHelperNamespace(namespace string, helpers map[string]interface{}) {
+	for name, helper := range helpers {
+		RegisterHelper(namespace+"."+name, helper)
+	}
+}
+
 // RemoveHelper unregisters a global helper
 func RemoveHelper(name string) {
 	helpersMutex.Lock()
 	defer helpersMutex.Unlock()
 
 	delete(helpers, name)
+	delete(builtinHelpers, name)
 }
 
 // RemoveAllHelpers unregisters all global helpers
@@ -70,6 +163,36 @@ func RemoveAllHelpers() {
 	defer helpersMutex.Unlock()
 
 	helpers = make(map[string]reflect.Value)
+	builtinHelpers = make(map[string]bool)
+}
+
+// HelperNames returns sorted names of all global helpers, builtin helpers included
+func HelperNames() []string {
+	helpersMutex.RLock()
+	defer helpersMutex.RUnlock()
+
+	return helperNames(helpers)
+}
+
+// IsBuiltinHelper returns true if given name is a builtin helper that is still registered
+func IsBuiltinHelper(name string) bool {
+	helpersMutex.RLock()
+	defer helpersMutex.RUnlock()
+
+	return builtinHelpers[name]
+}
+
+// helperNames returns sorted names of given helpers
+func helperNames(helpers map[string]reflect.Value) []string {
+	result := make([]string, 0, len(helpers))
+
+	for name := range helpers {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
 }
 
 // ensureValidHelper panics if given helper is not valid
@@ -96,22 +219,29 @@ func findHelper(name string) reflect.Value {
 }
 
 // newOptions instanciates a new Options
-func newOptions(eval *evalVisitor, params []interface{}, hash map[string]interface{}) *Options {
+func newOptions(eval *evalVisitor, name string, params []interface{}, hash map[string]interface{}) *Options {
 	return &Options{
 		eval:   eval,
+		name:   name,
 		params: params,
 		hash:   hash,
 	}
 }
 
 // newEmptyOptions instanciates a new empty Options
-func newEmptyOptions(eval *evalVisitor) *Options {
+func newEmptyOptions(eval *evalVisitor, name string) *Options {
 	return &Options{
 		eval: eval,
+		name: name,
 		hash: make(map[string]interface{}),
 	}
 }
 
+// Name returns the name of the called helper or context function.
+func (options *Options) Name() string {
+	return options.name
+}
+
 //
 // Context Values
 //
@@ -128,12 +258,17 @@ func (options *Options) Value(name string) interface{} {
 
 // ValueStr returns string representation of field value from current context.
 func (options *Options) ValueStr(name string) string {
-	return Str(options.Value(name))
+	return options.eval.str(options.Value(name))
 }
 
-// Ctx returns current evaluation context.
+// Ctx returns current evaluation context, or nil if there is none.
 func (options *Options) Ctx() interface{} {
-	return options.eval.curCtx().Interface()
+	ctx := options.eval.curCtx()
+	if !ctx.IsValid() {
+		return nil
+	}
+
+	return ctx.Interface()
 }
 
 //
@@ -147,7 +282,7 @@ func (options *Options) HashProp(name string) interface{} {
 
 // HashStr returns string representation of hash property.
 func (options *Options) HashStr(name string) string {
-	return Str(options.hash[name])
+	return options.eval.str(options.hash[name])
 }
 
 // Hash returns entire hash.
@@ -155,6 +290,19 @@ func (options *Options) Hash() map[string]interface{} {
 	return options.hash
 }
 
+// HashKeys returns sorted hash keys.
+func (options *Options) HashKeys() []string {
+	result := make([]string, 0, len(options.hash))
+
+	for key := range options.hash {
+		result = append(result, key)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
 //
 // Parameters
 //
@@ -170,7 +318,7 @@ func (options *Options) Param(pos int) interface{} {
 
 // ParamStr returns string representation of parameter at given position.
 func (options *Options) ParamStr(pos int) string {
-	return Str(options.Param(pos))
+	return options.eval.str(options.Param(pos))
 }
 
 // Params returns all parameters.
@@ -178,6 +326,44 @@ func (options *Options) Params() []interface{} {
 	return options.params
 }
 
+// NumParams returns the number of parameters.
+func (options *Options) NumParams() int {
+	return len(options.params)
+}
+
+// Require returns an error naming the helper if less than n parameters were provided.
+func (options *Options) Require(n int) error {
+	if len(options.params) < n {
+		return fmt.Errorf("Helper %s requires %d parameters, got %d", options.name, n, len(options.params))
+	}
+
+	return nil
+}
+
+// RequireTypes returns an error naming the helper if parameters do not have given kinds, in order.
+//
+// Pointers are not dereferenced, and reflect.Interface matches any value except nil. Parameters beyond given kinds are
+// not checked.
+func (options *Options) RequireTypes(kinds ...reflect.Kind) error {
+	if err := options.Require(len(kinds)); err != nil {
+		return err
+	}
+
+	for i, kind := range kinds {
+		val := reflect.ValueOf(options.params[i])
+
+		if !val.IsValid() {
+			return fmt.Errorf("Helper %s parameter %d must be a %s, got nil", options.name, i, kind)
+		}
+
+		if (kind != reflect.Interface) && (val.Kind() != kind) {
+			return fmt.Errorf("Helper %s parameter %d must be a %s, got %s", options.name, i, kind, val.Kind())
+		}
+	}
+
+	return nil
+}
+
 //
 // Private data
 //
@@ -189,7 +375,7 @@ func (options *Options) Data(name string) interface{} {
 
 // DataStr returns string representation of private data value.
 func (options *Options) DataStr(name string) string {
-	return Str(options.eval.dataFrame.Get(name))
+	return options.eval.str(options.eval.dataFrame.Get(name))
 }
 
 // DataFrame returns current private data frame.
@@ -215,15 +401,30 @@ func (options *Options) newIterDataFrame(length int, i int, key interface{}) *Da
 
 // evalBlock evaluates block with given context, private data and iteration key
 func (options *Options) evalBlock(ctx interface{}, data *DataFrame, key interface{}) string {
+	return options.evalBlockParams(ctx, data, key, nil)
+}
+
+// evalBlockParams evaluates block with given context, private data, iteration key and block params values
+func (options *Options) evalBlockParams(ctx interface{}, data *DataFrame, key interface{}, params []interface{}) string {
 	result := ""
 
 	if block := options.eval.curBlock(); (block != nil) && (block.Program != nil) {
-		result = options.eval.evalProgram(block.Program, ctx, data, key)
+		result = options.evalProgram(block.Program, ctx, data, key, params)
 	}
 
 	return result
 }
 
+// evalProgram evaluates given block program, or executes it in the output buffer of a compiled block
+func (options *Options) evalProgram(program *ast.Program, ctx interface{}, data *DataFrame, key interface{}, params []interface{}) string {
+	if options.out != nil {
+		options.eval.execProgram(options.out, program, ctx, data, key, params)
+		return ""
+	}
+
+	return options.eval.evalProgram(program, ctx, data, key, params)
+}
+
 // Fn evaluates block with current evaluation context.
 func (options *Options) Fn() string {
 	return options.evalBlock(nil, nil, nil)
@@ -244,16 +445,129 @@ func (options *Options) FnData(data *DataFrame) string {
 	return options.evalBlock(nil, data, nil)
 }
 
+// FnBlockParams evaluates block with given context, and with given values for the block params declared with
+// `as |a b|`.
+func (options *Options) FnBlockParams(ctx interface{}, params ...interface{}) string {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	return options.evalBlockParams(ctx, nil, nil, params)
+}
+
+// BlockParams returns the names of the block params declared with `as |a b|`, or nil if there is none.
+func (options *Options) BlockParams() []string {
+	// a helper called in a block program is not that block helper
+	block := options.eval.curBlock()
+	if (block != nil) && (block.Expression == options.eval.curExpr()) && (block.Program != nil) {
+		return block.Program.BlockParams
+	}
+
+	return nil
+}
+
+// CheckBlockParams returns an error if the block declares more block params with `as |a b|` than given number, that is
+// the number of block params the helper provides.
+func (options *Options) CheckBlockParams(n int) error {
+	if declared := len(options.BlockParams()); declared > n {
+		return fmt.Errorf("Helper %s provides %d block params, but %d are declared", options.name, n, declared)
+	}
+
+	return nil
+}
+
+// checkStrictBlockParams panics if block params are checked, and if the block declares more block params than given
+// number
+func (options *Options) checkStrictBlockParams(n int) {
+	if !options.eval.strictBlockParams {
+		return
+	}
+
+	if err := options.CheckBlockParams(n); err != nil {
+		panic(err)
+	}
+}
+
+// RenderString evaluates given template source with current context and private data, and with the helpers and partials
+// of the template being evaluated.
+//
+// The result is a SafeString, as it was already escaped during evaluation. Parsed sources are cached. A parsing error is
+// returned, whereas an evaluation error fails the whole template evaluation, as in a partial.
+func (options *Options) RenderString(source string) (SafeString, error) {
+	tpl, err := renderTemplates.parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	return SafeString(options.eval.evalSubTemplate(tpl)), nil
+}
+
+// Iterate evaluates block for each item of given array, slice, map or struct, like the #each helper does, or evaluates
+// "else block" if there is nothing to iterate.
+//
+// The `@index`, `@key`, `@first` and `@last` private data, and block params, are set for each item.
+func (options *Options) Iterate(items interface{}) string {
+	if !IsTrue(items) {
+		return options.Inverse()
+	}
+
+	all := options.eachItems(reflect.ValueOf(items))
+	if len(all) == 0 {
+		return options.Inverse()
+	}
+
+	options.eval.checkIterations(len(all))
+
+	return options.iterate(all)
+}
+
 // Inverse evaluates "else block".
 func (options *Options) Inverse() string {
 	result := ""
 	if block := options.eval.curBlock(); (block != nil) && (block.Inverse != nil) {
-		result, _ = block.Inverse.Accept(options.eval).(string)
+		if options.out != nil {
+			options.eval.execInverse(options.out, block.Inverse)
+		} else {
+			result, _ = block.Inverse.Accept(options.eval).(string)
+		}
 	}
 
 	return result
 }
 
+// InverseWith evaluates "else block" with given context.
+func (options *Options) InverseWith(ctx interface{}) string {
+	result := ""
+	if block := options.eval.curBlock(); (block != nil) && (block.Inverse != nil) {
+		result = options.evalProgram(block.Inverse, ctx, nil, nil, nil)
+	}
+
+	return result
+}
+
+// RegisterHelper registers a helper on the template being evaluated, so that it is available to the rest of that
+// evaluation and to the following ones.
+//
+// In safe mode, that evaluation fails with a *SafeModeError instead.
+func (options *Options) RegisterHelper(name string, helper interface{}) {
+	options.eval.checkRegistration("Helper", name)
+
+	options.eval.tpl.RegisterHelper(name, helper)
+
+	// forget a previous lookup of that name, that may have missed
+	delete(options.eval.helpers, name)
+}
+
+// RegisterPartial registers a partial on the template being evaluated, so that it is available to the rest of that
+// evaluation and to the following ones.
+//
+// In safe mode, that evaluation fails with a *SafeModeError instead.
+func (options *Options) RegisterPartial(name string, source string) {
+	options.eval.checkRegistration("Partial", name)
+
+	options.eval.tpl.RegisterPartial(name, source)
+}
+
 // Eval evaluates field for given context.
 func (options *Options) Eval(ctx interface{}, field string) interface{} {
 	if ctx == nil {
@@ -295,6 +609,8 @@ func (options *Options) isIncludableZero() bool {
 
 // #if block helper
 func ifHelper(conditional interface{}, options *Options) interface{} {
+	options.checkStrictBlockParams(0)
+
 	if options.isIncludableZero() || IsTrue(conditional) {
 		return options.Fn()
 	}
@@ -304,6 +620,8 @@ func ifHelper(conditional interface{}, options *Options) interface{} {
 
 // #unless block helper
 func unlessHelper(conditional interface{}, options *Options) interface{} {
+	options.checkStrictBlockParams(0)
+
 	if options.isIncludableZero() || IsTrue(conditional) {
 		return options.Inverse()
 	}
@@ -313,6 +631,8 @@ func unlessHelper(conditional interface{}, options *Options) interface{} {
 
 // #with block helper
 func withHelper(context interface{}, options *Options) interface{} {
+	options.checkStrictBlockParams(1)
+
 	if IsTrue(context) {
 		return options.FnWith(context)
 	}
@@ -320,60 +640,225 @@ func withHelper(context interface{}, options *Options) interface{} {
 	return options.Inverse()
 }
 
+// eachItem represents an item iterated by the #each helper
+type eachItem struct {
+	key interface{}
+	ctx interface{}
+}
+
 // #each block helper
+//
+// The `reverse`, `offset` and `limit` hash options are applied in that order before iteration.
 func eachHelper(context interface{}, options *Options) interface{} {
-	if !IsTrue(context) {
+	options.checkStrictBlockParams(2)
+
+	if val := reflect.ValueOf(context); val.Kind() == reflect.Chan {
+		return options.iterateChan(val)
+	}
+
+	items, ok := options.eachWindow(context)
+	if !ok {
 		return options.Inverse()
 	}
 
-	result := ""
+	return options.iterate(items)
+}
+
+// iterate evaluates block for each given item, with iteration private data and block params
+func (options *Options) iterate(items []eachItem) string {
+	var result strings.Builder
+
+	for i, item := range items {
+		// computes private data
+		data := options.newIterDataFrame(len(items), i, item.key)
+
+		// evaluates block
+		result.WriteString(options.evalBlock(item.ctx, data, item.blockKey(i)))
+	}
+
+	return result.String()
+}
+
+// iterateChan evaluates block for each value received from given channel until it is closed, with iteration private
+// data and block params
+func (options *Options) iterateChan(ch reflect.Value) string {
+	var result strings.Builder
+
+	ok := options.eachChan(ch, func(ctx interface{}, data *DataFrame, i int) {
+		result.WriteString(options.evalBlock(ctx, data, i))
+	})
+	if !ok {
+		return options.Inverse()
+	}
+
+	return result.String()
+}
+
+// eachChan calls given function for each value received from given channel until it is closed, with iteration private
+// data, and returns false if "else block" must be evaluated instead
+//
+// Channel length is unknown, so @last is not set, and hash arguments are ignored. The "else block" is evaluated if the
+// channel is nil, or if it is closed without sending any value.
+func (options *Options) eachChan(ch reflect.Value, fn func(ctx interface{}, data *DataFrame, i int)) bool {
+	if ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		options.eval.errorf("Can't iterate over a send-only channel: %s", ch.Type())
+	}
+
+	if ch.IsNil() {
+		return false
+	}
+
+	i := 0
+	for {
+		val, ok := ch.Recv()
+		if !ok {
+			break
+		}
+
+		options.eval.checkIterations(i + 1)
+
+		// computes private data
+		data := options.eval.dataFrame.newStreamDataFrame(i)
+
+		fn(val.Interface(), data, i)
+
+		i++
+	}
+
+	return i > 0
+}
+
+// eachWindow returns the items iterated by the #each helper, with a boolean set to false if "else block" must be evaluated instead
+func (options *Options) eachWindow(context interface{}) ([]eachItem, bool) {
+	if !IsTrue(context) {
+		return nil, false
+	}
+
+	items := options.eachItems(reflect.ValueOf(context))
+
+	if b, ok := options.HashProp("reverse").(bool); ok && b {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if prop := options.HashProp("offset"); prop != nil {
+		offset, _ := options.numberValue("each", prop)
+		if (int(offset) < 0) || (int(offset) >= len(items)) {
+			return nil, false
+		}
+
+		items = items[int(offset):]
+	}
+
+	if prop := options.HashProp("limit"); prop != nil {
+		// limit=0 means unlimited
+		limit, _ := options.numberValue("each", prop)
+		if (int(limit) > 0) && (int(limit) < len(items)) {
+			items = items[:int(limit)]
+		}
+	}
+
+	options.eval.checkIterations(len(items))
+
+	return items, true
+}
+
+// blockKey returns the block param key of item at given iteration index
+//
+// It is the map key or struct field name, and the iteration index otherwise.
+func (item eachItem) blockKey(i int) interface{} {
+	if item.key == nil {
+		return i
+	}
+
+	return item.key
+}
+
+// eachItems returns the items to iterate for given value
+func (options *Options) eachItems(val reflect.Value) []eachItem {
+	if result, ok := orderedMapItems(val); ok {
+		return result
+	}
+
+	var result []eachItem
 
-	val := reflect.ValueOf(context)
 	switch val.Kind() {
 	case reflect.Array, reflect.Slice:
+		result = make([]eachItem, 0, val.Len())
 		for i := 0; i < val.Len(); i++ {
-			// computes private data
-			data := options.newIterDataFrame(val.Len(), i, nil)
-
-			// evaluates block
-			result += options.evalBlock(val.Index(i).Interface(), data, i)
+			result = append(result, eachItem{nil, val.Index(i).Interface()})
 		}
 	case reflect.Map:
-		// note: a go hash is not ordered, so result may vary, this behaviour differs from the JS implementation
-		keys := val.MapKeys()
-		for i := 0; i < len(keys); i++ {
-			key := keys[i].Interface()
-			ctx := val.MapIndex(keys[i]).Interface()
+		// note: a go hash is not ordered, so keys are sorted, this behaviour differs from the JS implementation
+		result = make([]eachItem, 0, val.Len())
+		options.eval.checkPrintKeys(val)
 
-			// computes private data
-			data := options.newIterDataFrame(len(keys), i, key)
-
-			// evaluates block
-			result += options.evalBlock(ctx, data, key)
+		for _, key := range sortedMapKeys(val) {
+			result = append(result, eachItem{key.Interface(), val.MapIndex(key).Interface()})
 		}
 	case reflect.Struct:
-		var exportedFields []int
-
 		// collect exported fields only
 		for i := 0; i < val.NumField(); i++ {
 			if tField := val.Type().Field(i); tField.PkgPath == "" {
-				exportedFields = append(exportedFields, i)
+				result = append(result, eachItem{tField.Name, val.Field(i).Interface()})
 			}
 		}
+	}
 
-		for i, fieldIndex := range exportedFields {
-			key := val.Type().Field(fieldIndex).Name
-			ctx := val.Field(fieldIndex).Interface()
+	return result
+}
 
-			// computes private data
-			data := options.newIterDataFrame(len(exportedFields), i, key)
+// #times block helper
+func timesHelper(count interface{}, options *Options) interface{} {
+	val, _ := options.numberValue("times", count)
 
-			// evaluates block
-			result += options.evalBlock(ctx, data, key)
-		}
+	return options.evalRange(0, int(val), 1)
+}
+
+// #range block helper
+//
+// Iterates from start (inclusive) to end (exclusive), the `step` hash option can be negative and defaults to 1.
+func rangeHelper(start interface{}, end interface{}, options *Options) interface{} {
+	valStart, _ := options.numberValue("range", start)
+	valEnd, _ := options.numberValue("range", end)
+
+	step := 1.0
+	if prop := options.HashProp("step"); prop != nil {
+		step, _ = options.numberValue("range", prop)
 	}
 
-	return result
+	if int(step) == 0 {
+		options.eval.errorf("Helper 'range' called with a zero step")
+	}
+
+	return options.evalRange(int(valStart), int(valEnd), int(step))
+}
+
+// evalRange evaluates block for each number from start (inclusive) to end (exclusive), or evaluates "else block" if there is none
+func (options *Options) evalRange(start int, end int, step int) string {
+	length := 0
+	if (end-start)*step > 0 {
+		length = (end - start + step - sign(step)) / step
+	}
+
+	if length == 0 {
+		return options.Inverse()
+	}
+
+	options.eval.checkIterations(length)
+
+	var result strings.Builder
+
+	for i := 0; i < length; i++ {
+		// computes private data
+		data := options.newIterDataFrame(length, i, nil)
+
+		// evaluates block
+		result.WriteString(options.evalBlock(start+i*step, data, i))
+	}
+
+	return result.String()
 }
 
 // #log helper
@@ -384,15 +869,103 @@ func logHelper(message string) interface{} {
 
 // #lookup helper
 func lookupHelper(obj interface{}, field string, options *Options) interface{} {
-	return Str(options.Eval(obj, field))
+	return options.eval.str(options.Eval(obj, field))
 }
 
 // #equal helper
 // Ref: https://github.com/aymerick/raymond/issues/7
+//
+// Numbers, including json.Number values, are compared by value, so that `1` equals `1.0`.
 func equalHelper(a interface{}, b interface{}, options *Options) interface{} {
-	if Str(a) == Str(b) {
+	if options.valuesEqual(a, b) {
 		return options.Fn()
 	}
 
 	return ""
 }
+
+// valuesEqual returns true if given numbers are equal, or if given values have the same string representation
+func (options *Options) valuesEqual(a interface{}, b interface{}) bool {
+	va, vb := jsonInteger(reflect.ValueOf(a)), jsonInteger(reflect.ValueOf(b))
+
+	// integers are compared exactly, as they may not be represented by a float64
+	if equal, ok := integersEqual(va, vb); ok {
+		return equal
+	}
+
+	if fa, ok := numberFloat(va); ok {
+		if fb, ok := numberFloat(vb); ok {
+			return fa == fb
+		}
+	}
+
+	return options.eval.str(a) == options.eval.str(b)
+}
+
+// #default helper
+//
+// Returns the first parameter that is present, ie. not nil and not an empty string. With the `strict=false` hash
+// option, returns the first truthy parameter instead.
+func defaultHelper(options *Options) interface{} {
+	strict := true
+	if b, ok := options.HashProp("strict").(bool); ok {
+		strict = b
+	}
+
+	for _, param := range options.Params() {
+		if strict {
+			if (param != nil) && (param != "") {
+				return param
+			}
+		} else if IsTrue(param) {
+			return param
+		}
+	}
+
+	return nil
+}
+
+// #json helper
+//
+// Returns given value marshaled to JSON. The `indent` hash option sets the number of spaces used to pretty-print it.
+// HTML special characters are escaped as unicode sequences, so that the result can be safely placed inside a <script>
+// element.
+func jsonHelper(value interface{}, options *Options) SafeString {
+	indent := 0
+	if prop := options.HashProp("indent"); prop != nil {
+		i, _ := options.numberValue("json", prop)
+		indent = int(i)
+	}
+
+	var result []byte
+	var err error
+
+	if indent > 0 {
+		result, err = json.MarshalIndent(value, "", strings.Repeat(" ", indent))
+	} else {
+		result, err = json.Marshal(value)
+	}
+
+	if err != nil {
+		options.eval.errorf("Helper 'json' failed to marshal value: %s", err)
+	}
+
+	return SafeString(result)
+}
+
+// #t helper
+//
+// Translates given key with the template translator, or the global one, using hash arguments as interpolation params.
+// Returns the key itself if there is no translator.
+func translateHelper(key string, options *Options) interface{} {
+	translator := options.eval.tpl.findTranslator()
+	if translator == nil {
+		translator = findTranslator()
+	}
+
+	if translator == nil {
+		return key
+	}
+
+	return translator(key, options.Hash())
+}
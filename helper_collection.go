@@ -0,0 +1,173 @@
+package raymond
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RegisterCollectionHelpers registers the global `first`, `last`, `length`, `slice` and `contains` helpers.
+//
+// Those helpers are not registered by default because their names are likely to collide with context fields. They
+// work on arrays, slices and strings, and return nil for other values.
+func RegisterCollectionHelpers() {
+	RegisterHelpers(collectionHelpers)
+}
+
+// collectionHelpers stores all collection helpers
+var collectionHelpers = map[string]interface{}{
+	"first":    firstHelper,
+	"last":     lastHelper,
+	"length":   lengthHelper,
+	"slice":    sliceHelper,
+	"contains": containsHelper,
+}
+
+// collectionValue returns given value if it is an array, a slice or a string, with a string converted to a slice of runes
+func collectionValue(value interface{}) (result reflect.Value, isStr bool, ok bool) {
+	val, _ := indirect(reflect.ValueOf(value))
+
+	switch val.Kind() {
+	case reflect.Array:
+		// an array must be addressable to be sliced
+		result = reflect.New(val.Type()).Elem()
+		result.Set(val)
+
+		return result, false, true
+	case reflect.Slice:
+		return val, false, true
+	case reflect.String:
+		return reflect.ValueOf([]rune(val.String())), true, true
+	}
+
+	return zero, false, false
+}
+
+// collectionItem returns the item at given index, with a rune converted back to a string
+func collectionItem(collection reflect.Value, isStr bool, i int) interface{} {
+	if isStr {
+		return string(collection.Index(i).Interface().(rune))
+	}
+
+	return collection.Index(i).Interface()
+}
+
+// #first helper
+func firstHelper(collection interface{}) interface{} {
+	val, isStr, ok := collectionValue(collection)
+	if !ok || (val.Len() == 0) {
+		return nil
+	}
+
+	return collectionItem(val, isStr, 0)
+}
+
+// #last helper
+func lastHelper(collection interface{}) interface{} {
+	val, isStr, ok := collectionValue(collection)
+	if !ok || (val.Len() == 0) {
+		return nil
+	}
+
+	return collectionItem(val, isStr, val.Len()-1)
+}
+
+// #length helper
+//
+// The length of a string is its number of runes.
+func lengthHelper(collection interface{}) interface{} {
+	val, _, ok := collectionValue(collection)
+	if !ok {
+		return nil
+	}
+
+	return val.Len()
+}
+
+// #slice helper
+//
+// Returns items from start (inclusive) to end (exclusive), out of range indexes are clamped.
+func sliceHelper(collection interface{}, start interface{}, end interface{}, options *Options) interface{} {
+	val, isStr, ok := collectionValue(collection)
+	if !ok {
+		return nil
+	}
+
+	valStart, _ := options.numberValue("slice", start)
+	valEnd, _ := options.numberValue("slice", end)
+
+	i := clamp(int(valStart), 0, val.Len())
+	j := clamp(int(valEnd), i, val.Len())
+
+	if isStr {
+		return string(val.Slice(i, j).Interface().([]rune))
+	}
+
+	return val.Slice(i, j).Interface()
+}
+
+// #join helper
+//
+// Joins stringified items of given array or slice with given separator. Other values are only stringified.
+//
+// If an item is a SafeString, the other items and the separator are escaped, and the result is a SafeString.
+func joinHelper(collection interface{}, separator string, options *Options) interface{} {
+	val, isStr, ok := collectionValue(collection)
+	if !ok {
+		return stringResult(collection, options.eval.str(collection))
+	}
+
+	if isStr {
+		return stringResult(collection, string(val.Interface().([]rune)))
+	}
+
+	items := make([]interface{}, val.Len())
+	safe := false
+
+	for i := range items {
+		items[i] = val.Index(i).Interface()
+		safe = safe || isSafeString(items[i])
+	}
+
+	strs := make([]string, len(items))
+	for i, item := range items {
+		if str, ok := item.(SafeString); ok {
+			strs[i] = string(str)
+		} else if safe {
+			// SafeString items must not be escaped, so the other ones are escaped now
+			strs[i] = Escape(options.eval.str(item))
+		} else {
+			strs[i] = options.eval.str(item)
+		}
+	}
+
+	if safe {
+		return SafeString(strings.Join(strs, Escape(separator)))
+	}
+
+	return strings.Join(strs, separator)
+}
+
+// #contains helper
+//
+// For a string, returns true if it contains given substring. Otherwise, items are compared with given value using their
+// string representation, as the #equal helper does.
+func containsHelper(collection interface{}, value interface{}, options *Options) interface{} {
+	val, isStr, ok := collectionValue(collection)
+	if !ok {
+		return false
+	}
+
+	needle := options.eval.str(value)
+
+	if isStr {
+		return strings.Contains(string(val.Interface().([]rune)), needle)
+	}
+
+	for i := 0; i < val.Len(); i++ {
+		if options.eval.str(val.Index(i).Interface()) == needle {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package raymond
+
+import "testing"
+
+var collectionHelperTests = []Test{
+	{
+		"#first and #last helpers",
+		`{{first items}} {{last items}} {{first name}} {{last name}}`,
+		map[string]interface{}{"items": []int{1, 2, 3}, "name": "élan"},
+		nil, collectionHelpers, nil,
+		`1 3 é n`,
+	},
+	{
+		"#first helper with empty slice",
+		`[{{first items}}]`,
+		map[string]interface{}{"items": []int{}},
+		nil, collectionHelpers, nil,
+		`[]`,
+	},
+	{
+		"#length helper",
+		`{{length items}} {{length array}} {{length name}}`,
+		map[string]interface{}{"items": []int{1, 2, 3}, "array": [2]string{"a", "b"}, "name": "élan"},
+		nil, collectionHelpers, nil,
+		`3 2 4`,
+	},
+	{
+		"collection helpers with nil inputs",
+		`[{{first missing}}{{last missing}}{{length missing}}{{join missing ", "}}{{slice missing 0 1}}]`,
+		nil, nil, collectionHelpers, nil,
+		`[]`,
+	},
+	{
+		"#slice helper",
+		`{{#each (slice items 1 3)}}{{this}}{{/each}} {{#each (slice array 0 10)}}{{this}}{{/each}} {{slice name 1 3}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c", "d"}, "array": [2]string{"a", "b"}, "name": "élan"},
+		nil, collectionHelpers, nil,
+		`bc ab la`,
+	},
+	{
+		"#join helper",
+		`{{join tags ", "}}`,
+		map[string]interface{}{"tags": []interface{}{"go", 1, true}},
+		nil, nil, nil,
+		`go, 1, true`,
+	},
+	{
+		"#join helper with []string and []int",
+		`{{join names ", "}} {{join ids "-"}} [{{join empty ", "}}]`,
+		map[string]interface{}{"names": []string{"foo", "bar"}, "ids": [3]int{1, 2, 3}, "empty": []int{}},
+		nil, nil, nil,
+		`foo, bar 1-2-3 []`,
+	},
+	{
+		"#join helper with non collection values",
+		`{{join name ", "}} {{join count ", "}} [{{join missing ", "}}]`,
+		map[string]interface{}{"name": "foo", "count": 12},
+		nil, nil, nil,
+		`foo 12 []`,
+	},
+	{
+		"#join helper output is escaped",
+		`{{join tags " & "}}`,
+		map[string]interface{}{"tags": []string{"<a>", "<b>"}},
+		nil, nil, nil,
+		`&lt;a&gt; &amp; &lt;b&gt;`,
+	},
+	{
+		"#contains helper",
+		`{{#if (contains roles "admin")}}admin{{/if}} {{#if (contains ids "2")}}two{{/if}} {{#if (contains ids 4)}}four{{/if}} {{#if (contains name "la")}}la{{/if}}`,
+		map[string]interface{}{"roles": []string{"user", "admin"}, "ids": []int{1, 2, 3}, "name": "élan"},
+		nil, collectionHelpers, nil,
+		`admin two  la`,
+	},
+}
+
+func TestCollectionHelpers(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, collectionHelperTests)
+}
+
+func TestRegisterCollectionHelpers(t *testing.T) {
+	RegisterCollectionHelpers()
+
+	defer func() {
+		for name := range collectionHelpers {
+			RemoveHelper(name)
+		}
+	}()
+
+	result := MustRender(`{{length items}}`, map[string]interface{}{"items": []int{1, 2}})
+	if result != "2" {
+		t.Errorf("Failed to register collection helpers: %q", result)
+	}
+}
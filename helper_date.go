@@ -0,0 +1,31 @@
+package raymond
+
+import "time"
+
+// #date helper
+//
+// Formats a time.Time, or a Unix timestamp in seconds, with the Go layout set by the `format` hash option. The default
+// layout is RFC3339, and timestamps are formatted in UTC.
+func dateHelper(value interface{}, options *Options) string {
+	layout := time.RFC3339
+	if format := options.HashStr("format"); format != "" {
+		layout = format
+	}
+
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return t.Format(layout)
+	case *time.Time:
+		if t == nil {
+			return ""
+		}
+
+		return t.Format(layout)
+	}
+
+	sec, _ := options.numberValue("date", value)
+
+	return time.Unix(int64(sec), 0).UTC().Format(layout)
+}
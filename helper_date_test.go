@@ -0,0 +1,50 @@
+package raymond
+
+import (
+	"testing"
+	"time"
+)
+
+var dateTime = time.Date(2015, time.March, 25, 16, 30, 5, 0, time.FixedZone("CET", 3600))
+
+var dateHelperTests = []Test{
+	{
+		"#date helper with a time.Time",
+		`{{date created}} {{date created format="2006-01-02"}} {{date updated format="Jan 2, 2006 at 15:04"}}`,
+		map[string]interface{}{"created": dateTime, "updated": &dateTime},
+		nil, nil, nil,
+		`2015-03-25T16:30:05+01:00 2015-03-25 Mar 25, 2015 at 16:30`,
+	},
+	{
+		"#date helper with a Unix timestamp",
+		`{{date created}} {{date created format="2006-01-02 15:04"}} {{date 0 format="2006"}}`,
+		map[string]interface{}{"created": dateTime.Unix()},
+		nil, nil, nil,
+		`2015-03-25T15:30:05Z 2015-03-25 15:30 1970`,
+	},
+	{
+		"#date helper with a missing value",
+		`[{{date missing}}]`,
+		nil, nil, nil, nil,
+		`[]`,
+	},
+}
+
+func TestDateHelper(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, dateHelperTests)
+}
+
+var dateHelperErrors = []Test{
+	{
+		"#date helper with an invalid value",
+		`{{date "yesterday"}}`,
+		nil, nil, nil, nil,
+		"Helper 'date' called with a non numeric argument",
+	},
+}
+
+func TestDateHelperErrors(t *testing.T) {
+	launchErrorTests(t, dateHelperErrors)
+}
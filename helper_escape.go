@@ -0,0 +1,112 @@
+package raymond
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// #urlEncode helper
+//
+// Escapes given string so it can be safely placed inside a URL query.
+func urlEncodeHelper(str string) SafeString {
+	return SafeString(url.QueryEscape(str))
+}
+
+// #pathEncode helper
+//
+// Escapes given string so it can be safely placed inside a URL path segment.
+func pathEncodeHelper(str string) SafeString {
+	return SafeString(url.PathEscape(str))
+}
+
+// #buildQuery helper
+//
+// Returns a URL query string built from hash arguments, sorted by key.
+func buildQueryHelper(options *Options) SafeString {
+	values := url.Values{}
+
+	for key, val := range options.Hash() {
+		values.Set(key, options.eval.str(val))
+	}
+
+	return SafeString(values.Encode())
+}
+
+// #escapeJS helper
+//
+// Escapes given string so it can be safely placed inside a quoted JavaScript string, in a <script> element or in an
+// event handler attribute. Quotes and HTML special characters are escaped as unicode sequences, so that they can't
+// close the string, the attribute or the <script> element.
+func escapeJSHelper(str string) SafeString {
+	var result strings.Builder
+
+	for _, r := range str {
+		switch r {
+		case '\\':
+			result.WriteString(`\\`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\r':
+			result.WriteString(`\r`)
+		case '\t':
+			result.WriteString(`\t`)
+		case '\'', '"', '`', '<', '>', '&', '=', '/', '\u2028', '\u2029':
+			fmt.Fprintf(&result, `\u%04X`, r)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&result, `\u%04X`, r)
+			} else {
+				result.WriteRune(r)
+			}
+		}
+	}
+
+	return SafeString(result.String())
+}
+
+// #escapeCSS helper
+//
+// Escapes given string so it can be safely placed inside a CSS value, in a <style> element or in a style attribute.
+// All ASCII characters except letters and digits are escaped with their hexadecimal code.
+func escapeCSSHelper(str string) SafeString {
+	var result strings.Builder
+
+	for _, r := range str {
+		if isAlphaNum(r) || (r >= 0x80) {
+			result.WriteRune(r)
+		} else {
+			// a trailing space terminates the escape sequence
+			fmt.Fprintf(&result, `\%X `, r)
+		}
+	}
+
+	return SafeString(result.String())
+}
+
+// #attr helper
+//
+// Returns HTML attributes built from hash arguments, sorted by key, with HTML escaped values. An attribute with a nil or
+// false value is skipped, and an attribute with a true value is output without value.
+func attrHelper(options *Options) SafeString {
+	var attrs []string
+
+	for _, key := range options.HashKeys() {
+		switch val := options.HashProp(key).(type) {
+		case nil:
+		case bool:
+			if val {
+				attrs = append(attrs, key)
+			}
+		default:
+			attrs = append(attrs, fmt.Sprintf(`%s="%s"`, key, Escape(options.eval.str(val))))
+		}
+	}
+
+	return SafeString(strings.Join(attrs, " "))
+}
+
+// isAlphaNum returns true if given rune is an ASCII letter or digit
+func isAlphaNum(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
@@ -0,0 +1,82 @@
+package raymond
+
+import "testing"
+
+var escapeHelperTests = []Test{
+	{
+		"#urlEncode helper",
+		`<a href="/search?q={{urlEncode search}}">`,
+		map[string]interface{}{"search": "tom & jerry/<3>"},
+		nil, nil, nil,
+		`<a href="/search?q=tom+%26+jerry%2F%3C3%3E">`,
+	},
+	{
+		"#pathEncode helper",
+		`<a href="/users/{{pathEncode name}}">`,
+		map[string]interface{}{"name": "jean valjean/24601"},
+		nil, nil, nil,
+		`<a href="/users/jean%20valjean%2F24601">`,
+	},
+	{
+		"#buildQuery helper",
+		`{{#each pages}}<a href="?{{buildQuery q=../search page=@index}}">{{/each}}`,
+		map[string]interface{}{"search": "foo & bar", "pages": []int{1, 2}},
+		nil, nil, nil,
+		`<a href="?page=0&q=foo+%26+bar"><a href="?page=1&q=foo+%26+bar">`,
+	},
+	{
+		"#escapeJS helper in a script element",
+		`<script>var name = "{{escapeJS name}}";</script>`,
+		map[string]interface{}{"name": `</script><script>alert('xss')</script>`},
+		nil, nil, nil,
+		`<script>var name = "\u003C\u002Fscript\u003E\u003Cscript\u003Ealert(\u0027xss\u0027)\u003C\u002Fscript\u003E";</script>`,
+	},
+	{
+		"#escapeJS helper in an event handler attribute",
+		`<a onclick="greet('{{escapeJS name}}')">`,
+		map[string]interface{}{"name": `');alert(1);//" onmouseover="alert(2)`},
+		nil, nil, nil,
+		`<a onclick="greet('\u0027);alert(1);\u002F\u002F\u0022 onmouseover\u003D\u0022alert(2)')">`,
+	},
+	{
+		"#escapeJS helper with backslashes and line terminators",
+		`'{{escapeJS str}}'`,
+		map[string]interface{}{"str": "a\\'b\nc\r\u2028d\x00`"},
+		nil, nil, nil,
+		`'a\\\u0027b\nc\r\u2028d\u0000\u0060'`,
+	},
+	{
+		"#escapeCSS helper",
+		`<div style="color: {{escapeCSS color}}">`,
+		map[string]interface{}{"color": `red;background:url("javascript:alert(1)")`},
+		nil, nil, nil,
+		`<div style="color: red\3B background\3A url\28 \22 javascript\3A alert\28 1\29 \22 \29 ">`,
+	},
+	{
+		"#escapeCSS helper with a style element breakout",
+		`<style>.a { font-family: {{escapeCSS font}}; }</style>`,
+		map[string]interface{}{"font": `</style><script>alert(1)</script>`},
+		nil, nil, nil,
+		`<style>.a { font-family: \3C \2F style\3E \3C script\3E alert\28 1\29 \3C \2F script\3E ; }</style>`,
+	},
+	{
+		"#attr helper",
+		`<input {{attr type="text" value=value data-id=id}}>`,
+		map[string]interface{}{"value": `" onfocus="alert(1)`, "id": 42},
+		nil, nil, nil,
+		`<input data-id="42" type="text" value="&quot; onfocus=&quot;alert(1)">`,
+	},
+	{
+		"#attr helper skips nil and false values",
+		`<input {{attr name="foo" title=missing checked=checked disabled=disabled}}>`,
+		map[string]interface{}{"checked": true, "disabled": false},
+		nil, nil, nil,
+		`<input checked name="foo">`,
+	},
+}
+
+func TestEscapeHelpers(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, escapeHelperTests)
+}
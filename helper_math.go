@@ -0,0 +1,179 @@
+package raymond
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// numberValue returns given value as a float, with a boolean set to true if it is an integer
+//
+// Numeric strings are converted, and it panics if given value can't be converted to a number.
+func (options *Options) numberValue(helper string, value interface{}) (float64, bool) {
+	val, _ := indirect(reflect.ValueOf(value))
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), false
+	case reflect.String:
+		if i, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
+			return float64(i), true
+		}
+
+		if f, err := strconv.ParseFloat(val.String(), 64); err == nil {
+			return f, false
+		}
+	}
+
+	options.eval.errorf("Helper '%s' called with a non numeric argument: %q", helper, options.eval.str(value))
+
+	return 0, false
+}
+
+// numberResult returns an integer or a float
+func numberResult(val float64, isInt bool) interface{} {
+	if isInt {
+		return int(val)
+	}
+
+	return val
+}
+
+// #add helper
+func addHelper(a interface{}, b interface{}, options *Options) interface{} {
+	valA, intA := options.numberValue("add", a)
+	valB, intB := options.numberValue("add", b)
+
+	return numberResult(valA+valB, intA && intB)
+}
+
+// #sub helper
+func subHelper(a interface{}, b interface{}, options *Options) interface{} {
+	valA, intA := options.numberValue("sub", a)
+	valB, intB := options.numberValue("sub", b)
+
+	return numberResult(valA-valB, intA && intB)
+}
+
+// #mul helper
+func mulHelper(a interface{}, b interface{}, options *Options) interface{} {
+	valA, intA := options.numberValue("mul", a)
+	valB, intB := options.numberValue("mul", b)
+
+	return numberResult(valA*valB, intA && intB)
+}
+
+// #div helper
+//
+// The result is an integer only if both arguments are integers and the division has no remainder.
+func divHelper(a interface{}, b interface{}, options *Options) interface{} {
+	valA, intA := options.numberValue("div", a)
+	valB, intB := options.numberValue("div", b)
+
+	if valB == 0 {
+		options.eval.errorf("Helper 'div' called with a zero divisor")
+	}
+
+	result := valA / valB
+
+	return numberResult(result, intA && intB && (math.Trunc(result) == result))
+}
+
+// #mod helper
+func modHelper(a interface{}, b interface{}, options *Options) interface{} {
+	valA, intA := options.numberValue("mod", a)
+	valB, intB := options.numberValue("mod", b)
+
+	if valB == 0 {
+		options.eval.errorf("Helper 'mod' called with a zero divisor")
+	}
+
+	return numberResult(math.Mod(valA, valB), intA && intB)
+}
+
+// #round helper
+//
+// The `precision` hash option sets the number of decimals to keep, default is 0.
+func roundHelper(value interface{}, options *Options) interface{} {
+	val, isInt := options.numberValue("round", value)
+	if isInt {
+		return numberResult(val, true)
+	}
+
+	precision := 0
+	if prop := options.HashProp("precision"); prop != nil {
+		p, _ := options.numberValue("round", prop)
+		precision = int(p)
+	}
+
+	if precision <= 0 {
+		return numberResult(math.Round(val), true)
+	}
+
+	pow := math.Pow(10, float64(precision))
+
+	return math.Round(val*pow) / pow
+}
+
+// #number helper
+//
+// Formats a number for display. The `decimals` hash option sets the number of decimals, default is 0 for integers and
+// as many as needed for floats. The `thousands` hash option sets the thousands separator, default is none, and the
+// `point` hash option sets the decimal separator, default is ".".
+func numberHelper(value interface{}, options *Options) string {
+	val, isInt := options.numberValue("number", value)
+
+	decimals := -1
+	if isInt {
+		decimals = 0
+	}
+
+	if prop := options.HashProp("decimals"); prop != nil {
+		d, _ := options.numberValue("number", prop)
+		decimals = int(math.Max(d, 0))
+	}
+
+	str := strconv.FormatFloat(val, 'f', decimals, 64)
+
+	sign := ""
+	if str[0] == '-' {
+		str = str[1:]
+
+		// a number rounded to zero is not negative
+		if strings.Trim(str, "0.") != "" {
+			sign = "-"
+		}
+	}
+
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+	}
+
+	if thousands := options.HashStr("thousands"); thousands != "" {
+		var groups []string
+
+		for len(intPart) > 3 {
+			groups = append([]string{intPart[len(intPart)-3:]}, groups...)
+			intPart = intPart[:len(intPart)-3]
+		}
+
+		intPart = strings.Join(append([]string{intPart}, groups...), thousands)
+	}
+
+	if fracPart == "" {
+		return sign + intPart
+	}
+
+	point := "."
+	if prop := options.HashProp("point"); prop != nil {
+		point = options.eval.str(prop)
+	}
+
+	return sign + intPart + point + fracPart
+}
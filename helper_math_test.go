@@ -0,0 +1,175 @@
+package raymond
+
+import "testing"
+
+var mathHelperTests = []Test{
+	{
+		"#add helper with integers",
+		`{{add 1 2}}`,
+		nil, nil, nil, nil,
+		`3`,
+	},
+	{
+		"#add helper with floats",
+		`{{add 1.5 2}}`,
+		nil, nil, nil, nil,
+		`3.5`,
+	},
+	{
+		"#add helper with numeric strings",
+		`{{add a "2"}}`,
+		map[string]interface{}{"a": "40"},
+		nil, nil, nil,
+		`42`,
+	},
+	{
+		"#add helper with @index",
+		`{{#each items}}{{add @index 1}}.{{this}} {{/each}}`,
+		map[string]interface{}{"items": []string{"foo", "bar"}},
+		nil, nil, nil,
+		`1.foo 2.bar `,
+	},
+	{
+		"#sub helper",
+		`{{sub 10 a}}`,
+		map[string]interface{}{"a": uint(3)},
+		nil, nil, nil,
+		`7`,
+	},
+	{
+		"#mul helper",
+		`{{mul 4 2.5}}`,
+		nil, nil, nil, nil,
+		`10`,
+	},
+	{
+		"#div helper with integers",
+		`{{div 10 2}} {{div 7 2}}`,
+		nil, nil, nil, nil,
+		`5 3.5`,
+	},
+	{
+		"#mod helper",
+		`{{mod 7 3}} {{mod 7.5 2}}`,
+		nil, nil, nil, nil,
+		`1 1.5`,
+	},
+	{
+		"#mod helper in a sub-expression",
+		`{{#each items}}{{#equal (mod @index 2) 0}}{{this}} {{/equal}}{{/each}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c", "d", "e"}},
+		nil, nil, nil,
+		`a c e `,
+	},
+	{
+		"nested math sub-expressions",
+		`{{mul (add 1 2) (sub 10 4)}}`,
+		nil, nil, nil, nil,
+		`18`,
+	},
+	{
+		"math helpers results are numbers",
+		`{{kind (add 1 2)}} {{kind (add 1 0.5)}}`,
+		nil, nil,
+		map[string]interface{}{"kind": func(val interface{}) string {
+			switch val.(type) {
+			case int:
+				return "int"
+			case float64:
+				return "float"
+			}
+			return "other"
+		}},
+		nil,
+		`int float`,
+	},
+	{
+		"#round helper",
+		`{{round 2.5}} {{round 2.4}} {{round 7}}`,
+		nil, nil, nil, nil,
+		`3 2 7`,
+	},
+	{
+		"#round helper with precision",
+		`{{round 3.14159 precision=2}}`,
+		nil, nil, nil, nil,
+		`3.14`,
+	},
+	{
+		"#number helper with decimals",
+		`{{number price decimals=2}} {{number 3 decimals=2}} {{number 2.005 decimals=1}} {{number "-0.5" decimals=0}} {{number 1.5 decimals=-1}}`,
+		map[string]interface{}{"price": 12.5},
+		nil, nil, nil,
+		`12.50 3.00 2.0 0 2`,
+	},
+	{
+		"#number helper without options",
+		`{{number 1234567}} {{number 1234.5678}} {{number price}}`,
+		map[string]interface{}{"price": float32(0.25)},
+		nil, nil, nil,
+		`1234567 1234.5678 0.25`,
+	},
+	{
+		"#number helper with thousands separator",
+		`{{number 1234567 thousands=","}} {{number -1234567.891 decimals=2 thousands=","}} {{number 123 thousands=","}} {{number 1000 thousands=" "}}`,
+		nil, nil, nil, nil,
+		`1,234,567 -1,234,567.89 123 1 000`,
+	},
+	{
+		"#number helper with decimal point",
+		`{{number price decimals=2 thousands="." point=","}}`,
+		map[string]interface{}{"price": uint64(1234567)},
+		nil, nil, nil,
+		`1.234.567,00`,
+	},
+	{
+		"#number helper output is escaped",
+		`{{number 1000 thousands="<"}}`,
+		nil, nil, nil, nil,
+		`1&lt;000`,
+	},
+}
+
+func TestMathHelpers(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, mathHelperTests)
+}
+
+var mathHelperErrors = []Test{
+	{
+		"#div helper with zero divisor",
+		`{{div 1 0}}`,
+		nil, nil, nil, nil,
+		"Helper 'div' called with a zero divisor",
+	},
+	{
+		"#mod helper with zero divisor",
+		`{{mod 1 zero}}`,
+		map[string]interface{}{"zero": 0.0},
+		nil, nil, nil,
+		"Helper 'mod' called with a zero divisor",
+	},
+	{
+		"#add helper with non numeric argument",
+		`{{add 1 "foo"}}`,
+		nil, nil, nil, nil,
+		"Helper 'add' called with a non numeric argument",
+	},
+	{
+		"#add helper with missing argument",
+		`{{add 1 missing}}`,
+		nil, nil, nil, nil,
+		"Helper 'add' called with a non numeric argument",
+	},
+	{
+		"#number helper with non numeric argument",
+		`{{number "foo" decimals=2}}`,
+		nil, nil, nil, nil,
+		"Helper 'number' called with a non numeric argument",
+	},
+}
+
+func TestMathHelpersErrors(t *testing.T) {
+	launchErrorTests(t, mathHelperErrors)
+}
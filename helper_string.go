@@ -0,0 +1,47 @@
+package raymond
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stringResult returns given string as a SafeString if value is a SafeString, so that it is not escaped
+func stringResult(value interface{}, str string) interface{} {
+	if isSafeString(value) {
+		return SafeString(str)
+	}
+
+	return str
+}
+
+// #upper helper
+func upperHelper(value interface{}, options *Options) interface{} {
+	return stringResult(value, strings.ToUpper(options.eval.str(value)))
+}
+
+// #lower helper
+func lowerHelper(value interface{}, options *Options) interface{} {
+	return stringResult(value, strings.ToLower(options.eval.str(value)))
+}
+
+// #trim helper
+//
+// Removes leading and trailing whitespaces.
+func trimHelper(value interface{}, options *Options) interface{} {
+	return stringResult(value, strings.TrimSpace(options.eval.str(value)))
+}
+
+// #capitalize helper
+//
+// Converts the first character to upper case, and keeps the rest unchanged.
+func capitalizeHelper(value interface{}, options *Options) interface{} {
+	str := options.eval.str(value)
+
+	r, size := utf8.DecodeRuneInString(str)
+	if size == 0 {
+		return stringResult(value, str)
+	}
+
+	return stringResult(value, string(unicode.ToUpper(r))+str[size:])
+}
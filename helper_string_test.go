@@ -0,0 +1,107 @@
+package raymond
+
+import "testing"
+
+var stringHelperTests = []Test{
+	{
+		"#upper helper",
+		`{{upper name}}`,
+		map[string]interface{}{"name": "Jean <Valjean>"},
+		nil, nil, nil,
+		`JEAN &lt;VALJEAN&gt;`,
+	},
+	{
+		"#upper helper with a SafeString",
+		`{{upper name}}`,
+		map[string]interface{}{"name": SafeString("<em>Jean</em>")},
+		nil, nil, nil,
+		`<EM>JEAN</EM>`,
+	},
+	{
+		"#upper helper with a number",
+		`{{upper 1.5}}`,
+		nil, nil, nil, nil,
+		`1.5`,
+	},
+	{
+		"#lower helper",
+		`{{lower name}}`,
+		map[string]interface{}{"name": "JEAN & VALJEAN"},
+		nil, nil, nil,
+		`jean &amp; valjean`,
+	},
+	{
+		"#lower helper with unicode",
+		`{{lower name}}`,
+		map[string]interface{}{"name": "ÉTÉ ΣΊΣΥΦΟΣ"},
+		nil, nil, nil,
+		`été σίσυφοσ`,
+	},
+	{
+		"#trim helper",
+		`[{{trim name}}]`,
+		map[string]interface{}{"name": " \t Jean Valjean\n"},
+		nil, nil, nil,
+		`[Jean Valjean]`,
+	},
+	{
+		"#capitalize helper",
+		`{{capitalize name}} {{capitalize "élan"}} [{{capitalize missing}}]`,
+		map[string]interface{}{"name": "jean valjean"},
+		nil, nil, nil,
+		`Jean valjean Élan []`,
+	},
+	{
+		"#capitalize helper with a SafeString",
+		`{{capitalize name}}`,
+		map[string]interface{}{"name": SafeString("<em>jean</em>")},
+		nil, nil, nil,
+		`<em>jean</em>`,
+	},
+	{
+		"string helpers as sub-expressions",
+		`{{capitalize (lower (trim name))}}`,
+		map[string]interface{}{"name": "  JEAN "},
+		nil, nil, nil,
+		`Jean`,
+	},
+}
+
+func TestStringHelpers(t *testing.T) {
+	launchTests(t, stringHelperTests)
+}
+
+var stringHelperErrors = []Test{
+	{
+		"#upper helper without argument",
+		`{{upper}}`,
+		map[string]interface{}{"name": "Jean"},
+		nil, nil, nil,
+		"Helper 'upper' called with wrong number of arguments, needed 1 but got 0",
+	},
+	{
+		"#lower helper without argument",
+		`{{lower}}`,
+		map[string]interface{}{"name": "Jean"},
+		nil, nil, nil,
+		"Helper 'lower' called with wrong number of arguments, needed 1 but got 0",
+	},
+	{
+		"#trim helper without argument",
+		`{{trim}}`,
+		map[string]interface{}{"name": "Jean"},
+		nil, nil, nil,
+		"Helper 'trim' called with wrong number of arguments, needed 1 but got 0",
+	},
+	{
+		"#capitalize helper without argument",
+		`{{capitalize}}`,
+		map[string]interface{}{"name": "Jean"},
+		nil, nil, nil,
+		"Helper 'capitalize' called with wrong number of arguments, needed 1 but got 0",
+	},
+}
+
+func TestStringHelpersErrors(t *testing.T) {
+	launchErrorTests(t, stringHelperErrors)
+}
@@ -1,6 +1,13 @@
 package raymond
 
-import "testing"
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
 
 const (
 	VERBOSE = false
@@ -12,6 +19,8 @@ const (
 
 func barHelper(options *Options) string { return "bar" }
 
+func nameHelper(options *Options) string { return options.Name() }
+
 func echoHelper(str string, nb int) string {
 	result := ""
 	for i := 0; i < nb; i++ {
@@ -152,6 +161,34 @@ var helperTests = []Test{
 		nil, nil, nil,
 		`YES MAN`,
 	},
+	{
+		"#with helper with empty slice",
+		`{{#with list}}{{length}}{{else}}none{{/with}}`,
+		map[string]interface{}{"list": []string{}},
+		nil, nil, nil,
+		`none`,
+	},
+	{
+		"#with helper with empty map",
+		`{{#with dict}}{{foo}}{{else}}none{{/with}}`,
+		map[string]interface{}{"dict": map[string]string{}},
+		nil, nil, nil,
+		`none`,
+	},
+	{
+		"#with helper with present empty string",
+		`{{#with str}}[{{this}}]{{else}}none{{/with}}`,
+		map[string]interface{}{"str": ""},
+		nil, nil, nil,
+		`none`,
+	},
+	{
+		"#with helper with non empty slice",
+		`{{#with list}}{{#each this}}{{this}}{{/each}}{{else}}none{{/with}}`,
+		map[string]interface{}{"list": []string{"foo"}},
+		nil, nil, nil,
+		`foo`,
+	},
 	{
 		"#equal helper with same string var",
 		`{{#equal foo "bar"}}YES MAN{{/equal}}`,
@@ -194,6 +231,52 @@ var helperTests = []Test{
 		nil, nil, nil,
 		``,
 	},
+	{
+		"#equal helper with numbers",
+		`{{#equal foo 1}}YES MAN{{/equal}} {{#equal foo bar}}YES MAN{{/equal}} {{#equal foo "1"}}YES MAN{{/equal}}`,
+		map[string]interface{}{"foo": 1.0, "bar": json.Number("1.00")},
+		nil, nil, nil,
+		`YES MAN YES MAN YES MAN`,
+	},
+	{
+		"pipes in string parameters",
+		`{{#each items as |item|}}{{echo "a|b" item}} {{/each}}`,
+		map[string]interface{}{"items": []int{1, 2}},
+		nil,
+		map[string]interface{}{"echo": echoHelper},
+		nil,
+		`a|b a|ba|b `,
+	},
+	{
+		"#equal helper with large integer literal",
+		`{{#equal foo 9999999999999}}YES MAN{{/equal}} {{echo 9999999999999 1}}`,
+		map[string]interface{}{"foo": int64(9999999999999)},
+		nil,
+		map[string]interface{}{"echo": echoHelper},
+		nil,
+		`YES MAN 9999999999999`,
+	},
+	{
+		"integer literal beyond float64 precision",
+		`{{#equal foo 9007199254740993}}YES MAN{{/equal}} {{echo 9007199254740993 1}}`,
+		map[string]interface{}{"foo": int64(9007199254740993)},
+		nil,
+		map[string]interface{}{"echo": echoHelper},
+		nil,
+		`YES MAN 9007199254740993`,
+	},
+	{
+		"#equal helper with integers above 2^53",
+		`{{#equal foo bar}}YES MAN{{/equal}} {{#equal foo baz}}YES MAN{{/equal}} {{#equal foo qux}}YES MAN{{/equal}} {{#equal bar qux}}YES MAN{{/equal}}`,
+		map[string]interface{}{
+			"foo": int64(9007199254740993),
+			"bar": int64(9007199254740992),
+			"baz": uint64(9007199254740993),
+			"qux": json.Number("9007199254740993"),
+		},
+		nil, nil, nil,
+		` YES MAN YES MAN `,
+	},
 	{
 		"#equal helper inside HTML tag",
 		`<option value="test" {{#equal value "test"}}selected{{/equal}}>Test</option>`,
@@ -220,6 +303,314 @@ foo is the same as baz
 there is one
 everything is stringified before comparison`,
 	},
+	{
+		"default helper with missing path",
+		`{{default nickname fullName "Anonymous"}}`,
+		map[string]interface{}{"fullName": "Jean Valjean"},
+		nil, nil, nil,
+		`Jean Valjean`,
+	},
+	{
+		"default helper with empty string",
+		`{{default nickname fullName "Anonymous"}}`,
+		map[string]interface{}{"nickname": "", "fullName": ""},
+		nil, nil, nil,
+		`Anonymous`,
+	},
+	{
+		"default helper with nil value",
+		`{{default nickname "N/A"}}`,
+		map[string]interface{}{"nickname": nil},
+		nil, nil, nil,
+		`N/A`,
+	},
+	{
+		"default helper with present value",
+		`{{default nickname "N/A"}} {{default nickname "N/A" strict=false}}`,
+		map[string]interface{}{"nickname": "Jean"},
+		nil, nil, nil,
+		`Jean Jean`,
+	},
+	{
+		"default helper with zero number and false boolean",
+		`{{default count "none"}} {{default enabled "unknown"}}`,
+		map[string]interface{}{"count": 0, "enabled": false},
+		nil, nil, nil,
+		`0 false`,
+	},
+	{
+		"default helper with strict=false",
+		`{{default count "none" strict=false}} {{default enabled "unknown" strict=false}}`,
+		map[string]interface{}{"count": 0, "enabled": false},
+		nil, nil, nil,
+		`none unknown`,
+	},
+	{
+		"default helper with nothing present",
+		`[{{default nickname fullName}}]`,
+		nil, nil, nil, nil,
+		`[]`,
+	},
+	{
+		"default helper as a subexpression",
+		`{{upper (default editedAt createdAt)}}`,
+		map[string]interface{}{"createdAt": "yesterday"},
+		nil,
+		map[string]interface{}{"upper": strings.ToUpper},
+		nil,
+		`YESTERDAY`,
+	},
+	{
+		"json helper with a map",
+		`<script>var data = {{json data}};</script>`,
+		map[string]interface{}{"data": map[string]interface{}{"name": "</script>", "tags": []string{"a", "b"}, "count": 2}},
+		nil, nil, nil,
+		`<script>var data = {"count":2,"name":"\u003c/script\u003e","tags":["a","b"]};</script>`,
+	},
+	{
+		"json helper with a struct",
+		`{{json author}}`,
+		map[string]interface{}{"author": struct {
+			FirstName string `json:"firstName"`
+			LastName  string `json:"-"`
+			Age       int
+		}{"Jean", "Valjean", 42}},
+		nil, nil, nil,
+		`{"firstName":"Jean","Age":42}`,
+	},
+	{
+		"json helper with indent",
+		`{{json author indent=2}}`,
+		map[string]interface{}{"author": map[string]interface{}{"name": "Jean", "tags": []string{"a"}}},
+		nil, nil, nil,
+		"{\n  \"name\": \"Jean\",\n  \"tags\": [\n    \"a\"\n  ]\n}",
+	},
+	{
+		"json helper with a nil value",
+		`{{json missing}}`,
+		nil, nil, nil, nil,
+		`null`,
+	},
+	{
+		"block helper with evaluated hash arguments",
+		`{{#each lists}}{{#list items class="wide" id=(concat "list-" name) index=@index title=../title}}{{this}}{{/list}}{{/each}}`,
+		map[string]interface{}{
+			"title": "Lists",
+			"lists": []map[string]interface{}{
+				{"name": "foo", "items": []string{"a", "b"}},
+				{"name": "bar", "items": []string{"c"}},
+			},
+		},
+		nil,
+		map[string]interface{}{
+			"concat": func(a, b string) string { return a + b },
+			"list": func(items []string, options *Options) SafeString {
+				result := fmt.Sprintf(`<ul class="%s" id="%s" data-index="%s" title="%s">`, options.HashStr("class"), options.HashStr("id"), options.HashStr("index"), options.HashStr("title"))
+				for _, item := range items {
+					result += "<li>" + options.FnWith(item) + "</li>"
+				}
+				return SafeString(result + "</ul>")
+			},
+		},
+		nil,
+		`<ul class="wide" id="list-foo" data-index="0" title="Lists"><li>a</li><li>b</li></ul>` +
+			`<ul class="wide" id="list-bar" data-index="1" title="Lists"><li>c</li></ul>`,
+	},
+	{
+		"block helper iterating with Options.Iterate",
+		`{{#rows items size=2 as |row i|}}{{i}}{{#if @first}} first{{/if}}{{#if @last}} last{{/if}}: {{#each row}}{{this}}{{/each}}{{@index}}
+{{/rows}}{{#rows empty size=2}}{{this}}{{else}}no rows{{/rows}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c", "d", "e"}, "empty": []string{}},
+		nil,
+		map[string]interface{}{
+			"rows": func(items []string, options *Options) string {
+				size, _ := options.HashProp("size").(int)
+
+				var rows [][]string
+				for len(items) > size {
+					rows, items = append(rows, items[:size]), items[size:]
+				}
+				if len(items) > 0 {
+					rows = append(rows, items)
+				}
+
+				return options.Iterate(rows)
+			},
+		},
+		nil,
+		"0 first: ab0\n1: cd1\n2 last: e2\nno rows",
+	},
+	{
+		"built-in block helpers ignore unknown hash arguments",
+		`{{#each items class="wide"}}{{this}}{{/each}} {{#if cond class="wide"}}yes{{/if}} {{#with person class="wide"}}{{name}}{{/with}}`,
+		map[string]interface{}{"items": []string{"a", "b"}, "cond": true, "person": map[string]string{"name": "Jean"}},
+		nil, nil, nil,
+		`ab yes Jean`,
+	},
+	{
+		"#each helper with limit and offset",
+		`{{#each items offset=1 limit=2}}{{@index}}.{{this}}{{#if @first}} first{{/if}}{{#if @last}} last{{/if}} {{/each}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c", "d"}},
+		nil, nil, nil,
+		`0.b first 1.c last `,
+	},
+	{
+		"#each helper with reverse",
+		`{{#each items reverse=true}}{{this}}{{/each}}`,
+		map[string]interface{}{"items": [3]string{"a", "b", "c"}},
+		nil, nil, nil,
+		`cba`,
+	},
+	{
+		"#each helper with limit=0",
+		`{{#each items limit=0}}{{this}}{{/each}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c"}},
+		nil, nil, nil,
+		`abc`,
+	},
+	{
+		"#each helper with out of range offset",
+		`{{#each items offset=3}}{{this}}{{else}}nothing{{/each}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c"}},
+		nil, nil, nil,
+		`nothing`,
+	},
+	{
+		"#each helper with sorted map and window options",
+		`{{#each items reverse=true offset=1 limit=2}}{{@key}}={{this}} {{/each}}`,
+		map[string]interface{}{"items": map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}},
+		nil, nil, nil,
+		`c=3 b=2 `,
+	},
+	{
+		"#each helper with map and block params",
+		`{{#each items as |v k|}}{{k}}={{v}} {{/each}}{{#each items as |v|}}{{v}}{{/each}}`,
+		map[string]interface{}{"items": map[string]int{"b": 2, "a": 1, "c": 3}},
+		nil, nil, nil,
+		`a=1 b=2 c=3 123`,
+	},
+	{
+		"#each helper with window options and block params",
+		`{{#each items offset=1 limit=2 as |item i|}}{{i}}:{{item}} {{/each}}`,
+		map[string]interface{}{"items": []string{"a", "b", "c", "d"}},
+		nil, nil, nil,
+		`0:b 1:c `,
+	},
+	{
+		"nested #each helpers with window options",
+		`{{#each rows limit=2}}[{{#each this reverse=true offset=1}}{{this}}{{/each}}]{{/each}}`,
+		map[string]interface{}{"rows": [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}},
+		nil, nil, nil,
+		`[21][54]`,
+	},
+	{
+		"#times helper",
+		`<ul>{{#times 3}}<li>{{@index}}:{{this}}{{#if @first}} first{{/if}}{{#if @last}} last{{/if}}</li>{{/times}}</ul>`,
+		nil, nil, nil, nil,
+		`<ul><li>0:0 first</li><li>1:1</li><li>2:2 last</li></ul>`,
+	},
+	{
+		"#times helper with zero iterations",
+		`{{#times count}}foo{{else}}nothing{{/times}}`,
+		map[string]interface{}{"count": 0},
+		nil, nil, nil,
+		`nothing`,
+	},
+	{
+		"#range helper",
+		`{{#range 1 4}}{{this}} {{/range}}`,
+		nil, nil, nil, nil,
+		`1 2 3 `,
+	},
+	{
+		"#range helper with step",
+		`{{#range 2 10 step=3}}{{this}} {{/range}}`,
+		nil, nil, nil, nil,
+		`2 5 8 `,
+	},
+	{
+		"#range helper with negative step and block param",
+		`{{#range 10 0 step=-2 as |n i|}}{{i}}.{{n}} {{/range}}`,
+		nil, nil, nil, nil,
+		`0.10 1.8 2.6 3.4 4.2 `,
+	},
+	{
+		"#range helper with zero iterations",
+		`{{#range 5 1}}foo{{else}}nothing{{/range}}`,
+		nil, nil, nil, nil,
+		`nothing`,
+	},
+	{
+		"helper name",
+		`{{foo}} {{bar 1}} {{#foo}}{{/foo}} {{baz}} {{qux.name}} {{echo (foo) 2}}`,
+		map[string]interface{}{
+			"baz": func(options *Options) string { return options.Name() },
+			"qux": map[string]interface{}{"name": func(options *Options) string { return options.Name() }},
+		},
+		nil,
+		map[string]interface{}{
+			"foo":  nameHelper,
+			"bar":  func(n int, options *Options) string { return options.Name() },
+			"echo": echoHelper,
+		},
+		nil,
+		`foo bar foo baz name foofoo`,
+	},
+	{
+		"block params of #with and #each",
+		`{{#with person as |p|}}{{p.name}}{{/with}} {{#each items as |item i|}}{{i}}:{{item}} {{/each}}`,
+		map[string]interface{}{"person": map[string]string{"name": "foo"}, "items": []string{"a", "b"}},
+		nil, nil, nil,
+		`foo 0:a 1:b `,
+	},
+	{
+		"block helper setting block params",
+		`{{#pair "foo" 1 as |a b|}}{{a}} {{b}} {{this}}{{/pair}} {{#pair "bar" 2 as |a b c|}}[{{a}}{{b}}{{c}}]{{/pair}}`,
+		map[string]string{"name": "ctx"},
+		nil,
+		map[string]interface{}{"pair": func(a string, b int, options *Options) string {
+			return options.FnBlockParams("ctx", a, b)
+		}},
+		nil,
+		`foo 1 ctx [bar2]`,
+	},
+	{
+		"block helper getting block params names",
+		`{{#names as |a b|}}{{/names}} {{#names}}{{/names}} {{#each items as |item|}}{{names}}{{/each}}`,
+		map[string]interface{}{"items": []int{1}},
+		nil,
+		map[string]interface{}{"names": func(options *Options) string {
+			return fmt.Sprintf("%v", options.BlockParams())
+		}},
+		nil,
+		`[a b] [] []`,
+	},
+	{
+		"block helper evaluating else block with a context",
+		`{{#each urls}}{{#fetch this}}{{data}} {{@index}}{{else}}{{error}} {{@index}} {{../this}}{{/fetch}}|{{/each}}`,
+		map[string]interface{}{"urls": []string{"/ok", "/ko"}},
+		nil,
+		map[string]interface{}{"fetch": func(url string, options *Options) string {
+			if url == "/ok" {
+				return options.FnWith(map[string]string{"data": "fetched " + url})
+			}
+
+			return options.InverseWith(map[string]string{"error": "failed " + url})
+		}},
+		nil,
+		`fetched /ok 0|failed /ko 1 /ko|`,
+	},
+	{
+		"raw block helper",
+		`{{{{pre}}}}{{title}} {{#if foo}}<b>{{/if}}{{{{/pre}}}} {{{{pre}}}}{{{{/pre}}}}`,
+		map[string]string{"title": "foo"},
+		nil,
+		map[string]interface{}{"pre": func(options *Options) SafeString {
+			return SafeString("<pre>" + Escape(options.Fn()) + "</pre>")
+		}},
+		nil,
+		`<pre>{{title}} {{#if foo}}&lt;b&gt;{{/if}}</pre> <pre></pre>`,
+	},
 }
 
 //
@@ -244,6 +635,147 @@ func TestRemoveHelper(t *testing.T) {
 	}
 }
 
+func TestHelperNames(t *testing.T) {
+	t.Parallel()
+
+	names := strings.Join(HelperNames(), " ")
+	for _, name := range []string{"each", "if", "lookup", "unless", "with"} {
+		if !strings.Contains(" "+names+" ", " "+name+" ") {
+			t.Errorf("Builtin helper %s not listed in global helper names: %s", name, names)
+		}
+
+		if !IsBuiltinHelper(name) {
+			t.Errorf("Helper %s must be a builtin helper", name)
+		}
+	}
+
+	if !sort.StringsAreSorted(HelperNames()) {
+		t.Errorf("Global helper names must be sorted: %s", names)
+	}
+
+	if IsBuiltinHelper("template") || IsBuiltinHelper("unknown") {
+		t.Errorf("Only builtin helpers must be reported as builtin")
+	}
+
+	tpl := MustParse("").WithHelpers(map[string]interface{}{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	})
+	tpl.RegisterPartials(map[string]string{"header": "", "footer": ""})
+
+	if names := tpl.HelperNames(); fmt.Sprint(names) != "[lower upper]" {
+		t.Errorf("Failed to list template helper names: %q", names)
+	}
+
+	if names := tpl.PartialNames(); fmt.Sprint(names) != "[footer header]" {
+		t.Errorf("Failed to list template partial names: %q", names)
+	}
+}
+
+func TestHelperNamespace(t *testing.T) {
+	RegisterHelperNamespace("testns", map[string]interface{}{
+		"shout": func(str string) string { return str + "!" },
+	})
+	defer RemoveHelper("testns.shout")
+
+	source := `{{str.upper name}} {{str.lower str.name}} {{#str.wrap}}{{str.name}}{{/str.wrap}} {{testns.shout name}} {{str/name}} {{other.upper}}`
+
+	ctx := map[string]interface{}{
+		"name":  "Foo",
+		"str":   map[string]interface{}{"upper": "context", "name": "Bar"},
+		"other": map[string]string{"upper": "field"},
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterHelperNamespace("str", map[string]interface{}{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"wrap":  func(options *Options) string { return "[" + options.Fn() + "]" },
+		"name":  func() string { return "helper" },
+	})
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if result := tpl.MustExec(ctx); result != "FOO bar [helper] Foo! Bar field" {
+			t.Errorf("Failed to call namespaced helpers: %q", result)
+		}
+	}
+
+	tpl.SetHelperPrecedence(FieldFirst)
+
+	if result := tpl.MustExec(ctx); result != "FOO bar [Bar] Foo! Bar field" {
+		t.Errorf("Namespaced helpers must follow helper precedence: %q", result)
+	}
+
+	_, err := MustParse(`{{str.fail}}`).
+		WithHelper("str.fail", func() string { panic("failure") }).
+		Exec(nil)
+	if helperErr, ok := err.(*HelperError); !ok || (helperErr.Helper != "str.fail") {
+		t.Errorf("Namespaced helper errors must report the helper full name: %v", err)
+	}
+}
+
+func TestStrictBlockParams(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]interface{}{"items": []string{"a"}, "foo": "bar"}
+
+	tests := []struct {
+		input  string
+		output string
+		err    string
+	}{
+		{`{{#each items as |item idx|}}{{idx}}{{item}}{{/each}}`, "0a", ""},
+		{`{{#each items as |item idx extra|}}{{/each}}`, "", "Helper each provides 2 block params, but 3 are declared"},
+		{`{{#with foo as |a|}}{{a}}{{/with}}`, "bar", ""},
+		{`{{#with foo as |a b|}}{{a}}{{/with}}`, "", "Helper with provides 1 block params, but 2 are declared"},
+		{`{{#if foo as |a|}}{{a}}{{/if}}`, "", "Helper if provides 0 block params, but 1 are declared"},
+		{`{{#triple as |a b c|}}{{a}}{{b}}{{c}}{{/triple}}`, "123", ""},
+		{`{{#triple as |a b c d|}}{{/triple}}`, "", "Helper triple provides 3 block params, but 4 are declared"},
+	}
+
+	for _, test := range tests {
+		tpl := MustParse(test.input)
+		tpl.RegisterHelper("triple", func(options *Options) string {
+			if err := options.CheckBlockParams(3); err != nil {
+				panic(err)
+			}
+			return options.FnBlockParams(nil, 1, 2, 3)
+		})
+
+		for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+			output, err := tpl.ExecWithOptions(ctx, nil, StrictBlockParams())
+			if test.err == "" {
+				if (err != nil) || (output != test.output) {
+					t.Errorf("Unexpected strict block params result for %q: %q %v", test.input, output, err)
+				}
+			} else if (err == nil) || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("Strict block params must fail for %q: %v", test.input, err)
+			}
+		}
+	}
+
+	// not strict
+	if output := MustParse(`{{#each items as |item idx extra|}}{{item}}{{extra}}{{/each}}`).MustExec(ctx); output != "a" {
+		t.Errorf("Extra block params must be ignored when not strict: %q", output)
+	}
+}
+
+func TestOptionsRegisterHelper(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{zzlate}}|{{reg}}{{zzlate}}`)
+	tpl.RegisterHelper("reg", func(options *Options) string {
+		options.RegisterHelper("zzlate", func() string { return "LATE" })
+		return ""
+	})
+
+	for _, tpl := range []*Template{tpl.Clone(), tpl.Clone().MustCompile()} {
+		if output := tpl.MustExec(nil); output != "|LATE" {
+			t.Errorf("Helper registered during evaluation must be available to the rest of that evaluation: %q", output)
+		}
+	}
+}
+
 //
 // Fixes: https://github.com/aymerick/raymond/issues/2
 //
@@ -271,3 +803,238 @@ func TestHelperCtx(t *testing.T) {
 		t.Errorf("Failed to render template in helper: %q", result)
 	}
 }
+
+func TestHelperCtxJSON(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{json}}|{{#with author}}{{json}}{{/with}}`)
+	tpl.RegisterHelper("json", func(options *Options) SafeString {
+		b, err := json.Marshal(options.Ctx())
+		if err != nil {
+			panic(err)
+		}
+
+		return SafeString(b)
+	})
+
+	ctx := map[string]interface{}{
+		"author": Author{"Alan", "Johnson"},
+	}
+
+	expected := `{"author":{"FirstName":"Alan","LastName":"Johnson"}}|{"FirstName":"Alan","LastName":"Johnson"}`
+	if result := tpl.MustExec(ctx); result != expected {
+		t.Errorf("Failed to serialize context in helper: %q", result)
+	}
+
+	if result := tpl.MustExec(nil); result != "null|" {
+		t.Errorf("Failed to serialize nil context in helper: %q", result)
+	}
+}
+
+type dictTranslator struct {
+	locale string
+	dict   map[string]map[string]string
+}
+
+func (t *dictTranslator) Translate(key string) string {
+	return t.dict[t.locale][key]
+}
+
+func (t *dictTranslator) Locale() string {
+	return t.locale
+}
+
+func (t *dictTranslator) SetLocale(locale string) {
+	t.locale = locale
+}
+
+func TestRegisterHelperMethods(t *testing.T) {
+	t.Parallel()
+
+	tr := &dictTranslator{
+		locale: "fr",
+		dict: map[string]map[string]string{
+			"fr": {"hello": "bonjour"},
+		},
+	}
+
+	tpl := MustParse(`{{locale}}: {{translate "hello"}}`)
+	tpl.RegisterHelperMethods(tr)
+
+	if tpl.findHelper("setLocale") != zero {
+		t.Errorf("Method without return value must not be registered as a helper")
+	}
+
+	result := tpl.MustExec(nil)
+	if result != "fr: bonjour" {
+		t.Errorf("Failed to render template with helper methods: %q", result)
+	}
+}
+
+func TestHelperNumParams(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{greet}} {{greet "John"}} {{greet "John" "Doe"}}`)
+	tpl.RegisterHelper("greet", VariadicHelper(func(options *Options) interface{} {
+		switch options.NumParams() {
+		case 0:
+			return "Hi!"
+		case 1:
+			return "Hi " + options.ParamStr(0) + "!"
+		default:
+			return "Hi " + options.ParamStr(0) + " " + options.ParamStr(1) + "!"
+		}
+	}))
+
+	result := tpl.MustExec(nil)
+	if result != "Hi! Hi John! Hi John Doe!" {
+		t.Errorf("Failed to branch on helper parameters number: %q", result)
+	}
+}
+
+func TestHelperOptionsOnlyArity(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{greet "John"}}`)
+	tpl.RegisterHelper("greet", func(options *Options) string {
+		return "Hi!"
+	})
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		_, err := tpl.Exec(nil)
+		if (err == nil) || !strings.Contains(err.Error(), "Helper 'greet' called with wrong number of arguments, needed 0 but got 1") {
+			t.Errorf("A helper that only expects options must not accept parameters: %v", err)
+		}
+	}
+}
+
+func TestHelperHashKeys(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{keys zeta=1 alpha=2 mu=3}}`)
+	tpl.RegisterHelper("keys", func(options *Options) string {
+		return fmt.Sprintf("%v", options.HashKeys())
+	})
+
+	result := tpl.MustExec(nil)
+	if result != "[alpha mu zeta]" {
+		t.Errorf("Failed to get sorted hash keys: %q", result)
+	}
+}
+
+func TestEachChannel(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each items as |item i|}}{{@index}}{{i}}:{{item}}{{#if @first}}(first){{/if}}{{@last}} {{else}}empty{{/each}}`)
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		ch := make(chan string, 3)
+		ch <- "a"
+		ch <- "b"
+		ch <- "c"
+		close(ch)
+
+		if output := tpl.MustExec(map[string]interface{}{"items": ch}); output != "00:a(first) 11:b 22:c " {
+			t.Errorf("Failed to iterate over channel: %q", output)
+		}
+
+		empty := make(chan string)
+		close(empty)
+
+		if output := tpl.MustExec(map[string]interface{}{"items": empty}); output != "empty" {
+			t.Errorf("Failed to evaluate else block of closed channel: %q", output)
+		}
+
+		var nilChan chan string
+
+		if output := tpl.MustExec(map[string]interface{}{"items": nilChan}); output != "empty" {
+			t.Errorf("Failed to evaluate else block of nil channel: %q", output)
+		}
+	}
+}
+
+func TestHelperRequire(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{check a b}}`)
+	tpl.RegisterHelper("check", VariadicHelper(func(options *Options) interface{} {
+		if err := options.RequireTypes(reflect.String, reflect.Int); err != nil {
+			return err.Error()
+		}
+
+		return "ok"
+	}))
+
+	tests := []struct {
+		ctx    map[string]interface{}
+		output string
+	}{
+		{map[string]interface{}{"a": "foo", "b": 1}, "ok"},
+		{map[string]interface{}{"a": "foo", "b": "bar"}, "Helper check parameter 1 must be a int, got string"},
+		{map[string]interface{}{"a": "foo"}, "Helper check parameter 1 must be a int, got nil"},
+	}
+
+	for _, test := range tests {
+		if output := tpl.MustExec(test.ctx); output != test.output {
+			t.Errorf("Failed to check helper parameter types with %v: %q", test.ctx, output)
+		}
+	}
+
+	tpl = MustParse(`{{check}} {{check "foo" (check 1 true)}}`)
+	tpl.RegisterHelper("check", VariadicHelper(func(options *Options) interface{} {
+		if err := options.Require(2); err != nil {
+			return err.Error()
+		}
+
+		if err := options.RequireTypes(reflect.Interface, reflect.Interface, reflect.Bool); err != nil {
+			return err.Error()
+		}
+
+		return "ok"
+	}))
+
+	if output := tpl.MustExec(nil); output != "Helper check requires 2 parameters, got 0 Helper check requires 3 parameters, got 2" {
+		t.Errorf("Failed to check helper parameters arity: %q", output)
+	}
+}
+
+func TestHelperRenderString(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each users}}{{greet}}
+{{/each}}`)
+	tpl.RegisterHelper("greet", func(options *Options) SafeString {
+		result, err := options.RenderString(options.ValueStr("greeting"))
+		if err != nil {
+			return "parse error"
+		}
+
+		return result
+	})
+	tpl.RegisterHelper("upper", strings.ToUpper)
+	tpl.RegisterPartial("signature", "-- {{@root.site}}")
+
+	ctx := map[string]interface{}{
+		"site": "example.com",
+		"users": []map[string]string{
+			{"name": "foo", "greeting": "Hello {{upper name}} #{{@index}} {{> signature}}"},
+			{"name": "bar", "greeting": "Hi {{name}}{{#if admin}} (admin){{/if}}", "admin": "yes"},
+			{"name": "baz", "greeting": "Hey {{name"},
+		},
+	}
+
+	expected := "Hello FOO #0 -- example.com\nHi bar (admin)\nparse error\n"
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if output := tpl.MustExec(ctx); output != expected {
+			t.Errorf("Failed to render strings\nexpected\n\t%q\ngot\n\t%q", expected, output)
+		}
+	}
+
+	// parsed sources are cached
+	first, _ := renderTemplates.parse("Hi {{name}}{{#if admin}} (admin){{/if}}")
+	second, _ := renderTemplates.parse("Hi {{name}}{{#if admin}} (admin){{/if}}")
+	if first != second {
+		t.Errorf("Rendered strings must be cached")
+	}
+}
@@ -0,0 +1,39 @@
+package raymond
+
+import (
+	"github.com/aymerick/raymond/ast"
+)
+
+// Interceptor is called around the evaluation of mustaches, blocks and partials, so that their output can be replaced.
+//
+// Both callbacks are optional. They receive the statement node, and the current evaluation context.
+type Interceptor struct {
+	// BeforeNode is called before a node is evaluated. If it returns true, the node is not evaluated and the returned
+	// value is used as its output.
+	BeforeNode func(node ast.Node, ctx interface{}) (interface{}, bool)
+
+	// AfterNode is called after a node was evaluated, with its output, and returns the output to use instead.
+	AfterNode func(node ast.Node, ctx interface{}, output interface{}) interface{}
+}
+
+// intercept evaluates given node with given function, between interceptor callbacks
+func (v *evalVisitor) intercept(node ast.Node, eval func() interface{}) interface{} {
+	var ctx interface{}
+	if val := v.curCtx(); val.IsValid() {
+		ctx = val.Interface()
+	}
+
+	if v.interceptor.BeforeNode != nil {
+		if result, ok := v.interceptor.BeforeNode(node, ctx); ok {
+			return result
+		}
+	}
+
+	result := eval()
+
+	if v.interceptor.AfterNode != nil {
+		result = v.interceptor.AfterNode(node, ctx, result)
+	}
+
+	return result
+}
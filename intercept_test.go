@@ -0,0 +1,97 @@
+package raymond
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+func TestInterceptor(t *testing.T) {
+	t.Parallel()
+
+	source := `{{title}} {{secret}} {{#each items}}[{{> item}}]{{/each}} {{#if admin}}admin{{/if}}`
+
+	ctx := map[string]interface{}{
+		"title":  "<foo>",
+		"secret": "bar",
+		"items":  []string{"a", "b"},
+		"admin":  true,
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterPartial("item", "{{.}}")
+
+	var contexts []string
+
+	tpl.SetInterceptor(&Interceptor{
+		BeforeNode: func(node ast.Node, ctx interface{}) (interface{}, bool) {
+			if node, ok := node.(*ast.MustacheStatement); ok && (node.Expression.Canonical() == "secret") {
+				return "***", true
+			}
+
+			if _, ok := node.(*ast.PartialStatement); ok {
+				contexts = append(contexts, fmt.Sprint(ctx))
+			}
+
+			return nil, false
+		},
+		AfterNode: func(node ast.Node, ctx interface{}, output interface{}) interface{} {
+			if node, ok := node.(*ast.BlockStatement); ok && (node.Expression.HelperName() == "if") {
+				return `<div class="variant-a">` + Str(output) + `</div>`
+			}
+
+			return output
+		},
+	})
+
+	expected := `&lt;foo&gt; *** [a][b] <div class="variant-a">admin</div>`
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		contexts = nil
+
+		if result := tpl.MustExec(ctx); result != expected {
+			t.Errorf("Unexpected intercepted output: %q", result)
+		}
+
+		if fmt.Sprint(contexts) != "[a b]" {
+			t.Errorf("Interceptor must receive current context: %q", contexts)
+		}
+	}
+}
+
+func TestInterceptorWithTraceHook(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{foo}}{{#if bar}}{{baz}}{{/if}}`)
+
+	tpl.SetInterceptor(&Interceptor{
+		BeforeNode: func(node ast.Node, ctx interface{}) (interface{}, bool) {
+			if _, ok := node.(*ast.BlockStatement); ok {
+				return "skipped", true
+			}
+
+			return nil, false
+		},
+	})
+
+	var events []string
+	tpl.SetTraceHook(func(event TraceEvent) {
+		if event.Phase == TraceEnter {
+			events = append(events, fmt.Sprintf("line:%d pos:%d", event.Line, event.Pos))
+		}
+	})
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		events = nil
+
+		if result := tpl.MustExec(map[string]string{"foo": "foo", "bar": "bar"}); result != "fooskipped" {
+			t.Errorf("Unexpected intercepted output: %q", result)
+		}
+
+		if strings.Join(events, ",") != "line:1 pos:0" {
+			t.Errorf("Skipped nodes must not be traced: %q", events)
+		}
+	}
+}
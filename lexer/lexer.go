@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -18,9 +19,16 @@ const (
 	escapedEscapedOpenMustache  = "\\\\{{"
 	escapedOpenMustache         = "\\{{"
 	openMustache                = "{{"
+	openRawMustache             = "{{{{"
+	openEndRawMustache          = "{{{{/"
 	closeMustache               = "}}"
 	closeStripMustache          = "~}}"
+	closeUnescapedMustache      = "}}}"
 	closeUnescapedStripMustache = "}~}}"
+	closeRawMustache            = "}}}}"
+
+	// characters matched by \s in regular expressions
+	spaces = "\t\n\f\r "
 )
 
 const eof = -1
@@ -30,10 +38,18 @@ type lexFunc func(*Lexer) lexFunc
 
 // Lexer is a lexical analyzer.
 type Lexer struct {
-	input    string     // input to scan
-	name     string     // lexer name, used for testing purpose
-	tokens   chan Token // channel of scanned tokens
-	nextFunc lexFunc    // the next function to execute
+	input    string        // input to scan
+	name     string        // lexer name, used for testing purpose
+	tokens   chan Token    // channel of scanned tokens, nil in synchronous mode
+	quit     chan struct{} // closed to stop scanning
+	quitOnce sync.Once     // protects quit channel closing
+	stopped  bool          // scanning was stopped
+	nextFunc lexFunc       // the next function to execute
+
+	// synchronous mode
+	queue []Token // scanned tokens not yet returned
+	head  int     // index of next token to return in queue
+	last  Token   // last returned token, returned again once scanning is over
 
 	pos   int // current byte position in input string
 	line  int // current line position in input string
@@ -50,35 +66,17 @@ var (
 	literalLookheadChars = `[\s` + regexp.QuoteMeta("~})") + `]`
 
 	// characters not allowed in an identifier
-	unallowedIDChars = " \n\t!\"#%&'()*+,./;<=>@[\\]^`{|}~"
+	unallowedIDChars = " \r\n\t!\"#%&'()*+,./;<=>@[\\]^`{|}~"
 
 	// regular expressions
-	rID                  = regexp.MustCompile(`^[^` + regexp.QuoteMeta(unallowedIDChars) + `]+`)
-	rDotID               = regexp.MustCompile(`^\.` + lookheadChars)
-	rTrue                = regexp.MustCompile(`^true` + literalLookheadChars)
-	rFalse               = regexp.MustCompile(`^false` + literalLookheadChars)
-	rOpenRaw             = regexp.MustCompile(`^\{\{\{\{`)
-	rCloseRaw            = regexp.MustCompile(`^\}\}\}\}`)
-	rOpenEndRaw          = regexp.MustCompile(`^\{\{\{\{/`)
-	rOpenEndRawLookAhead = regexp.MustCompile(`\{\{\{\{/`)
-	rOpenUnescaped       = regexp.MustCompile(`^\{\{~?\{`)
-	rCloseUnescaped      = regexp.MustCompile(`^\}~?\}\}`)
-	rOpenBlock           = regexp.MustCompile(`^\{\{~?#`)
-	rOpenEndBlock        = regexp.MustCompile(`^\{\{~?/`)
-	rOpenPartial         = regexp.MustCompile(`^\{\{~?>`)
-	// {{^}} or {{else}}
-	rInverse          = regexp.MustCompile(`^(\{\{~?\^\s*~?\}\}|\{\{~?\s*else\s*~?\}\})`)
-	rOpenInverse      = regexp.MustCompile(`^\{\{~?\^`)
-	rOpenInverseChain = regexp.MustCompile(`^\{\{~?\s*else`)
-	// {{ or {{&
-	rOpen            = regexp.MustCompile(`^\{\{~?&?`)
-	rClose           = regexp.MustCompile(`^~?\}\}`)
+	rID              = regexp.MustCompile(`^[^` + regexp.QuoteMeta(unallowedIDChars) + `]+`)
+	rDotID           = regexp.MustCompile(`^\.` + lookheadChars)
+	rTrue            = regexp.MustCompile(`^true` + literalLookheadChars)
+	rFalse           = regexp.MustCompile(`^false` + literalLookheadChars)
 	rOpenBlockParams = regexp.MustCompile(`^as\s+\|`)
 	// {{!--  ... --}}
-	rOpenCommentDash  = regexp.MustCompile(`^\{\{~?!--\s*`)
 	rCloseCommentDash = regexp.MustCompile(`^\s*--~?\}\}`)
 	// {{! ... }}
-	rOpenComment  = regexp.MustCompile(`^\{\{~?!\s*`)
 	rCloseComment = regexp.MustCompile(`^\s*~?\}\}`)
 )
 
@@ -89,6 +87,18 @@ func Scan(input string) *Lexer {
 	return scanWithName(input, "")
 }
 
+// ScanSync scans given input synchronously.
+//
+// Tokens are scanned on demand by the NextToken() function on returned lexer, without any goroutine. Contrary to Scan(),
+// nothing is left running if all tokens are not fetched.
+func ScanSync(input string) *Lexer {
+	return &Lexer{
+		input:    input,
+		nextFunc: lexContent,
+		line:     1,
+	}
+}
+
 // scanWithName scans given input, with a name used for testing
 //
 // Tokens can then be fetched sequentially thanks to NextToken() function on returned lexer.
@@ -97,6 +107,7 @@ func scanWithName(input string, name string) *Lexer {
 		input:  input,
 		name:   name,
 		tokens: make(chan Token),
+		quit:   make(chan struct{}),
 		line:   1,
 	}
 
@@ -111,7 +122,7 @@ func scanWithName(input string, name string) *Lexer {
 func Collect(input string) []Token {
 	var result []Token
 
-	l := Scan(input)
+	l := ScanSync(input)
 	for {
 		token := l.NextToken()
 		result = append(result, token)
@@ -124,16 +135,79 @@ func Collect(input string) []Token {
 	return result
 }
 
+// Tokenize scans given input and returns all its tokens, without the final EOF token.
+//
+// On lexical error, it returns the tokens scanned before that error, and an error with the line and column where it
+// happened.
+func Tokenize(input string) ([]Token, error) {
+	var result []Token
+
+	l := ScanSync(input)
+	for {
+		token := l.NextToken()
+
+		switch token.Kind {
+		case TokenEOF:
+			return result, nil
+		case TokenError:
+			return result, fmt.Errorf("Lexer error on line %d, column %d: %s", token.Line, Column(input, token.Pos), token.Val)
+		}
+
+		result = append(result, token)
+	}
+}
+
+// Column returns the column of given byte position in input.
+//
+// Columns start at 1, and they are counted in runes so that multi-byte characters count as a single column.
+func Column(input string, pos int) int {
+	if pos > len(input) {
+		pos = len(input)
+	}
+
+	lineStart := strings.LastIndexByte(input[:pos], '\n') + 1
+
+	return utf8.RuneCountInString(input[lineStart:pos]) + 1
+}
+
 // NextToken returns the next scanned token.
 func (l *Lexer) NextToken() Token {
-	result := <-l.tokens
+	if l.tokens != nil {
+		return <-l.tokens
+	}
 
-	return result
+	// synchronous mode: scan until a token is available
+	for l.head == len(l.queue) {
+		if l.nextFunc == nil {
+			return l.last
+		}
+
+		l.queue, l.head = l.queue[:0], 0
+		l.nextFunc = l.nextFunc(l)
+	}
+
+	l.last = l.queue[l.head]
+	l.head++
+
+	return l.last
+}
+
+// Close stops scanning, so that the goroutine started by Scan() terminates even if all tokens were not consumed.
+//
+// NextToken() must not be called after that. It is a no-op in synchronous mode.
+func (l *Lexer) Close() {
+	if l.quit == nil {
+		return
+	}
+
+	l.quitOnce.Do(func() {
+		close(l.quit)
+	})
 }
 
 // run starts lexical analysis
 func (l *Lexer) run() {
-	for l.nextFunc = lexContent; l.nextFunc != nil; {
+	for l.nextFunc = lexContent; (l.nextFunc != nil) && !l.stopped; {
 		l.nextFunc = l.nextFunc(l)
 	}
 }
@@ -153,7 +227,7 @@ func (l *Lexer) next() rune {
 }
 
 func (l *Lexer) produce(kind TokenKind, val string) {
-	l.tokens <- Token{kind, val, l.start, l.line}
+	l.send(Token{kind, val, l.start, l.line})
 
 	// scanning a new token
 	l.start = l.pos
@@ -162,6 +236,19 @@ func (l *Lexer) produce(kind TokenKind, val string) {
 	l.line += strings.Count(val, "\n")
 }
 
+// send sends a scanned token to the channel, or queues it in synchronous mode
+func (l *Lexer) send(token Token) {
+	if l.tokens != nil {
+		select {
+		case l.tokens <- token:
+		case <-l.quit:
+			l.stopped = true
+		}
+	} else {
+		l.queue = append(l.queue, token)
+	}
+}
+
 // emit emits a new scanned token
 func (l *Lexer) emit(kind TokenKind) {
 	l.produce(kind, l.input[l.start:l.pos])
@@ -200,6 +287,9 @@ func (l *Lexer) backup() {
 
 // ignoreskips all characters that have been scanned up to current position
 func (l *Lexer) ignore() {
+	// update line number
+	l.line += strings.Count(l.input[l.start:l.pos], "\n")
+
 	l.start = l.pos
 }
 
@@ -224,7 +314,7 @@ func (l *Lexer) acceptRun(valid string) {
 
 // errorf emits an error token
 func (l *Lexer) errorf(format string, args ...interface{}) lexFunc {
-	l.tokens <- Token{TokenError, fmt.Sprintf(format, args...), l.start, l.line}
+	l.send(Token{TokenError, fmt.Sprintf(format, args...), l.start, l.line})
 	return nil
 }
 
@@ -238,15 +328,135 @@ func (l *Lexer) findRegexp(r *regexp.Regexp) string {
 	return r.FindString(l.input[l.pos:])
 }
 
-// indexRegexp returns the index of the first string from current scanning position that matches given regular expression
-//
-// It returns -1 if not found
-func (l *Lexer) indexRegexp(r *regexp.Regexp) int {
-	loc := r.FindStringIndex(l.input[l.pos:])
-	if loc == nil {
-		return -1
+// byteAt returns the byte at given offset from current scanning position, or 0 if out of input
+func (l *Lexer) byteAt(offset int) byte {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
+// skipSpaces returns the offset of the first non space character from given offset
+func (l *Lexer) skipSpaces(offset int) int {
+	for strings.IndexByte(spaces, l.byteAt(offset)) >= 0 {
+		offset++
 	}
-	return loc[0]
+	return offset
+}
+
+// openComment returns the regular expression that scans the close of the comment opened at current scanning position,
+// or nil if current scanning position is not at an open comment
+func (l *Lexer) openComment() *regexp.Regexp {
+	i := len(openMustache)
+	if l.byteAt(i) == '~' {
+		i++
+	}
+
+	if l.byteAt(i) != '!' {
+		return nil
+	}
+
+	if (l.byteAt(i+1) == '-') && (l.byteAt(i+2) == '-') {
+		// {{!--
+		return rCloseCommentDash
+	}
+
+	// {{!
+	return rCloseComment
+}
+
+// openMustache returns the kind and the length of the open mustache token at current scanning position
+func (l *Lexer) openMustache() (TokenKind, int) {
+	if l.isString(openEndRawMustache) {
+		// {{{{/
+		return TokenOpenEndRawBlock, len(openEndRawMustache)
+	}
+
+	if l.isString(openRawMustache) {
+		// {{{{
+		return TokenOpenRawBlock, len(openRawMustache)
+	}
+
+	// skip {{ and strip flag
+	i := len(openMustache)
+	if l.byteAt(i) == '~' {
+		i++
+	}
+
+	switch l.byteAt(i) {
+	case '{':
+		// {{{
+		return TokenOpenUnescaped, i + 1
+	case '#':
+		// {{#
+		return TokenOpenBlock, i + 1
+	case '/':
+		// {{/
+		return TokenOpenEndBlock, i + 1
+	case '>':
+		// {{>
+		return TokenOpenPartial, i + 1
+	case '^':
+		if end := l.closeInverse(i + 1); end != -1 {
+			// {{^}}
+			return TokenInverse, end
+		}
+
+		// {{^
+		return TokenOpenInverse, i + 1
+	case '&':
+		// {{&
+		return TokenOpen, i + 1
+	}
+
+	if j := l.skipSpaces(i); strings.HasPrefix(l.input[l.pos+j:], "else") {
+		if end := l.closeInverse(j + len("else")); end != -1 {
+			// {{else}}
+			return TokenInverse, end
+		}
+
+		// {{else
+		return TokenOpenInverseChain, j + len("else")
+	}
+
+	// {{
+	return TokenOpen, i
+}
+
+// closeInverse returns the offset following the close mustache of an inverse, starting at given offset and
+// ignoring spaces, or -1 if there is none
+func (l *Lexer) closeInverse(offset int) int {
+	i := l.skipSpaces(offset)
+	if l.byteAt(i) == '~' {
+		i++
+	}
+
+	if strings.HasPrefix(l.input[l.pos+i:], closeMustache) {
+		return i + len(closeMustache)
+	}
+
+	return -1
+}
+
+// closeMustache returns the kind and the length of the close mustache token at current scanning position
+func (l *Lexer) closeMustache() (TokenKind, int) {
+	switch {
+	case l.isString(closeRawMustache):
+		// }}}}
+		return TokenCloseRawBlock, len(closeRawMustache)
+	case l.isString(closeUnescapedMustache):
+		// }}}
+		return TokenCloseUnescaped, len(closeUnescapedMustache)
+	case l.isString(closeUnescapedStripMustache):
+		// }~}}
+		return TokenCloseUnescaped, len(closeUnescapedStripMustache)
+	case l.isString(closeStripMustache):
+		// ~}}
+		return TokenClose, len(closeStripMustache)
+	}
+
+	// }}
+	return TokenClose, len(closeMustache)
 }
 
 // lexContent scans content (ie: not between mustaches)
@@ -254,7 +464,7 @@ func lexContent(l *Lexer) lexFunc {
 	var next lexFunc
 
 	if l.rawBlock {
-		if i := l.indexRegexp(rOpenEndRawLookAhead); i != -1 {
+		if i := strings.Index(l.input[l.pos:], openEndRawMustache); i != -1 {
 			// {{{{/
 			l.rawBlock = false
 			l.pos += i
@@ -278,19 +488,14 @@ func lexContent(l *Lexer) lexFunc {
 	} else if l.isString(escapedOpenMustache) {
 		// \{{
 		next = lexEscapedOpenMustache
-	} else if str := l.findRegexp(rOpenCommentDash); str != "" {
-		// {{!--
-		l.closeComment = rCloseCommentDash
-
-		next = lexComment
-	} else if str := l.findRegexp(rOpenComment); str != "" {
-		// {{!
-		l.closeComment = rCloseComment
-
-		next = lexComment
 	} else if l.isString(openMustache) {
-		// {{
-		next = lexOpenMustache
+		if l.closeComment = l.openComment(); l.closeComment != nil {
+			// {{! or {{!--
+			next = lexComment
+		} else {
+			// {{
+			next = lexOpenMustache
+		}
 	}
 
 	if next != nil {
@@ -331,39 +536,17 @@ func lexEscapedOpenMustache(l *Lexer) lexFunc {
 
 // lexOpenMustache scans {{
 func lexOpenMustache(l *Lexer) lexFunc {
-	var str string
-	var tok TokenKind
+	tok, length := l.openMustache()
 
 	nextFunc := lexExpression
-
-	if str = l.findRegexp(rOpenEndRaw); str != "" {
-		tok = TokenOpenEndRawBlock
-	} else if str = l.findRegexp(rOpenRaw); str != "" {
-		tok = TokenOpenRawBlock
+	switch tok {
+	case TokenOpenRawBlock:
 		l.rawBlock = true
-	} else if str = l.findRegexp(rOpenUnescaped); str != "" {
-		tok = TokenOpenUnescaped
-	} else if str = l.findRegexp(rOpenBlock); str != "" {
-		tok = TokenOpenBlock
-	} else if str = l.findRegexp(rOpenEndBlock); str != "" {
-		tok = TokenOpenEndBlock
-	} else if str = l.findRegexp(rOpenPartial); str != "" {
-		tok = TokenOpenPartial
-	} else if str = l.findRegexp(rInverse); str != "" {
-		tok = TokenInverse
+	case TokenInverse:
 		nextFunc = lexContent
-	} else if str = l.findRegexp(rOpenInverse); str != "" {
-		tok = TokenOpenInverse
-	} else if str = l.findRegexp(rOpenInverseChain); str != "" {
-		tok = TokenOpenInverseChain
-	} else if str = l.findRegexp(rOpen); str != "" {
-		tok = TokenOpen
-	} else {
-		// this is rotten
-		panic("Current pos MUST be an opening mustache")
 	}
 
-	l.pos += len(str)
+	l.pos += length
 	l.emit(tok)
 
 	return nextFunc
@@ -371,24 +554,9 @@ func lexOpenMustache(l *Lexer) lexFunc {
 
 // lexCloseMustache scans }} or ~}}
 func lexCloseMustache(l *Lexer) lexFunc {
-	var str string
-	var tok TokenKind
+	tok, length := l.closeMustache()
 
-	if str = l.findRegexp(rCloseRaw); str != "" {
-		// }}}}
-		tok = TokenCloseRawBlock
-	} else if str = l.findRegexp(rCloseUnescaped); str != "" {
-		// }}}
-		tok = TokenCloseUnescaped
-	} else if str = l.findRegexp(rClose); str != "" {
-		// }}
-		tok = TokenClose
-	} else {
-		// this is rotten
-		panic("Current pos MUST be a closing mustache")
-	}
-
-	l.pos += len(str)
+	l.pos += length
 	l.emit(tok)
 
 	return lexContent
@@ -457,6 +625,11 @@ func lexExpression(l *Lexer) lexFunc {
 		return lexString
 	case r == '/' || r == '.':
 		l.emit(TokenSep)
+
+		// a path segment can start with a digit, eg: `items.1`
+		if r := l.peek(); r >= '0' && r <= '9' {
+			return lexIdentifier
+		}
 	case r == '|':
 		l.emit(TokenCloseBlockParams)
 	case r == '+' || r == '-' || (r >= '0' && r <= '9'):
@@ -475,6 +648,9 @@ func lexExpression(l *Lexer) lexFunc {
 }
 
 // lexComment scans {{!-- or {{!
+//
+// As in the JS implementation, a {{! comment ends at the first }}, whereas a {{!-- comment ends at the first --}} so it can
+// contain mustaches.
 func lexComment(l *Lexer) lexFunc {
 	if str := l.findRegexp(l.closeComment); str != "" {
 		l.pos += len(str)
@@ -626,9 +802,9 @@ func lexPathLiteral(l *Lexer) lexFunc {
 	return lexExpression
 }
 
-// isIgnorable returns true if given character is ignorable (ie. whitespace of line feed)
+// isIgnorable returns true if given character is ignorable (ie. whitespace of line feed, including CRLF line endings)
 func isIgnorable(r rune) bool {
-	return r == ' ' || r == '\t' || r == '\n'
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
 }
 
 // isAlphaNumeric reports whether r is an alphabetic, digit, or underscore.
@@ -2,7 +2,10 @@ package lexer
 
 import (
 	"fmt"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 type lexTest struct {
@@ -172,6 +175,11 @@ var lexTests = []lexTest{
 		`{{foo.bar.baz}}`,
 		[]Token{tokOpen, tokID("foo"), tokSep("."), tokID("bar"), tokSep("."), tokID("baz"), tokClose, tokEOF},
 	},
+	{
+		`allows dot notation with numeric segments`,
+		`{{foo.1.bar}}`,
+		[]Token{tokOpen, tokID("foo"), tokSep("."), tokID("1"), tokSep("."), tokID("bar"), tokClose, tokEOF},
+	},
 	{
 		`allows path literals with []`,
 		`{{foo.[bar]}}`,
@@ -212,6 +220,11 @@ var lexTests = []lexTest{
 		"{{  foo  \n   bar }}",
 		[]Token{tokOpen, tokID("foo"), tokID("bar"), tokClose, tokEOF},
 	},
+	{
+		`tokenizes a simple mustache with CRLF line breaks as "OPEN ID ID CLOSE"`,
+		"{{  foo\r\n   bar\r\n}}",
+		[]Token{tokOpen, tokID("foo"), tokID("bar"), tokClose, tokEOF},
+	},
 	{
 		`tokenizes raw content as "CONTENT"`,
 		`foo {{ bar }} baz`,
@@ -222,6 +235,11 @@ var lexTests = []lexTest{
 		`{{> foo}}`,
 		[]Token{tokOpenPartial, tokID("foo"), tokClose, tokEOF},
 	},
+	{
+		`tokenizes a partial with whitespace control as "OPEN_PARTIAL ID CLOSE"`,
+		`{{~> foo ~}}`,
+		[]Token{{TokenOpenPartial, "{{~>", 0, 1}, tokID("foo"), tokCloseStrip, tokEOF},
+	},
 	{
 		`tokenizes a partial with context as "OPEN_PARTIAL ID ID CLOSE"`,
 		`{{> foo bar }}`,
@@ -257,6 +275,16 @@ var lexTests = []lexTest{
 		"foo {{!-- this is a\n{{comment}}\n--}} bar {{ baz }}",
 		[]Token{tokContent("foo "), tokComment("{{!-- this is a\n{{comment}}\n--}}"), tokContent(" bar "), tokOpen, tokID("baz"), tokClose, tokEOF},
 	},
+	{
+		`ends a comment at the first "}}"`,
+		`foo {{! this is a {{comment}} }} bar`,
+		[]Token{tokContent("foo "), tokComment("{{! this is a {{comment}}"), tokContent(" }} bar"), tokEOF},
+	},
+	{
+		`tokenizes a block comment containing "}}" as "COMMENT"`,
+		`foo {{!-- this }} is a {{#comment}}{{/comment}} --}} bar`,
+		[]Token{tokContent("foo "), tokComment("{{!-- this }} is a {{#comment}}{{/comment}} --}}"), tokContent(" bar"), tokEOF},
+	},
 	{
 		`tokenizes open and closing blocks as OPEN_BLOCK, ID, CLOSE ..., OPEN_ENDBLOCK ID CLOSE`,
 		`{{#foo}}content{{/foo}}`,
@@ -277,6 +305,21 @@ var lexTests = []lexTest{
 		`{{ else }}`,
 		[]Token{tokInverse("{{ else }}"), tokEOF},
 	},
+	{
+		`tokenizes inverse sections as "INVERSE" with strip flags`,
+		"{{~^~}} {{~\telse\n~}}",
+		[]Token{tokInverse("{{~^~}}"), tokContent(" "), tokInverse("{{~\telse\n~}}"), tokEOF},
+	},
+	{
+		`tokenizes an ID starting with else as "OPEN_INVERSE_CHAIN ID CLOSE"`,
+		`{{elsewhere}}`,
+		[]Token{tokOpenInverseChain, tokID("where"), tokClose, tokEOF},
+	},
+	{
+		`tokenizes comments with strip flags as "COMMENT"`,
+		`{{~! foo ~}}{{~!-- bar --~}}`,
+		[]Token{tokComment("{{~! foo ~}}"), tokComment("{{~!-- bar --~}}"), tokEOF},
+	},
 	{
 		`tokenizes inverse sections with ID as "OPEN_INVERSE ID CLOSE"`,
 		`{{^foo}}`,
@@ -463,6 +506,16 @@ var lexTests = []lexTest{
 		`{{else foo as |bar baz|}}`,
 		[]Token{tokOpenInverseChain, tokID("foo"), tokOpenBlockParams, tokID("bar"), tokID("baz"), tokCloseBlockParams, tokClose, tokEOF},
 	},
+	{
+		`does not tokenize block params delimiters in strings (1)`,
+		`{{foo "a|b" 'as |c|'}}`,
+		[]Token{tokOpen, tokID("foo"), tokString("a|b"), tokString("as |c|"), tokClose, tokEOF},
+	},
+	{
+		`does not tokenize block params delimiters in strings (2)`,
+		`{{#foo "|" as |bar|}}`,
+		[]Token{tokOpenBlock, tokID("foo"), tokString("|"), tokOpenBlockParams, tokID("bar"), tokCloseBlockParams, tokClose, tokEOF},
+	},
 }
 
 func collect(t *lexTest) []Token {
@@ -503,6 +556,81 @@ func equal(i1, i2 []Token, checkPos bool) bool {
 	return true
 }
 
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tokens, err := Tokenize("{{#each x}}y{{/each}}")
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %s", err)
+	}
+
+	expected := []Token{
+		{TokenOpenBlock, "{{#", 0, 1},
+		{TokenID, "each", 3, 1},
+		{TokenID, "x", 8, 1},
+		{TokenClose, "}}", 9, 1},
+		{TokenContent, "y", 11, 1},
+		{TokenOpenEndBlock, "{{/", 12, 1},
+		{TokenID, "each", 15, 1},
+		{TokenClose, "}}", 19, 1},
+	}
+
+	if !equal(tokens, expected, true) {
+		t.Errorf("Failed to tokenize\nexpected\n\t%v\ngot\n\t%+v", expected, tokens)
+	}
+
+	tokens, err = Tokenize("foo\nbär {{baz &}}")
+	if (err == nil) || (err.Error() != "Lexer error on line 2, column 11: Unexpected character in expression: '&'") {
+		t.Errorf("Tokenize must fail on lexer error: %v", err)
+	}
+
+	if len(tokens) != 3 {
+		t.Errorf("Tokenize must return tokens scanned before lexer error: %v", tokens)
+	}
+}
+
+func TestColumn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		pos    int
+		column int
+	}{
+		{"{{foo}}", 0, 1},
+		{"{{foo}}", 2, 3},
+		{"é😀{{foo}}", 6, 3},
+		{"foo\nbär {{baz}}", 9, 5},
+		{"foo", 10, 4},
+	}
+
+	for _, test := range tests {
+		if column := Column(test.input, test.pos); column != test.column {
+			t.Errorf("Failed to compute column of position %d in %q: expected %d, got %d", test.pos, test.input, test.column, column)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	t.Parallel()
+
+	tokens, err := Tokenize("a\n{{foo\n  bar\r\n\"baz\"\n}}\nb")
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %s", err)
+	}
+
+	expected := []int{1, 2, 2, 3, 4, 5, 5}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Failed to tokenize: %+v", tokens)
+	}
+
+	for i, token := range tokens {
+		if token.Line != expected[i] {
+			t.Errorf("Failed to compute line of token %s: expected %d, got %d", token, expected[i], token.Line)
+		}
+	}
+}
+
 func TestLexer(t *testing.T) {
 	t.Parallel()
 
@@ -514,6 +642,75 @@ func TestLexer(t *testing.T) {
 	}
 }
 
+func TestLexerSync(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range lexTests {
+		tokens := collect(&test)
+
+		var syncTokens []Token
+
+		l := ScanSync(test.input)
+		for {
+			token := l.NextToken()
+			syncTokens = append(syncTokens, token)
+
+			if token.Kind == TokenEOF || token.Kind == TokenError {
+				break
+			}
+		}
+
+		if !equal(syncTokens, tokens, true) {
+			t.Errorf("Test '%s' failed in synchronous mode\ninput:\n\t'%s'\nexpected\n\t%v\ngot\n\t%+v\n", test.name, test.input, tokens, syncTokens)
+		}
+
+		// last token is returned again once scanning is over
+		if last := l.NextToken(); last != syncTokens[len(syncTokens)-1] {
+			t.Errorf("Test '%s' failed in synchronous mode, expected last token %s to be returned again, got %s", test.name, syncTokens[len(syncTokens)-1], last)
+		}
+	}
+}
+
+func TestScanCloseNoLeak(t *testing.T) {
+	inputs := []string{
+		"{{foo}} {{bar}} {{baz}}",
+		"{{#foo}} {{/bar}} {{baz}}",
+		"{{foo bar=}} {{{baz}}",
+		"{{foo}} {{! unclosed comment",
+	}
+
+	before := runtime.NumGoroutine()
+
+	var lexers []*Lexer
+	for i := 0; i < 100; i++ {
+		for _, input := range inputs {
+			l := Scan(input)
+			l.NextToken()
+			lexers = append(lexers, l)
+		}
+	}
+
+	// abandoned lexers goroutines are blocked until they are closed
+	if running := runtime.NumGoroutine() - before; running < len(lexers) {
+		t.Fatalf("Test failed - expected %d lexers goroutines to be running, got %d", len(lexers), running)
+	}
+
+	for _, l := range lexers {
+		l.Close()
+		l.Close()
+	}
+
+	// lexers goroutines exit asynchronously
+	deadline := time.Now().Add(5 * time.Second)
+	for (runtime.NumGoroutine() > before) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Test failed - %d goroutines leaked by closed lexers", after-before)
+	}
+}
+
 // @todo Test errors:
 //   `{{{{raw foo`
 
@@ -539,3 +736,16 @@ func Example() {
 	fmt.Print(output)
 	// Output: Content{"You know "} Open{"{{"} ID{"nothing"} Close{"}}"} Content{" John Snow"} EOF
 }
+
+func BenchmarkScanMustaches(b *testing.B) {
+	input := strings.Repeat(`<li>{{#if active}}{{~name~}} {{{raw}}} {{! note }}{{else}}{{> item}}{{/if}} {{^list}}{{foo.bar}}{{/list}}</li>`, 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Tokenize(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package lexer
+
+import "testing"
+
+func TestTokenKindString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		kind     TokenKind
+		expected string
+	}{
+		{TokenOpenBlock, "OpenBlock"},
+		{TokenCloseUnescaped, "CloseUnescaped"},
+		{TokenEOF, "EOF"},
+		{TokenKind(999), "Token-999"},
+	}
+
+	for _, test := range tests {
+		if str := test.kind.String(); str != test.expected {
+			t.Errorf("Failed to stringify token kind %d: expected %q, got %q", int(test.kind), test.expected, str)
+		}
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		token    Token
+		expected string
+	}{
+		{Token{TokenOpenBlock, "{{#", 0, 1}, `OpenBlock{"{{#"}`},
+		{Token{TokenID, "each", 3, 1}, `ID{"each"}`},
+		{Token{TokenEOF, "", 10, 1}, `EOF`},
+	}
+
+	for _, test := range tests {
+		if str := test.token.String(); str != test.expected {
+			t.Errorf("Failed to stringify token: expected %q, got %q", test.expected, str)
+		}
+	}
+}
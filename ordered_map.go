@@ -0,0 +1,181 @@
+package raymond
+
+import (
+	"reflect"
+	"sync"
+)
+
+// OrderedMap is implemented by maps that keep their keys ordered.
+//
+// The #each helper iterates over an ordered map in keys order, and keys of an ordered map can be looked up in paths,
+// like keys of a go map.
+type OrderedMap interface {
+	// Keys returns map keys, in order
+	Keys() []string
+
+	// Get returns value for given key
+	Get(key string) interface{}
+}
+
+var orderedMapType = reflect.TypeOf((*OrderedMap)(nil)).Elem()
+
+// orderedMapKind tells if a type is an ordered map
+type orderedMapKind int
+
+const (
+	notOrderedMap orderedMapKind = iota
+	orderedMapValue
+	orderedMapPointer // pointer type implements OrderedMap
+	orderedMapSlice   // yaml.MapSlice
+)
+
+// orderedMapKinds caches ordered map kinds of types
+var orderedMapKinds sync.Map // map[reflect.Type]orderedMapKind
+
+// orderedMapKindOf returns the ordered map kind of given type
+func orderedMapKindOf(typ reflect.Type) orderedMapKind {
+	if (typ.Name() == "") && (typ.Kind() != reflect.Struct) && (typ.Kind() != reflect.Ptr) {
+		// no method, eg: map[string]interface{}
+		return notOrderedMap
+	}
+
+	if kind, ok := orderedMapKinds.Load(typ); ok {
+		return kind.(orderedMapKind)
+	}
+
+	kind := notOrderedMap
+
+	switch {
+	case typ.Implements(orderedMapType):
+		kind = orderedMapValue
+	case (typ.Kind() != reflect.Ptr) && reflect.PtrTo(typ).Implements(orderedMapType):
+		kind = orderedMapPointer
+	case isMapSlice(typ):
+		kind = orderedMapSlice
+	}
+
+	orderedMapKinds.Store(typ, kind)
+
+	return kind
+}
+
+// isMapSlice returns true if given type is yaml.MapSlice
+//
+// To avoid a dependency on the yaml package, a MapSlice is detected by its structure: a slice named MapSlice of structs
+// with Key and Value fields.
+func isMapSlice(typ reflect.Type) bool {
+	if (typ.Kind() != reflect.Slice) || (typ.Name() != "MapSlice") || (typ.Elem().Kind() != reflect.Struct) {
+		return false
+	}
+
+	_, hasKey := typ.Elem().FieldByName("Key")
+	_, hasValue := typ.Elem().FieldByName("Value")
+
+	return hasKey && hasValue
+}
+
+// asOrderedMap returns given value as an OrderedMap, if it implements that interface
+func asOrderedMap(val reflect.Value) (OrderedMap, bool) {
+	if !val.IsValid() || !val.CanInterface() {
+		return nil, false
+	}
+
+	switch orderedMapKindOf(val.Type()) {
+	case orderedMapValue:
+		return val.Interface().(OrderedMap), true
+	case orderedMapPointer:
+		if val.CanAddr() {
+			return val.Addr().Interface().(OrderedMap), true
+		}
+	}
+
+	return nil, false
+}
+
+// isOrderedMap returns true if given value is an ordered map or a yaml.MapSlice
+func isOrderedMap(val reflect.Value) bool {
+	if !val.IsValid() {
+		return false
+	}
+
+	switch orderedMapKindOf(val.Type()) {
+	case orderedMapValue, orderedMapSlice:
+		return true
+	case orderedMapPointer:
+		return val.CanAddr()
+	}
+
+	return false
+}
+
+// isMapSliceValue returns true if given value is a yaml.MapSlice
+func isMapSliceValue(val reflect.Value) bool {
+	return val.IsValid() && (orderedMapKindOf(val.Type()) == orderedMapSlice)
+}
+
+// orderedMapAddr returns a pointer to given value if that pointer is an ordered map, so that the map is not copied and
+// still implements OrderedMap once converted to an interface
+func orderedMapAddr(val reflect.Value) reflect.Value {
+	if val.IsValid() && val.CanAddr() && (orderedMapKindOf(val.Type()) == orderedMapPointer) {
+		return val.Addr()
+	}
+
+	return val
+}
+
+// kindOf returns the kind of given value, ordered maps and yaml.MapSlice values being considered as maps
+func kindOf(val reflect.Value) reflect.Kind {
+	if isOrderedMap(val) {
+		return reflect.Map
+	}
+
+	return val.Kind()
+}
+
+// orderedMapItems returns the items of given ordered map or yaml.MapSlice in order, with a boolean set to false if
+// given value is neither of them
+func orderedMapItems(val reflect.Value) ([]eachItem, bool) {
+	if m, ok := asOrderedMap(val); ok {
+		var result []eachItem
+
+		for _, key := range m.Keys() {
+			result = append(result, eachItem{key, m.Get(key)})
+		}
+
+		return result, true
+	}
+
+	if isMapSliceValue(val) {
+		result := make([]eachItem, val.Len())
+
+		for i := 0; i < val.Len(); i++ {
+			item := val.Index(i)
+			result[i] = eachItem{item.FieldByName("Key").Interface(), item.FieldByName("Value").Interface()}
+		}
+
+		return result, true
+	}
+
+	return nil, false
+}
+
+// orderedMapGet returns the value for given key in given ordered map or yaml.MapSlice, with a boolean set to false if
+// given value is neither of them
+func orderedMapGet(val reflect.Value, key string) (reflect.Value, bool) {
+	if m, ok := asOrderedMap(val); ok {
+		return reflect.ValueOf(m.Get(key)), true
+	}
+
+	if isMapSliceValue(val) {
+		for i := 0; i < val.Len(); i++ {
+			item := val.Index(i)
+			if Str(item.FieldByName("Key").Interface()) == key {
+				return item.FieldByName("Value"), true
+			}
+		}
+
+		return zero, true
+	}
+
+	return zero, false
+}
@@ -0,0 +1,92 @@
+package raymond
+
+import "testing"
+
+// testOrderedMap implements OrderedMap
+type testOrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newTestOrderedMap(pairs ...interface{}) *testOrderedMap {
+	result := &testOrderedMap{values: make(map[string]interface{})}
+
+	for i := 0; i < len(pairs); i += 2 {
+		key := pairs[i].(string)
+
+		result.keys = append(result.keys, key)
+		result.values[key] = pairs[i+1]
+	}
+
+	return result
+}
+
+func (m *testOrderedMap) Keys() []string {
+	return m.keys
+}
+
+func (m *testOrderedMap) Get(key string) interface{} {
+	return m.values[key]
+}
+
+// MapItem mimics yaml.MapItem
+type MapItem struct {
+	Key, Value interface{}
+}
+
+// MapSlice mimics yaml.MapSlice
+type MapSlice []MapItem
+
+var orderedMapTests = []Test{
+	{
+		"#each helper with an ordered map",
+		`{{#each config}}{{@index}}:{{@key}}={{this}} {{/each}}`,
+		map[string]interface{}{"config": newTestOrderedMap("zeta", 1, "alpha", 2, "mu", 3)},
+		nil, nil, nil,
+		`0:zeta=1 1:alpha=2 2:mu=3 `,
+	},
+	{
+		"#each helper with a yaml.MapSlice",
+		`{{#each config as |value key|}}{{key}}={{value}}{{#unless @last}}, {{/unless}}{{/each}}`,
+		map[string]interface{}{"config": MapSlice{{"zeta", 1}, {"alpha", 2}, {3, "three"}}},
+		nil, nil, nil,
+		`zeta=1, alpha=2, 3=three`,
+	},
+	{
+		"#each helper with an empty ordered map",
+		`{{#each config}}{{this}}{{else}}empty{{/each}} {{#each slice}}{{this}}{{else}}empty{{/each}}`,
+		map[string]interface{}{"config": newTestOrderedMap(), "slice": MapSlice{}},
+		nil, nil, nil,
+		`empty empty`,
+	},
+	{
+		"path lookup in a yaml.MapSlice tree",
+		`{{config.database.host}}:{{config.database.port}} [{{config.database.missing}}]`,
+		map[string]interface{}{"config": MapSlice{
+			{"name", "app"},
+			{"database", MapSlice{{"host", "localhost"}, {"port", 5432}}},
+		}},
+		nil, nil, nil,
+		`localhost:5432 []`,
+	},
+	{
+		"path lookup in an ordered map",
+		`{{config.database.host}} {{#with config.database}}{{port}}{{/with}}`,
+		map[string]interface{}{"config": newTestOrderedMap("database", newTestOrderedMap("host", "localhost", "port", 5432))},
+		nil, nil, nil,
+		`localhost 5432`,
+	},
+	{
+		"block with a yaml.MapSlice context",
+		`{{#config}}{{name}}{{/config}} {{#with config}}{{name}}{{/with}}`,
+		map[string]interface{}{"config": MapSlice{{"name", "app"}}},
+		nil, nil, nil,
+		`app app`,
+	},
+}
+
+func TestOrderedMap(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, orderedMapTests)
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/aymerick/raymond/ast"
 	"github.com/aymerick/raymond/lexer"
@@ -28,6 +29,9 @@ type parser struct {
 
 	// All tokens have been retreieved from lexer
 	lexOver bool
+
+	// Duplicate hash keys are accepted
+	lenient bool
 }
 
 var (
@@ -39,16 +43,39 @@ var (
 // new instanciates a new parser
 func new(input string) *parser {
 	return &parser{
-		lex: lexer.Scan(input),
+		lex: lexer.ScanSync(input),
 	}
 }
 
 // Parse analyzes given input and returns the AST root node.
 func Parse(input string) (result *ast.Program, err error) {
+	// template without any mustache
+	if !strings.Contains(input, "{{") {
+		return parseContentOnly(input), nil
+	}
+
+	return parse(input, false)
+}
+
+// ParseLenient analyzes given input and returns the AST root node, like Parse(), but it accepts duplicate hash keys.
+//
+// All hash pairs are kept in the AST, and the last value of a duplicated key is the one used by evaluation.
+func ParseLenient(input string) (result *ast.Program, err error) {
+	// template without any mustache
+	if !strings.Contains(input, "{{") {
+		return parseContentOnly(input), nil
+	}
+
+	return parse(input, true)
+}
+
+// parse analyses given input with the lexer and returns the resulting program
+func parse(input string, lenient bool) (result *ast.Program, err error) {
 	// recover error
-	defer errRecover(&err)
+	defer errRecover(input, &err)
 
 	parser := new(input)
+	parser.lenient = lenient
 
 	// parse
 	result = parser.parseProgram()
@@ -67,13 +94,33 @@ func Parse(input string) (result *ast.Program, err error) {
 	return
 }
 
+// parseContentOnly returns the program of a template that does not contain any mustache, without scanning it
+func parseContentOnly(input string) *ast.Program {
+	result := ast.NewProgram(0, 1)
+
+	if input != "" {
+		result.AddStatement(ast.NewContentStatement(0, 1, input))
+	}
+
+	return result
+}
+
+// parseError is a parsing error at a given position
+type parseError struct {
+	err  error
+	line int
+	pos  int
+}
+
 // errRecover recovers parsing panic
-func errRecover(errp *error) {
+func errRecover(input string, errp *error) {
 	e := recover()
 	if e != nil {
 		switch err := e.(type) {
 		case runtime.Error:
 			panic(e)
+		case *parseError:
+			*errp = fmt.Errorf("Parse error on line %d, column %d:\n%s", err.line, lexer.Column(input, err.pos), err.err)
 		case error:
 			*errp = err
 		default:
@@ -83,23 +130,23 @@ func errRecover(errp *error) {
 }
 
 // errPanic panics
-func errPanic(err error, line int) {
-	panic(fmt.Errorf("Parse error on line %d:\n%s", line, err))
+func errPanic(err error, line int, pos int) {
+	panic(&parseError{err, line, pos})
 }
 
 // errNode panics with given node infos
 func errNode(node ast.Node, msg string) {
-	errPanic(fmt.Errorf("%s\nNode: %s", msg, node), node.Location().Line)
+	errPanic(fmt.Errorf("%s\nNode: %s", msg, node), node.Location().Line, node.Location().Pos)
 }
 
 // errNode panics with given Token infos
 func errToken(tok *lexer.Token, msg string) {
-	errPanic(fmt.Errorf("%s\nToken: %s", msg, tok), tok.Line)
+	errPanic(fmt.Errorf("%s\nToken: %s", msg, tok), tok.Line, tok.Pos)
 }
 
 // errNode panics because of an unexpected Token kind
 func errExpected(expect lexer.TokenKind, tok *lexer.Token) {
-	errPanic(fmt.Errorf("Expecting %s, got: '%s'", expect, tok), tok.Line)
+	errPanic(fmt.Errorf("Expecting %s, got: '%s'", expect, tok), tok.Line, tok.Pos)
 }
 
 // program : statement*
@@ -167,7 +214,6 @@ func (p *parser) parseContent() *ast.ContentStatement {
 	// CONTENT
 	tok := p.shift()
 	if tok.Kind != lexer.TokenContent {
-		// @todo This check can be removed if content is optional in a raw block
 		errExpected(lexer.TokenContent, tok)
 	}
 
@@ -240,8 +286,13 @@ func (p *parser) parseRawBlock() *ast.BlockStatement {
 	}
 
 	// content
-	// @todo Is content mandatory in a raw block ?
-	content := p.parseContent()
+	var content *ast.ContentStatement
+	if p.isToken(lexer.TokenContent) {
+		content = p.parseContent()
+	} else {
+		// empty raw block, its program is still a single content statement
+		content = ast.NewContentStatement(tok.Pos+len(tok.Val), tok.Line, "")
+	}
 
 	program := ast.NewProgram(tok.Pos, tok.Line)
 	program.AddStatement(content)
@@ -386,6 +437,8 @@ func (p *parser) parseInverseChain() *ast.Program {
 
 	setBlockInverseStrip(block)
 
+	// the `{{else if}}` tag whitespace control applies to the parent block inverse
+	result.Strip = block.OpenStrip
 	result.Chained = true
 	result.AddStatement(block)
 
@@ -572,8 +625,17 @@ func (p *parser) parseSexpr() *ast.SubExpression {
 func (p *parser) parseHash() *ast.Hash {
 	var pairs []*ast.HashPair
 
+	seen := make(map[string]bool)
+
 	for p.isHashSegment() {
-		pairs = append(pairs, p.parseHashSegment())
+		pair := p.parseHashSegment()
+
+		if seen[pair.Key] && !p.lenient {
+			errNode(pair, fmt.Sprintf("Duplicate hash key: %s", pair.Key))
+		}
+		seen[pair.Key] = true
+
+		pairs = append(pairs, pair)
 	}
 
 	firstLoc := pairs[0].Location()
@@ -647,8 +709,7 @@ func (p *parser) parseHelperName() ast.Node {
 		// NUMBER
 		p.shift()
 
-		val, isInt := parseNumber(tok)
-		result = ast.NewNumberLiteral(tok.Pos, tok.Line, val, isInt, tok.Val)
+		result = parseNumber(tok)
 	case lexer.TokenString:
 		// STRING
 		p.shift()
@@ -665,26 +726,24 @@ func (p *parser) parseHelperName() ast.Node {
 }
 
 // parseNumber parses a number
-func parseNumber(tok *lexer.Token) (result float64, isInt bool) {
-	var valInt int
-	var err error
-
-	valInt, err = strconv.Atoi(tok.Val)
+//
+// Integer literals keep their exact int64 value, so that they are never silently rounded.
+func parseNumber(tok *lexer.Token) *ast.NumberLiteral {
+	valInt, err := strconv.ParseInt(tok.Val, 10, 64)
 	if err == nil {
-		isInt = true
+		return ast.NewIntegerLiteral(tok.Pos, tok.Line, valInt, tok.Val)
+	}
 
-		result = float64(valInt)
-	} else {
-		isInt = false
+	if numErr, ok := err.(*strconv.NumError); ok && (numErr.Err == strconv.ErrRange) {
+		errToken(tok, fmt.Sprintf("Integer out of range: %s", tok.Val))
+	}
 
-		result, err = strconv.ParseFloat(tok.Val, 64)
-		if err != nil {
-			errToken(tok, fmt.Sprintf("Failed to parse number: %s", tok.Val))
-		}
+	val, err := strconv.ParseFloat(tok.Val, 64)
+	if err != nil {
+		errToken(tok, fmt.Sprintf("Failed to parse number: %s", tok.Val))
 	}
 
-	// named returned values
-	return
+	return ast.NewNumberLiteral(tok.Pos, tok.Line, val, false, tok.Val)
 }
 
 // Returns true if next tokens represent a `helperName`
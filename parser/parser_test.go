@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"runtime"
 	"testing"
 
 	"github.com/aymerick/raymond/ast"
@@ -38,6 +39,9 @@ var parserTests = []parserTest{
 	{"parses mustaches with parameters", `{{foo bar}}`, "{{ PATH:foo [PATH:bar] }}\n"},
 	{"parses mustaches with string parameters", `{{foo bar "baz" }}`, "{{ PATH:foo [PATH:bar, \"baz\"] }}\n"},
 	{"parses mustaches with NUMBER parameters", `{{foo 1}}`, "{{ PATH:foo [NUMBER{1}] }}\n"},
+	{"parses mustaches with STRING parameters containing pipes", `{{foo "a|b" bar="|"}}`, "{{ PATH:foo [\"a|b\"] HASH{bar=\"|\"} }}\n"},
+	{"parses mustaches with large NUMBER parameters", `{{foo 9999999999999 -9007199254740992}}`, "{{ PATH:foo [NUMBER{9999999999999}, NUMBER{-9007199254740992}] }}\n"},
+	{"parses mustaches with NUMBER parameters beyond float64 precision", `{{foo 9007199254740993 -9223372036854775808}}`, "{{ PATH:foo [NUMBER{9007199254740993}, NUMBER{-9223372036854775808}] }}\n"},
 	{"parses mustaches with BOOLEAN parameters (1)", `{{foo true}}`, "{{ PATH:foo [BOOLEAN{true}] }}\n"},
 	{"parses mustaches with BOOLEAN parameters (2)", `{{foo false}}`, "{{ PATH:foo [BOOLEAN{false}] }}\n"},
 	{"parses mustaches with DATA parameters", `{{foo @bar}}`, "{{ PATH:foo [@PATH:bar] }}\n"},
@@ -73,6 +77,8 @@ var parserTests = []parserTest{
 	{"parses multiple inverse sections", `{{#foo}} bar {{else if bar}}{{else}} baz {{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    CONTENT[ ' bar ' ]\n  {{^}}\n    BLOCK:\n      PATH:if [PATH:bar]\n      PROGRAM:\n      {{^}}\n        CONTENT[ ' baz ' ]\n"},
 	{"parses empty blocks", `{{#foo}}{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n"},
 	{"parses empty blocks with empty inverse section", `{{#foo}}{{^}}{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n  {{^}}\n"},
+	{"parses raw blocks", `{{{{raw foo}}}} {{bar}} {{{{/raw}}}}`, "BLOCK:\n  PATH:raw [PATH:foo]\n  PROGRAM:\n    CONTENT[ ' {{bar}} ' ]\n"},
+	{"parses empty raw blocks", `{{{{raw}}}}{{{{/raw}}}}`, "BLOCK:\n  PATH:raw []\n  PROGRAM:\n    CONTENT[ '' ]\n"},
 	{"parses empty blocks with empty inverse (else-style) section", `{{#foo}}{{else}}{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n  {{^}}\n"},
 	{"parses non-empty blocks with empty inverse section", `{{#foo}} bar {{^}}{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    CONTENT[ ' bar ' ]\n  {{^}}\n"},
 	{"parses non-empty blocks with empty inverse (else-style) section", `{{#foo}} bar {{else}}{{/foo}}`, "BLOCK:\n  PATH:foo []\n  PROGRAM:\n    CONTENT[ ' bar ' ]\n  {{^}}\n"},
@@ -131,6 +137,9 @@ var parserErrorTests = []parserTest{
 
 	{"block param must have at least one param", `{{#foo as ||}}content{{/foo}}`, "Expecting ID"},
 	{"open block params must be closed", `{{#foo as |}}content{{/foo}}`, "Expecting ID"},
+	{"block params must start with as (1)", `{{#foo |bar|}}content{{/foo}}`, "Expecting Close, got: 'CloseBlockParams"},
+	{"block params must start with as (2)", `{{#foo as|bar|}}content{{/foo}}`, "Expecting Close, got: 'CloseBlockParams"},
+	{"a pipe outside block params is invalid", `{{foo | bar}}`, "Expecting Close, got: 'CloseBlockParams"},
 
 	{"a path must start with an ID", `{{#/}}content{{/foo}}`, "Expecting ID"},
 	{"a path must end with an ID", `{{foo/bar/}}`, "Expecting ID"},
@@ -155,6 +164,16 @@ var parserErrorTests = []parserTest{
 	{"knows how to report the correct line number in errors (2)", "hello\n\nmy\n\n{{foo}", "Parse error on line 5"},
 
 	{"knows how to report the correct line number in errors when the first character is a newline", "\n\nhello\n\nmy\n\n{{foo}", "Parse error on line 7"},
+
+	{"raises on integers out of range", `{{foo 99999999999999999999}}`, "Integer out of range: 99999999999999999999"},
+
+	{"reports column in runes when line contains multi-byte characters (1)", "é😀{{foo &}}", "Parse error on line 1, column 9:"},
+	{"reports column in runes when line contains multi-byte characters (2)", "héllo\nçà {{#foo}}{{/bar}}", "Parse error on line 2, column 15:"},
+
+	{"raises on duplicate hash keys", "{{foo a=1 b=2\n  a=3}}", "Parse error on line 2, column 3:\nDuplicate hash key: a"},
+	{"raises on duplicate hash keys of a block", "{{#foo a=1 a=2}}{{/foo}}", "Parse error on line 1, column 12:\nDuplicate hash key: a"},
+	{"raises on duplicate hash keys of a partial", "{{> foo bar a=1 b=2 a=3}}", "Parse error on line 1, column 21:\nDuplicate hash key: a"},
+	{"raises on duplicate hash keys of a subexpression", "{{foo (bar a=1 a=2) a=3}}", "Parse error on line 1, column 16:\nDuplicate hash key: a"},
 }
 
 func TestParserErrors(t *testing.T) {
@@ -180,6 +199,179 @@ func TestParserErrors(t *testing.T) {
 	}
 }
 
+func TestParserErrorNoLeak(t *testing.T) {
+	inputs := []string{
+		"{{foo}} {{#bar}}{{/baz}} {{a}} {{b}} {{c}}",
+		"{{foo bar=}} {{a}} {{b}}",
+		"{{#foo}} {{a}} {{b}}",
+		"{{foo}} {{! unclosed comment",
+		"{{> }} {{a}} {{b}}",
+	}
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		for _, input := range inputs {
+			if _, err := Parse(input); err == nil {
+				t.Fatalf("Test failed - Error expected for input: %q", input)
+			}
+		}
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Test failed - %d goroutines leaked by parsing errors", after-before)
+	}
+}
+
+var equalTests = []struct {
+	a     string
+	b     string
+	equal bool
+}{
+	{`{{foo}}`, `{{ foo }}`, true},
+	{`{{foo bar "baz" 1 true}}`, "{{foo  bar\n\"baz\"   1 true }}", true},
+	{`{{foo bar=baz}}`, `{{ foo  bar = baz }}`, true},
+	{`{{foo (bar baz)}}`, `{{foo ( bar  baz )}}`, true},
+	{`{{#foo as |a b|}}{{a}}{{else}}none{{/foo}}`, `{{# foo as | a b | }}{{ a }}{{^}}none{{/ foo }}`, true},
+	{`{{#if a}}x{{else if b}}y{{/if}}`, `{{#if a}}x{{else}}{{#if b}}y{{/if}}{{/if}}`, true},
+	{`{{> foo bar}}`, `{{>  foo   bar }}`, true},
+	{`{{this/foo}}`, `{{this.foo}}`, true},
+	{`{{! comment }}`, `{{!-- comment --}}`, true},
+	{`{{! comment }}`, `{{! other }}`, false},
+	{`{{foo}}`, `{{{foo}}}`, false},
+	{`{{foo}}`, `{{bar}}`, false},
+	{`{{foo}}`, `{{./foo}}`, false},
+	{`{{foo bar}}`, `{{foo "bar"}}`, false},
+	{`{{foo 1}}`, `{{foo 1.0}}`, false},
+	{`{{foo a=1}}`, `{{foo b=1}}`, false},
+	{`{{foo a=1 b=2}}`, `{{foo b=2 a=1}}`, false},
+	{`{{#foo}}x{{/foo}}`, `{{#foo}}x{{else}}{{/foo}}`, false},
+	{`{{#foo as |a|}}{{/foo}}`, `{{#foo as |b|}}{{/foo}}`, false},
+	{`a {{foo}}`, `a  {{foo}}`, false},
+	{`a {{~foo}}`, `a{{foo}}`, true},
+}
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range equalTests {
+		a, err := Parse(test.a)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.a, err)
+			continue
+		}
+
+		b, err := Parse(test.b)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.b, err)
+			continue
+		}
+
+		if ast.Equal(a, b) != test.equal {
+			t.Errorf("Test failed - expected equality of %q and %q to be %t", test.a, test.b, test.equal)
+		}
+
+		if !ast.Equal(a, a) {
+			t.Errorf("Test failed - %q must be equal to itself", test.a)
+		}
+	}
+
+	if !ast.Equal(nil, nil) || ast.Equal(nil, ast.NewProgram(0, 1)) {
+		t.Errorf("Test failed - nil node must only be equal to nil")
+	}
+}
+
+var unescapedTests = []struct {
+	input     string
+	unescaped bool
+}{
+	{`{{foo}}`, false},
+	{`{{{foo}}}`, true},
+	{`{{~{foo}~}}`, true},
+	{`{{&foo}}`, true},
+	{`{{~& foo}}`, true},
+}
+
+func TestParserUnescaped(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range unescapedTests {
+		program, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.input, err)
+			continue
+		}
+
+		node, ok := program.Body[0].(*ast.MustacheStatement)
+		if !ok {
+			t.Errorf("Test failed for input %q - expected a mustache statement, got: %s", test.input, program.Body[0])
+			continue
+		}
+
+		if node.Unescaped != test.unescaped {
+			t.Errorf("Test failed for input %q - expected unescaped flag %t, got %t", test.input, test.unescaped, node.Unescaped)
+		}
+	}
+}
+
+var contentOnlyTests = []string{
+	"",
+	"foo",
+	"foo\nbar\n  baz  \n",
+	"<p>{ foo } {bar}</p>",
+	"foo \\{{bar}}",
+	"foo \\\\{{bar}}",
+	"foo {{! comment }}",
+}
+
+func TestParseContentOnly(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range contentOnlyTests {
+		expected, err := parse(input, false)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", input, err)
+			continue
+		}
+
+		program, err := Parse(input)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", input, err)
+			continue
+		}
+
+		if output := ast.Print(program); output != ast.Print(expected) {
+			t.Errorf("Test failed for content only input %q\nexpected\n\t%q\ngot\n\t%q", input, ast.Print(expected), output)
+		}
+	}
+
+	// escaped mustaches are handled by the lexer
+	program, _ := Parse("foo \\{{bar}}")
+	if output := ast.Print(program); output != "CONTENT[ 'foo ' ]\nCONTENT[ '{{bar}}' ]\n" {
+		t.Errorf("Escaped mustache was not unescaped: %q", output)
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	t.Parallel()
+
+	input := "{{foo a=1 a=2}}{{> bar b=1 b=2}}{{foo (bar c=1 c=2)}}"
+
+	if _, err := Parse(input); err == nil {
+		t.Errorf("Duplicate hash keys must be rejected")
+	}
+
+	program, err := ParseLenient(input)
+	if err != nil {
+		t.Fatalf("Duplicate hash keys must be accepted in lenient mode: %s", err)
+	}
+
+	expected := "{{ PATH:foo [] HASH{a=NUMBER{1}, a=NUMBER{2}} }}\n{{> PARTIAL:bar HASH{b=NUMBER{1}, b=NUMBER{2}} }}\n{{ PATH:foo [PATH:bar [] HASH{c=NUMBER{1}, c=NUMBER{2}}] }}\n"
+	if output := ast.Print(program); output != expected {
+		t.Errorf("Unexpected lenient AST\nexpected\n\t%q\ngot\n\t%q", expected, output)
+	}
+}
+
 // package example
 func Example() {
 	source := "You know {{nothing}} John Snow"
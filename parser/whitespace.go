@@ -2,6 +2,7 @@ package parser
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/aymerick/raymond/ast"
 )
@@ -14,12 +15,11 @@ type whitespaceVisitor struct {
 	isRootSeen bool
 }
 
-var (
-	rTrimLeft         = regexp.MustCompile(`^[ \t]*\r?\n?`)
-	rTrimLeftMultiple = regexp.MustCompile(`^\s+`)
+// whitespaces are the characters matched by `\s` in regular expressions
+const whitespaces = "\t\n\f\r "
 
-	rTrimRight         = regexp.MustCompile(`[ \t]+$`)
-	rTrimRightMultiple = regexp.MustCompile(`\s+$`)
+var (
+	rTrimLeft = regexp.MustCompile(`^[ \t]*\r?\n?`)
 
 	rPrevWhitespace      = regexp.MustCompile(`\r?\n\s*?$`)
 	rPrevWhitespaceStart = regexp.MustCompile(`(^|\r?\n)\s*?$`)
@@ -64,14 +64,14 @@ func omitRight(body []ast.Node, i int, multiple bool) {
 
 	original := node.Value
 
-	r := rTrimLeft
+	// content is sliced, not copied
 	if multiple {
-		r = rTrimLeftMultiple
+		node.Value = strings.TrimLeft(node.Value, whitespaces)
+	} else if loc := rTrimLeft.FindStringIndex(node.Value); loc != nil {
+		node.Value = node.Value[loc[1]:]
 	}
 
-	node.Value = r.ReplaceAllString(node.Value, "")
-
-	node.RightStripped = (original != node.Value)
+	node.RightStripped = (len(original) != len(node.Value))
 }
 
 func omitLeftLast(body []ast.Node, multiple bool) {
@@ -96,14 +96,14 @@ func omitLeft(body []ast.Node, i int, multiple bool) bool {
 
 	original := node.Value
 
-	r := rTrimRight
+	// content is sliced, not copied
 	if multiple {
-		r = rTrimRightMultiple
+		node.Value = strings.TrimRight(node.Value, whitespaces)
+	} else {
+		node.Value = strings.TrimRight(node.Value, " \t")
 	}
 
-	node.Value = r.ReplaceAllString(node.Value, "")
-
-	node.LeftStripped = (original != node.Value)
+	node.LeftStripped = (len(original) != len(node.Value))
 
 	return node.LeftStripped
 }
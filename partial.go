@@ -1,15 +1,29 @@
 package raymond
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // partial represents a partial template
 type partial struct {
 	name   string
 	source string
-	tpl    *Template
+
+	// protects memoized template
+	mutex sync.Mutex
+
+	// registered template, or template memoized from source
+	tpl *Template
+
+	// memoized parsing error
+	err error
+
+	// partial cache generation of memoized template, or 0 if template was registered
+	generation uint64
 }
 
 // partials stores all global partials
@@ -18,6 +32,20 @@ var partials map[string]*partial
 // protects global partials
 var partialsMutex sync.RWMutex
 
+// maxCachedTemplates is the maximum number of parsed templates held by each templates cache
+const maxCachedTemplates = 256
+
+// partialTemplates caches parsed partial templates by source hash
+//
+// Partials memoize their parsed template, so an evicted entry only means that identical sources are parsed again.
+var partialTemplates = newTemplateCache(maxCachedTemplates)
+
+// renderTemplates caches templates parsed by Options.RenderString() by source hash
+var renderTemplates = newTemplateCache(maxCachedTemplates)
+
+// partialCacheGeneration is incremented by ClearPartialCache(), to invalidate templates memoized by partials
+var partialCacheGeneration uint64 = 1
+
 func init() {
 	partials = make(map[string]*partial)
 }
@@ -78,6 +106,27 @@ func RemoveAllPartials() {
 	partials = make(map[string]*partial)
 }
 
+// PartialNames returns sorted names of all global partials
+func PartialNames() []string {
+	partialsMutex.RLock()
+	defer partialsMutex.RUnlock()
+
+	return partialNames(partials)
+}
+
+// partialNames returns sorted names of given partials
+func partialNames(partials map[string]*partial) []string {
+	result := make([]string, 0, len(partials))
+
+	for name := range partials {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
 // findPartial finds a registered global partial
 func findPartial(name string) *partial {
 	partialsMutex.RLock()
@@ -87,15 +136,97 @@ func findPartial(name string) *partial {
 }
 
 // template returns parsed partial template
+//
+// The template parsed from source is memoized until the partial cache is cleared.
 func (p *partial) template() (*Template, error) {
-	if p.tpl == nil {
-		var err error
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if (p.tpl != nil) && (p.generation == 0) {
+		return p.tpl, nil
+	}
+
+	generation := atomic.LoadUint64(&partialCacheGeneration)
+	if p.generation != generation {
+		p.tpl, p.err = partialTemplates.parse(p.source)
+		p.generation = generation
+	}
+
+	return p.tpl, p.err
+}
+
+// ClearPartialCache removes all parsed partial templates, and sources parsed by Options.RenderString(), from cache, so that partials are parsed again on their next evaluation.
+//
+// This is useful to free memory, or when partial files are reloaded during development.
+func ClearPartialCache() {
+	partialTemplates.clear()
+	renderTemplates.clear()
+
+	atomic.AddUint64(&partialCacheGeneration, 1)
+}
+
+// templateCacheEntry is a parsing result cached by a templateCache
+type templateCacheEntry struct {
+	tpl *Template
+	err error
+}
+
+// templateCache caches parsing results by source hash, so that identical sources are parsed only once
+type templateCache struct {
+	// maximum number of entries
+	max int
+
+	mutex   sync.RWMutex
+	entries map[[sha256.Size]byte]templateCacheEntry
+}
+
+// newTemplateCache instanciates a new templateCache, holding at most max entries
+func newTemplateCache(max int) *templateCache {
+	return &templateCache{
+		max:     max,
+		entries: make(map[[sha256.Size]byte]templateCacheEntry),
+	}
+}
 
-		p.tpl, err = Parse(p.source)
-		if err != nil {
-			return nil, err
+// parse returns the parsed template of given source, or the parsing error
+func (cache *templateCache) parse(source string) (*Template, error) {
+	key := sha256.Sum256([]byte(source))
+
+	cache.mutex.RLock()
+	entry, ok := cache.entries[key]
+	cache.mutex.RUnlock()
+
+	if ok {
+		return entry.tpl, entry.err
+	}
+
+	entry.tpl, entry.err = Parse(source)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// that source may have been parsed concurrently
+	if cached, ok := cache.entries[key]; ok {
+		return cached.tpl, cached.err
+	}
+
+	if len(cache.entries) >= cache.max {
+		// evict an arbitrary entry
+		for k := range cache.entries {
+			delete(cache.entries, k)
+			break
 		}
 	}
 
-	return p.tpl, nil
+	cache.entries[key] = entry
+
+	return entry.tpl, entry.err
+}
+
+// clear removes all entries from cache
+func (cache *templateCache) clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.entries = make(map[[sha256.Size]byte]templateCacheEntry)
 }
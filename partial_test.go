@@ -0,0 +1,294 @@
+package raymond
+
+import "testing"
+
+func TestPartialCache(t *testing.T) {
+	t.Parallel()
+
+	source := `<header>{{title}}</header>`
+
+	tpl1 := MustParse(`{{> header}}`)
+	tpl1.RegisterPartial("header", source)
+
+	tpl2 := MustParse(`{{> header}}!`)
+	tpl2.RegisterPartial("header", source)
+
+	if result := tpl1.MustExec(map[string]string{"title": "foo"}); result != "<header>foo</header>" {
+		t.Errorf("Failed to render partial: %q", result)
+	}
+
+	if result := tpl2.MustExec(map[string]string{"title": "bar"}); result != "<header>bar</header>!" {
+		t.Errorf("Failed to render partial: %q", result)
+	}
+
+	p1, _ := tpl1.findPartial("header").template()
+	p2, _ := tpl2.findPartial("header").template()
+
+	if (p1 == nil) || (p1 != p2) {
+		t.Errorf("Partials with the same source must share the same parsed template")
+	}
+
+	ClearPartialCache()
+
+	p3, _ := tpl1.findPartial("header").template()
+	if (p3 == nil) || (p3 == p1) {
+		t.Errorf("Partial must be parsed again after cache is cleared")
+	}
+
+	if p4, _ := tpl1.findPartial("header").template(); p4 != p3 {
+		t.Errorf("Parsed partial template must be memoized")
+	}
+}
+
+func TestPartialCacheError(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{> broken}}`)
+	tpl.RegisterPartial("broken", `{{#if}}`)
+
+	p := tpl.findPartial("broken")
+
+	_, err1 := p.template()
+	_, err2 := p.template()
+
+	if (err1 == nil) || (err1 != err2) {
+		t.Errorf("Partial parsing error must be memoized: %v, %v", err1, err2)
+	}
+
+	if _, err := tpl.Exec(nil); err == nil {
+		t.Errorf("Broken partial must fail evaluation")
+	}
+}
+
+func TestPartialCacheRegisteredTemplate(t *testing.T) {
+	t.Parallel()
+
+	partialTpl := MustParse(`<footer/>`)
+
+	tpl := MustParse(`{{> footer}}`)
+	tpl.RegisterPartialTemplate("footer", partialTpl)
+
+	ClearPartialCache()
+
+	if p, _ := tpl.findPartial("footer").template(); p != partialTpl {
+		t.Errorf("Registered partial template must not be affected by partial cache")
+	}
+}
+
+func TestTemplateCacheBounded(t *testing.T) {
+	t.Parallel()
+
+	cache := newTemplateCache(2)
+
+	first, _ := cache.parse("a")
+	cache.parse("b")
+	cache.parse("c")
+
+	if len(cache.entries) != 2 {
+		t.Errorf("Template cache must hold at most 2 entries, got %d", len(cache.entries))
+	}
+
+	if tpl, _ := cache.parse("a"); (tpl == nil) || (tpl.source != first.source) {
+		t.Errorf("Evicted source must be parsed again")
+	}
+}
+
+func TestPartialTemplatesBounded(t *testing.T) {
+	t.Parallel()
+
+	if (partialTemplates.max <= 0) || (renderTemplates.max <= 0) {
+		t.Errorf("Partial and render templates caches must be bounded")
+	}
+}
+
+func TestPartialNames(t *testing.T) {
+	RegisterPartial("testpartialnames", "")
+	defer RemovePartial("testpartialnames")
+
+	found := false
+	for _, name := range PartialNames() {
+		if name == "testpartialnames" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Global partial not listed in partial names: %q", PartialNames())
+	}
+}
+
+var isolatedPartialTests = []Test{
+	{
+		"inherited partial context",
+		`{{#each people}}{{> person}} {{/each}}`,
+		map[string]interface{}{"title": "Mr", "people": []map[string]string{{"name": "foo"}, {"name": "bar"}}},
+		nil, nil,
+		map[string]string{"person": "{{title}} {{name}}"},
+		"Mr foo Mr bar ",
+	},
+	{
+		"isolated partial context",
+		`{{#each people}}{{> person isolate=true}} {{/each}}`,
+		map[string]interface{}{"title": "Mr", "people": []map[string]string{{"name": "foo"}, {"name": "bar"}}},
+		nil, nil,
+		map[string]string{"person": "{{title}} {{name}}"},
+		" foo  bar ",
+	},
+	{
+		"isolated partial with context parameter",
+		`{{> person author isolate=true}}`,
+		map[string]interface{}{"title": "Mr", "author": map[string]string{"name": "foo"}},
+		nil, nil,
+		map[string]string{"person": "{{title}} {{name}} {{../title}} {{@root.name}}"},
+		" foo  foo",
+	},
+	{
+		"isolated partial with hash parameters",
+		`{{> person name=author.name isolate=true}}`,
+		map[string]interface{}{"title": "Mr", "author": map[string]string{"name": "foo"}},
+		nil, nil,
+		map[string]string{"person": "{{title}} {{name}}"},
+		" foo",
+	},
+	{
+		"isolated partial without parameters",
+		`{{#with author}}{{> person isolate=true}}{{/with}}`,
+		map[string]interface{}{"title": "Mr", "author": map[string]string{"name": "foo"}},
+		nil, nil,
+		map[string]string{"person": "[{{title}} {{name}}]"},
+		"[ foo]",
+	},
+	{
+		"isolated partial hides block parameters",
+		`{{#each people as |person|}}{{> person person isolate=true}}{{/each}}`,
+		map[string]interface{}{"people": []map[string]string{{"name": "foo"}}},
+		nil, nil,
+		map[string]string{"person": "{{name}}{{person.name}}"},
+		"foo",
+	},
+	{
+		"isolated partial keeps private data",
+		`{{#each people}}{{> person this isolate=true}}{{/each}}`,
+		map[string]interface{}{"people": []map[string]string{{"name": "foo"}, {"name": "bar"}}},
+		nil, nil,
+		map[string]string{"person": "{{@index}}{{name}}"},
+		"0foo1bar",
+	},
+	{
+		"partial isolation disabled",
+		`{{> person isolate=false}}`,
+		map[string]interface{}{"name": "foo"},
+		nil, nil,
+		map[string]string{"person": "{{name}}"},
+		"foo",
+	},
+}
+
+func TestIsolatedPartial(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, isolatedPartialTests)
+}
+
+type partialContextUser struct {
+	Name    string
+	Address partialContextAddress
+}
+
+type partialContextAddress struct {
+	City string
+}
+
+var partialContextTests = []Test{
+	{
+		"partial with dotted path context",
+		`{{> address user.address}}`,
+		map[string]interface{}{"user": map[string]interface{}{"address": map[string]string{"city": "Paris"}}},
+		nil, nil,
+		map[string]string{"address": "<p>{{city}}</p>"},
+		"<p>Paris</p>",
+	},
+	{
+		"partial with dotted path context over structs",
+		`{{#each users}}{{> address Address}}{{> address ../user.Address}}{{/each}}`,
+		map[string]interface{}{
+			"user":  partialContextUser{"foo", partialContextAddress{"Paris"}},
+			"users": []partialContextUser{{"bar", partialContextAddress{"Lyon"}}},
+		},
+		nil, nil,
+		map[string]string{"address": "<p>{{City}}</p>"},
+		"<p>Lyon</p><p>Paris</p>",
+	},
+	{
+		"partial with block parameter path context",
+		`{{#each users as |user|}}{{> address user.address}}{{/each}}`,
+		map[string]interface{}{"users": []map[string]interface{}{{"address": map[string]string{"city": "Paris"}}}},
+		nil, nil,
+		map[string]string{"address": "<p>{{city}}</p>"},
+		"<p>Paris</p>",
+	},
+	{
+		"partial with sub-expression context",
+		`{{> address (addressOf user)}}`,
+		map[string]interface{}{"user": partialContextUser{"foo", partialContextAddress{"Paris"}}},
+		nil,
+		map[string]interface{}{"addressOf": func(user partialContextUser) partialContextAddress { return user.Address }},
+		map[string]string{"address": "<p>{{City}}</p>"},
+		"<p>Paris</p>",
+	},
+	{
+		"partial with missing dotted path context",
+		`{{> address user.address}}`,
+		map[string]interface{}{"city": "Paris", "user": map[string]interface{}{}},
+		nil, nil,
+		map[string]string{"address": "<p>{{city}}</p>"},
+		"<p>Paris</p>",
+	},
+}
+
+func TestPartialContext(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, partialContextTests)
+}
+
+var partialEachTests = []Test{
+	{
+		"partial with this context and hash sub-expression in each",
+		`{{#each cartItems}}{{> itemRow this price=(money price currency)}}{{/each}}[{{@index}}{{price}}{{name}}]`,
+		map[string]interface{}{
+			"cartItems": []map[string]interface{}{
+				{"name": "foo", "price": 10, "currency": "EUR"},
+				{"name": "bar", "price": 2.5, "currency": "USD"},
+			},
+		},
+		nil,
+		map[string]interface{}{"money": func(price interface{}, currency string) string {
+			return Str(price) + " " + currency
+		}},
+		map[string]string{"itemRow": "<tr>{{@index}}:{{name}}:{{price}}:{{this.name}}{{#if @first}} first{{/if}}{{#if @last}} last{{/if}}</tr>"},
+		"<tr>0:foo:10 EUR:foo first</tr><tr>1:bar:2.5 USD:bar last</tr>[]",
+	},
+	{
+		"partial with struct context and hash parameters in each",
+		`{{#each users as |user i|}}{{> user user index=i}}{{/each}}`,
+		map[string]interface{}{"users": []partialContextUser{{"foo", partialContextAddress{"Paris"}}, {"bar", partialContextAddress{"Lyon"}}}},
+		nil, nil,
+		map[string]string{"user": "{{index}}:{{Name}}:{{Address.City}} "},
+		"0:foo:Paris 1:bar:Lyon ",
+	},
+	{
+		"isolated partial with context and hash parameters",
+		`{{#each items}}{{> item this label=../label isolate=true}}{{/each}}`,
+		map[string]interface{}{"label": "item", "title": "hidden", "items": []map[string]string{{"name": "foo"}}},
+		nil, nil,
+		map[string]string{"item": "{{label}} {{name}} {{title}} {{@index}}"},
+		"item foo  0",
+	},
+}
+
+func TestPartialEach(t *testing.T) {
+	t.Parallel()
+
+	launchTests(t, partialEachTests)
+}
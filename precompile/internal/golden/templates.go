@@ -0,0 +1,1004 @@
+// Code generated by raymond/precompile. DO NOT EDIT.
+
+package golden
+
+import (
+	"github.com/aymerick/raymond"
+	"github.com/aymerick/raymond/ast"
+)
+
+// Templates is the set of precompiled templates.
+var Templates = raymond.NewTemplateSet()
+
+func init() {
+	Templates.Add("item", raymond.NewParsedTemplate("<span>{{name}}{{@root.title}}</span>\n", &ast.Program{
+		Loc: ast.Loc{
+			Line: 1,
+		},
+		Body: []ast.Node{
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Line: 1,
+				},
+				Value:    "<span>",
+				Original: "<span>",
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  6,
+					Line: 1,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  6,
+						Line: 1,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  8,
+							Line: 1,
+						},
+						Original: "name",
+						Parts: []string{
+							"name",
+						},
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  14,
+					Line: 1,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  14,
+						Line: 1,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  17,
+							Line: 1,
+						},
+						Original: "@root.title",
+						Parts: []string{
+							"root",
+							"title",
+						},
+						Data: true,
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  29,
+					Line: 1,
+				},
+				Value:    "</span>\n",
+				Original: "</span>\n",
+			},
+		},
+	}))
+	Templates.Add("layouts/header", raymond.NewParsedTemplate("<h1>{{title}}</h1>\n", &ast.Program{
+		Loc: ast.Loc{
+			Line: 1,
+		},
+		Body: []ast.Node{
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Line: 1,
+				},
+				Value:    "<h1>",
+				Original: "<h1>",
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  4,
+					Line: 1,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  4,
+						Line: 1,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  6,
+							Line: 1,
+						},
+						Original: "title",
+						Parts: []string{
+							"title",
+						},
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  13,
+					Line: 1,
+				},
+				Value:    "</h1>\n",
+				Original: "</h1>\n",
+			},
+		},
+	}))
+	Templates.Add("pages/home", raymond.NewParsedTemplate("{{> layouts/header}}\n<ul>\n  {{#each posts as |post i|}}\n  <li class=\"{{#if @first}}first{{else if @last}}last{{/if}}\">{{i}}: {{post.title}} {{~#if post.draft}} (draft){{/if}}</li>\n  {{else}}\n  <li>none</li>\n  {{/each}}\n</ul>\n{{! comment }}\n{{#with author}}{{firstName}} {{{lastName}}}{{/with}} {{round 1.5}} {{add 9007199254740992 -1.5}} {{lookup . \"title\"}}\n{{{{raw}}}} {{raw}} {{{{/raw}}}}\n  {{> item name=(lookup author \"firstName\") isolate=true}}\n", &ast.Program{
+		Loc: ast.Loc{
+			Line: 1,
+		},
+		Body: []ast.Node{
+			&ast.PartialStatement{
+				NodeType: ast.NodePartial,
+				Loc: ast.Loc{
+					Line: 1,
+				},
+				Name: &ast.PathExpression{
+					NodeType: ast.NodePath,
+					Loc: ast.Loc{
+						Pos:  4,
+						Line: 1,
+					},
+					Original: "layouts/header",
+					Parts: []string{
+						"layouts",
+						"header",
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  20,
+					Line: 1,
+				},
+				Value:         "<ul>\n",
+				Original:      "\n<ul>\n  ",
+				RightStripped: true,
+				LeftStripped:  true,
+			},
+			&ast.BlockStatement{
+				NodeType: ast.NodeBlock,
+				Loc: ast.Loc{
+					Pos:  28,
+					Line: 3,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  28,
+						Line: 3,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  31,
+							Line: 3,
+						},
+						Original: "each",
+						Parts: []string{
+							"each",
+						},
+					},
+					Params: []ast.Node{
+						&ast.PathExpression{
+							NodeType: ast.NodePath,
+							Loc: ast.Loc{
+								Pos:  36,
+								Line: 3,
+							},
+							Original: "posts",
+							Parts: []string{
+								"posts",
+							},
+						},
+					},
+				},
+				Program: &ast.Program{
+					Loc: ast.Loc{
+						Pos:  55,
+						Line: 3,
+					},
+					Body: []ast.Node{
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  55,
+								Line: 3,
+							},
+							Value:         "  <li class=\"",
+							Original:      "\n  <li class=\"",
+							RightStripped: true,
+						},
+						&ast.BlockStatement{
+							NodeType: ast.NodeBlock,
+							Loc: ast.Loc{
+								Pos:  69,
+								Line: 4,
+							},
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  69,
+									Line: 4,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  72,
+										Line: 4,
+									},
+									Original: "if",
+									Parts: []string{
+										"if",
+									},
+								},
+								Params: []ast.Node{
+									&ast.PathExpression{
+										NodeType: ast.NodePath,
+										Loc: ast.Loc{
+											Pos:  76,
+											Line: 4,
+										},
+										Original: "@first",
+										Parts: []string{
+											"first",
+										},
+										Data: true,
+									},
+								},
+							},
+							Program: &ast.Program{
+								Loc: ast.Loc{
+									Pos:  83,
+									Line: 4,
+								},
+								Body: []ast.Node{
+									&ast.ContentStatement{
+										NodeType: ast.NodeContent,
+										Loc: ast.Loc{
+											Pos:  83,
+											Line: 4,
+										},
+										Value:    "first",
+										Original: "first",
+									},
+								},
+							},
+							Inverse: &ast.Program{
+								Loc: ast.Loc{
+									Pos:  88,
+									Line: 4,
+								},
+								Body: []ast.Node{
+									&ast.BlockStatement{
+										NodeType: ast.NodeBlock,
+										Loc: ast.Loc{
+											Pos:  88,
+											Line: 4,
+										},
+										Expression: &ast.Expression{
+											NodeType: ast.NodeExpression,
+											Loc: ast.Loc{
+												Pos:  88,
+												Line: 4,
+											},
+											Path: &ast.PathExpression{
+												NodeType: ast.NodePath,
+												Loc: ast.Loc{
+													Pos:  95,
+													Line: 4,
+												},
+												Original: "if",
+												Parts: []string{
+													"if",
+												},
+											},
+											Params: []ast.Node{
+												&ast.PathExpression{
+													NodeType: ast.NodePath,
+													Loc: ast.Loc{
+														Pos:  99,
+														Line: 4,
+													},
+													Original: "@last",
+													Parts: []string{
+														"last",
+													},
+													Data: true,
+												},
+											},
+										},
+										Program: &ast.Program{
+											Loc: ast.Loc{
+												Pos:  105,
+												Line: 4,
+											},
+											Body: []ast.Node{
+												&ast.ContentStatement{
+													NodeType: ast.NodeContent,
+													Loc: ast.Loc{
+														Pos:  105,
+														Line: 4,
+													},
+													Value:    "last",
+													Original: "last",
+												},
+											},
+										},
+										OpenStrip:  &ast.Strip{},
+										CloseStrip: &ast.Strip{},
+									},
+								},
+								Chained: true,
+								Strip:   &ast.Strip{},
+							},
+							OpenStrip:    &ast.Strip{},
+							InverseStrip: &ast.Strip{},
+							CloseStrip:   &ast.Strip{},
+						},
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  116,
+								Line: 4,
+							},
+							Value:    "\">",
+							Original: "\">",
+						},
+						&ast.MustacheStatement{
+							NodeType: ast.NodeMustache,
+							Loc: ast.Loc{
+								Pos:  118,
+								Line: 4,
+							},
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  118,
+									Line: 4,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  120,
+										Line: 4,
+									},
+									Original: "i",
+									Parts: []string{
+										"i",
+									},
+								},
+							},
+							Strip: &ast.Strip{},
+						},
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  123,
+								Line: 4,
+							},
+							Value:    ": ",
+							Original: ": ",
+						},
+						&ast.MustacheStatement{
+							NodeType: ast.NodeMustache,
+							Loc: ast.Loc{
+								Pos:  125,
+								Line: 4,
+							},
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  125,
+									Line: 4,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  127,
+										Line: 4,
+									},
+									Original: "post.title",
+									Parts: []string{
+										"post",
+										"title",
+									},
+								},
+							},
+							Strip: &ast.Strip{},
+						},
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  139,
+								Line: 4,
+							},
+							Original:     " ",
+							LeftStripped: true,
+						},
+						&ast.BlockStatement{
+							NodeType: ast.NodeBlock,
+							Loc: ast.Loc{
+								Pos:  140,
+								Line: 4,
+							},
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  140,
+									Line: 4,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  144,
+										Line: 4,
+									},
+									Original: "if",
+									Parts: []string{
+										"if",
+									},
+								},
+								Params: []ast.Node{
+									&ast.PathExpression{
+										NodeType: ast.NodePath,
+										Loc: ast.Loc{
+											Pos:  147,
+											Line: 4,
+										},
+										Original: "post.draft",
+										Parts: []string{
+											"post",
+											"draft",
+										},
+									},
+								},
+							},
+							Program: &ast.Program{
+								Loc: ast.Loc{
+									Pos:  159,
+									Line: 4,
+								},
+								Body: []ast.Node{
+									&ast.ContentStatement{
+										NodeType: ast.NodeContent,
+										Loc: ast.Loc{
+											Pos:  159,
+											Line: 4,
+										},
+										Value:    " (draft)",
+										Original: " (draft)",
+									},
+								},
+							},
+							OpenStrip: &ast.Strip{
+								Open: true,
+							},
+							CloseStrip: &ast.Strip{},
+						},
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  174,
+								Line: 4,
+							},
+							Value:        "</li>\n",
+							Original:     "</li>\n  ",
+							LeftStripped: true,
+						},
+					},
+					BlockParams: []string{
+						"post",
+						"i",
+					},
+				},
+				Inverse: &ast.Program{
+					Loc: ast.Loc{
+						Pos:  190,
+						Line: 5,
+					},
+					Body: []ast.Node{
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  190,
+								Line: 5,
+							},
+							Value:         "  <li>none</li>\n",
+							Original:      "\n  <li>none</li>\n  ",
+							RightStripped: true,
+							LeftStripped:  true,
+						},
+					},
+					Strip: &ast.Strip{},
+				},
+				OpenStrip:    &ast.Strip{},
+				InverseStrip: &ast.Strip{},
+				CloseStrip:   &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  218,
+					Line: 7,
+				},
+				Value:         "</ul>\n",
+				Original:      "\n</ul>\n",
+				RightStripped: true,
+			},
+			&ast.CommentStatement{
+				NodeType: ast.NodeComment,
+				Loc: ast.Loc{
+					Pos:  225,
+					Line: 9,
+				},
+				Value: " comment ",
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  239,
+					Line: 9,
+				},
+				Original:      "\n",
+				RightStripped: true,
+			},
+			&ast.BlockStatement{
+				NodeType: ast.NodeBlock,
+				Loc: ast.Loc{
+					Pos:  240,
+					Line: 10,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  240,
+						Line: 10,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  243,
+							Line: 10,
+						},
+						Original: "with",
+						Parts: []string{
+							"with",
+						},
+					},
+					Params: []ast.Node{
+						&ast.PathExpression{
+							NodeType: ast.NodePath,
+							Loc: ast.Loc{
+								Pos:  248,
+								Line: 10,
+							},
+							Original: "author",
+							Parts: []string{
+								"author",
+							},
+						},
+					},
+				},
+				Program: &ast.Program{
+					Loc: ast.Loc{
+						Pos:  256,
+						Line: 10,
+					},
+					Body: []ast.Node{
+						&ast.MustacheStatement{
+							NodeType: ast.NodeMustache,
+							Loc: ast.Loc{
+								Pos:  256,
+								Line: 10,
+							},
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  256,
+									Line: 10,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  258,
+										Line: 10,
+									},
+									Original: "firstName",
+									Parts: []string{
+										"firstName",
+									},
+								},
+							},
+							Strip: &ast.Strip{},
+						},
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  269,
+								Line: 10,
+							},
+							Value:    " ",
+							Original: " ",
+						},
+						&ast.MustacheStatement{
+							NodeType: ast.NodeMustache,
+							Loc: ast.Loc{
+								Pos:  270,
+								Line: 10,
+							},
+							Unescaped: true,
+							Expression: &ast.Expression{
+								NodeType: ast.NodeExpression,
+								Loc: ast.Loc{
+									Pos:  270,
+									Line: 10,
+								},
+								Path: &ast.PathExpression{
+									NodeType: ast.NodePath,
+									Loc: ast.Loc{
+										Pos:  273,
+										Line: 10,
+									},
+									Original: "lastName",
+									Parts: []string{
+										"lastName",
+									},
+								},
+							},
+							Strip: &ast.Strip{},
+						},
+					},
+				},
+				OpenStrip:  &ast.Strip{},
+				CloseStrip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  293,
+					Line: 10,
+				},
+				Value:    " ",
+				Original: " ",
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  294,
+					Line: 10,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  294,
+						Line: 10,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  296,
+							Line: 10,
+						},
+						Original: "round",
+						Parts: []string{
+							"round",
+						},
+					},
+					Params: []ast.Node{
+						&ast.NumberLiteral{
+							NodeType: ast.NodeNumber,
+							Loc: ast.Loc{
+								Pos:  302,
+								Line: 10,
+							},
+							Value:    1.5,
+							Original: "1.5",
+						},
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  307,
+					Line: 10,
+				},
+				Value:    " ",
+				Original: " ",
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  308,
+					Line: 10,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  308,
+						Line: 10,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  310,
+							Line: 10,
+						},
+						Original: "add",
+						Parts: []string{
+							"add",
+						},
+					},
+					Params: []ast.Node{
+						&ast.NumberLiteral{
+							NodeType: ast.NodeNumber,
+							Loc: ast.Loc{
+								Pos:  314,
+								Line: 10,
+							},
+							Value:    9.007199254740992e+15,
+							IsInt:    true,
+							Int:      9007199254740992,
+							Original: "9007199254740992",
+						},
+						&ast.NumberLiteral{
+							NodeType: ast.NodeNumber,
+							Loc: ast.Loc{
+								Pos:  331,
+								Line: 10,
+							},
+							Value:    -1.5,
+							Original: "-1.5",
+						},
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  337,
+					Line: 10,
+				},
+				Value:    " ",
+				Original: " ",
+			},
+			&ast.MustacheStatement{
+				NodeType: ast.NodeMustache,
+				Loc: ast.Loc{
+					Pos:  338,
+					Line: 10,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  338,
+						Line: 10,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  340,
+							Line: 10,
+						},
+						Original: "lookup",
+						Parts: []string{
+							"lookup",
+						},
+					},
+					Params: []ast.Node{
+						&ast.PathExpression{
+							NodeType: ast.NodePath,
+							Loc: ast.Loc{
+								Pos:  347,
+								Line: 10,
+							},
+							Original: ".",
+							Scoped:   true,
+						},
+						&ast.StringLiteral{
+							NodeType: ast.NodeString,
+							Loc: ast.Loc{
+								Pos:  350,
+								Line: 10,
+							},
+							Value: "title",
+						},
+					},
+				},
+				Strip: &ast.Strip{},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  358,
+					Line: 10,
+				},
+				Value:    "\n",
+				Original: "\n",
+			},
+			&ast.BlockStatement{
+				NodeType: ast.NodeBlock,
+				Loc: ast.Loc{
+					Pos:  359,
+					Line: 11,
+				},
+				Expression: &ast.Expression{
+					NodeType: ast.NodeExpression,
+					Loc: ast.Loc{
+						Pos:  359,
+						Line: 11,
+					},
+					Path: &ast.PathExpression{
+						NodeType: ast.NodePath,
+						Loc: ast.Loc{
+							Pos:  363,
+							Line: 11,
+						},
+						Original: "raw",
+						Parts: []string{
+							"raw",
+						},
+					},
+				},
+				Program: &ast.Program{
+					Loc: ast.Loc{
+						Pos:  366,
+						Line: 11,
+					},
+					Body: []ast.Node{
+						&ast.ContentStatement{
+							NodeType: ast.NodeContent,
+							Loc: ast.Loc{
+								Pos:  370,
+								Line: 11,
+							},
+							Value:    " {{raw}} ",
+							Original: " {{raw}} ",
+						},
+					},
+				},
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  391,
+					Line: 11,
+				},
+				Value:        "\n",
+				Original:     "\n  ",
+				LeftStripped: true,
+			},
+			&ast.PartialStatement{
+				NodeType: ast.NodePartial,
+				Loc: ast.Loc{
+					Pos:  394,
+					Line: 12,
+				},
+				Name: &ast.PathExpression{
+					NodeType: ast.NodePath,
+					Loc: ast.Loc{
+						Pos:  398,
+						Line: 12,
+					},
+					Original: "item",
+					Parts: []string{
+						"item",
+					},
+				},
+				Hash: &ast.Hash{
+					NodeType: ast.NodeHash,
+					Loc: ast.Loc{
+						Pos:  403,
+						Line: 12,
+					},
+					Pairs: []*ast.HashPair{
+						&ast.HashPair{
+							NodeType: ast.NodeHashPair,
+							Loc: ast.Loc{
+								Pos:  403,
+								Line: 12,
+							},
+							Key: "name",
+							Val: &ast.SubExpression{
+								NodeType: ast.NodeSubExpression,
+								Loc: ast.Loc{
+									Pos:  408,
+									Line: 12,
+								},
+								Expression: &ast.Expression{
+									NodeType: ast.NodeExpression,
+									Loc: ast.Loc{
+										Pos:  408,
+										Line: 12,
+									},
+									Path: &ast.PathExpression{
+										NodeType: ast.NodePath,
+										Loc: ast.Loc{
+											Pos:  409,
+											Line: 12,
+										},
+										Original: "lookup",
+										Parts: []string{
+											"lookup",
+										},
+									},
+									Params: []ast.Node{
+										&ast.PathExpression{
+											NodeType: ast.NodePath,
+											Loc: ast.Loc{
+												Pos:  416,
+												Line: 12,
+											},
+											Original: "author",
+											Parts: []string{
+												"author",
+											},
+										},
+										&ast.StringLiteral{
+											NodeType: ast.NodeString,
+											Loc: ast.Loc{
+												Pos:  424,
+												Line: 12,
+											},
+											Value: "firstName",
+										},
+									},
+								},
+							},
+						},
+						&ast.HashPair{
+							NodeType: ast.NodeHashPair,
+							Loc: ast.Loc{
+								Pos:  436,
+								Line: 12,
+							},
+							Key: "isolate",
+							Val: &ast.BooleanLiteral{
+								NodeType: ast.NodeBoolean,
+								Loc: ast.Loc{
+									Pos:  444,
+									Line: 12,
+								},
+								Value:    true,
+								Original: "true",
+							},
+						},
+					},
+				},
+				Strip:  &ast.Strip{},
+				Indent: "  ",
+			},
+			&ast.ContentStatement{
+				NodeType: ast.NodeContent,
+				Loc: ast.Loc{
+					Pos:  450,
+					Line: 12,
+				},
+				Original:      "\n",
+				RightStripped: true,
+			},
+		},
+	}))
+}
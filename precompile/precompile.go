@@ -0,0 +1,167 @@
+// Package precompile generates Go source code that constructs parsed handlebars templates, so that templates are not parsed at runtime.
+package precompile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/parser"
+)
+
+// nodeTypes stores the names of node type constants
+var nodeTypes = map[ast.NodeType]string{
+	ast.NodeProgram:       "NodeProgram",
+	ast.NodeMustache:      "NodeMustache",
+	ast.NodeBlock:         "NodeBlock",
+	ast.NodePartial:       "NodePartial",
+	ast.NodeContent:       "NodeContent",
+	ast.NodeComment:       "NodeComment",
+	ast.NodeExpression:    "NodeExpression",
+	ast.NodeSubExpression: "NodeSubExpression",
+	ast.NodePath:          "NodePath",
+	ast.NodeBoolean:       "NodeBoolean",
+	ast.NodeNumber:        "NodeNumber",
+	ast.NodeString:        "NodeString",
+	ast.NodeHash:          "NodeHash",
+	ast.NodeHashPair:      "NodeHashPair",
+}
+
+var nodeTypeType = reflect.TypeOf(ast.NodeType(0))
+
+// GenerateGo returns the source of a Go file of given package, that adds given templates to the `Templates` template set
+// at init time.
+//
+// Templates are given by name. They are parsed when generating the Go file, and the generated code constructs their
+// AST without calling the parser, so that rendering is the same than with templates parsed at runtime. An error is
+// returned if a template fails to be parsed.
+//
+// Generated file is meant to be committed, for example with a `go:generate` directive.
+func GenerateGo(pkg string, templates map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+
+	buf.WriteString("// Code generated by raymond/precompile. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if len(names) > 0 {
+		buf.WriteString("import (\n\t\"github.com/aymerick/raymond\"\n\t\"github.com/aymerick/raymond/ast\"\n)\n\n")
+	} else {
+		buf.WriteString("import \"github.com/aymerick/raymond\"\n\n")
+	}
+	buf.WriteString("// Templates is the set of precompiled templates.\n")
+	buf.WriteString("var Templates = raymond.NewTemplateSet()\n\n")
+	buf.WriteString("func init() {\n")
+
+	for _, name := range names {
+		program, err := parser.Parse(templates[name])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse template %s: %s", name, err)
+		}
+
+		fmt.Fprintf(&buf, "Templates.Add(%s, raymond.NewParsedTemplate(%s, ", strconv.Quote(name), strconv.Quote(templates[name]))
+		writeValue(&buf, reflect.ValueOf(program))
+		buf.WriteString("))\n")
+	}
+
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// writeValue writes the Go expression of given AST value
+func writeValue(buf *bytes.Buffer, val reflect.Value) {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			buf.WriteString("nil")
+		} else {
+			if val.Kind() == reflect.Ptr {
+				buf.WriteString("&")
+			}
+			writeValue(buf, val.Elem())
+		}
+	case reflect.Struct:
+		buf.WriteString(typeName(val.Type()))
+		buf.WriteString("{\n")
+
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Field(i)
+			if isZero(field) {
+				continue
+			}
+
+			buf.WriteString(val.Type().Field(i).Name)
+			buf.WriteString(": ")
+			writeValue(buf, field)
+			buf.WriteString(",\n")
+		}
+
+		buf.WriteString("}")
+	case reflect.Slice:
+		if val.IsNil() {
+			buf.WriteString("nil")
+			return
+		}
+
+		buf.WriteString(typeName(val.Type()))
+		buf.WriteString("{")
+
+		for i := 0; i < val.Len(); i++ {
+			buf.WriteString("\n")
+			writeValue(buf, val.Index(i))
+			buf.WriteString(",")
+		}
+
+		if val.Len() > 0 {
+			buf.WriteString("\n")
+		}
+
+		buf.WriteString("}")
+	case reflect.String:
+		buf.WriteString(strconv.Quote(val.String()))
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(val.Bool()))
+	case reflect.Int:
+		if val.Type() == nodeTypeType {
+			buf.WriteString("ast." + nodeTypes[ast.NodeType(val.Int())])
+		} else {
+			buf.WriteString(strconv.FormatInt(val.Int(), 10))
+		}
+	case reflect.Int64:
+		buf.WriteString(strconv.FormatInt(val.Int(), 10))
+	case reflect.Float64:
+		buf.WriteString(strconv.FormatFloat(val.Float(), 'g', -1, 64))
+	default:
+		panic(fmt.Errorf("Unsupported AST value kind: %s", val.Kind()))
+	}
+}
+
+// typeName returns the Go name of given AST type
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	}
+
+	if t.PkgPath() != "" {
+		return "ast." + t.Name()
+	}
+
+	return t.Name()
+}
+
+// isZero returns true if given value is the zero value of its type
+func isZero(val reflect.Value) bool {
+	return reflect.DeepEqual(val.Interface(), reflect.Zero(val.Type()).Interface())
+}
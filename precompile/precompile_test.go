@@ -0,0 +1,115 @@
+package precompile
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/raymond"
+	"github.com/aymerick/raymond/precompile/internal/golden"
+)
+
+var update = flag.Bool("update", false, "update golden file")
+
+const goldenFile = "internal/golden/templates.go"
+
+// goldenTemplates are the templates precompiled in golden file
+var goldenTemplates = map[string]string{
+	"layouts/header": "<h1>{{title}}</h1>\n",
+	"pages/home": `{{> layouts/header}}
+<ul>
+  {{#each posts as |post i|}}
+  <li class="{{#if @first}}first{{else if @last}}last{{/if}}">{{i}}: {{post.title}} {{~#if post.draft}} (draft){{/if}}</li>
+  {{else}}
+  <li>none</li>
+  {{/each}}
+</ul>
+{{! comment }}
+{{#with author}}{{firstName}} {{{lastName}}}{{/with}} {{round 1.5}} {{add 9007199254740992 -1.5}} {{lookup . "title"}}
+{{{{raw}}}} {{raw}} {{{{/raw}}}}
+  {{> item name=(lookup author "firstName") isolate=true}}
+`,
+	"item": "<span>{{name}}{{@root.title}}</span>\n",
+}
+
+var goldenCtx = map[string]interface{}{
+	"title": "Home",
+	"posts": []map[string]interface{}{
+		{"title": "foo"},
+		{"title": "bar", "draft": true},
+		{"title": "baz"},
+	},
+	"author": map[string]string{"firstName": "Jean", "lastName": "<Valjean>"},
+}
+
+func TestGenerateGo(t *testing.T) {
+	source, err := GenerateGo("golden", goldenTemplates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := ioutil.WriteFile(goldenFile, source, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(source, expected) {
+		t.Errorf("Generated source differs from %s, run tests with -update flag", goldenFile)
+	}
+}
+
+func TestPrecompiledRendering(t *testing.T) {
+	raw := func(options *raymond.Options) string { return options.Fn() }
+
+	set := raymond.NewTemplateSet()
+	for name, source := range goldenTemplates {
+		set.MustParse(name, source)
+	}
+	set.RegisterHelper("raw", raw)
+	golden.Templates.RegisterHelper("raw", raw)
+
+	if len(golden.Templates.Names()) != len(goldenTemplates) {
+		t.Fatalf("Failed to add precompiled templates to set: %q", golden.Templates.Names())
+	}
+
+	for name := range goldenTemplates {
+		precompiled := golden.Templates.Lookup(name)
+
+		if ast, expected := precompiled.PrintAST(), set.Lookup(name).PrintAST(); ast != expected {
+			t.Errorf("Precompiled template %s AST differs\nexpected\n%s\ngot\n%s", name, expected, ast)
+		}
+
+		expected, err := set.Exec(name, goldenCtx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// builtin helpers are rendered, so that number literals are compared
+		if (name == "pages/home") && !strings.Contains(expected, "Jean <Valjean> 2 9007199254740990 Home") {
+			t.Errorf("Unexpected rendering of template %s: %q", name, expected)
+		}
+
+		if result, err := golden.Templates.Exec(name, goldenCtx); (err != nil) || (result != expected) {
+			t.Errorf("Precompiled template %s rendering differs\nexpected\n%q\ngot\n%q (%v)", name, expected, result, err)
+		}
+
+		if result, err := precompiled.Clone().MustCompile().Exec(goldenCtx); (err != nil) || (result != expected) {
+			t.Errorf("Compiled precompiled template %s rendering differs\nexpected\n%q\ngot\n%q (%v)", name, expected, result, err)
+		}
+	}
+}
+
+func TestGenerateGoParseError(t *testing.T) {
+	_, err := GenerateGo("views", map[string]string{"broken": "{{#if}}"})
+	if (err == nil) || !strings.HasPrefix(err.Error(), "Failed to parse template broken: ") {
+		t.Errorf("Generating an invalid template must fail: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package raymond
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+const (
+	// DefaultSafeMaxIterations is the default maximum number of iterations of a block in safe mode.
+	DefaultSafeMaxIterations = 1000
+
+	// DefaultSafeMaxPartialDepth is the default maximum nesting of partials in safe mode.
+	DefaultSafeMaxPartialDepth = 32
+)
+
+// SafeLimits defines the limits enforced by safe mode. A zero value means the default limit.
+type SafeLimits struct {
+	// MaxIterations is the maximum number of iterations of a single #each, #times or #range block
+	MaxIterations int
+
+	// MaxPartialDepth is the maximum nesting of partials, that prevents infinite partial recursion
+	MaxPartialDepth int
+}
+
+// SafeViolation identifies a rule that was violated in safe mode.
+type SafeViolation int
+
+const (
+	// SafeFuncCall is a call to a function context value or to a context method.
+	SafeFuncCall SafeViolation = iota + 1
+
+	// SafeIterationLimit is a block iterating more than the maximum number of iterations.
+	SafeIterationLimit
+
+	// SafeRecursionLimit is a partial nesting deeper than the maximum partial depth.
+	SafeRecursionLimit
+
+	// SafeRegistration is a helper or partial registration during evaluation.
+	SafeRegistration
+)
+
+// SafeModeError is the error returned when a template evaluated in safe mode violates a sandbox rule.
+type SafeModeError struct {
+	Violation SafeViolation
+	Reason    string
+
+	// position of offending node in template source
+	Line int
+	Pos  int
+}
+
+// Error implements the error interface.
+func (err *SafeModeError) Error() string {
+	return fmt.Sprintf("Safe mode violation on line %d, position %d: %s", err.Line, err.Pos, err.Reason)
+}
+
+// SafeMode returns an ExecOption that evaluates a template authored by an untrusted party.
+//
+// In safe mode, function context values and context methods are never called, blocks iterations and partials nesting
+// are limited, and helpers can't register helpers or partials with Options.RegisterHelper() or
+// Options.RegisterPartial(). A violation aborts evaluation with a *SafeModeError.
+func SafeMode(limits SafeLimits) ExecOption {
+	if limits.MaxIterations <= 0 {
+		limits.MaxIterations = DefaultSafeMaxIterations
+	}
+
+	if limits.MaxPartialDepth <= 0 {
+		limits.MaxPartialDepth = DefaultSafeMaxPartialDepth
+	}
+
+	return func(opts *execOptions) {
+		opts.safe = &limits
+	}
+}
+
+// safeViolation panics with a safe mode error located at given node
+func safeViolation(violation SafeViolation, node ast.Node, format string, args ...interface{}) {
+	err := &SafeModeError{
+		Violation: violation,
+		Reason:    fmt.Sprintf(format, args...),
+	}
+
+	if node != nil {
+		loc := node.Location()
+		err.Line, err.Pos = loc.Line, loc.Pos
+	}
+
+	panic(err)
+}
+
+// locateSafeError sets the position of a safe mode error raised without node to the node being evaluated
+func (v *evalVisitor) locateSafeError() {
+	e := recover()
+	if e == nil {
+		return
+	}
+
+	if err, ok := e.(*SafeModeError); ok && (err.Line == 0) && (v.curNode != nil) {
+		loc := v.curNode.Location()
+		err.Line, err.Pos = loc.Line, loc.Pos
+	}
+
+	panic(e)
+}
+
+// checkRegistration panics in safe mode, as helpers and partials must not be registered during evaluation
+func (v *evalVisitor) checkRegistration(kind string, name string) {
+	if v.safe != nil {
+		safeViolation(SafeRegistration, v.curNode, "%s '%s' registered during evaluation", kind, name)
+	}
+}
+
+// checkPrint panics in safe mode if printing given value would call one of its methods
+func (v *evalVisitor) checkPrint(val reflect.Value) {
+	if v.safe == nil {
+		return
+	}
+
+	if name, typ := printMethod(val, 0); name != "" {
+		safeViolation(SafeFuncCall, v.curNode, "method '%s' of %s called", name, typ)
+	}
+}
+
+// checkPrintKeys panics in safe mode if sorting the keys of given map would call one of their methods
+func (v *evalVisitor) checkPrintKeys(val reflect.Value) {
+	if v.safe == nil {
+		return
+	}
+
+	for _, key := range val.MapKeys() {
+		v.checkPrint(key)
+	}
+}
+
+// printMethod returns the name of the method, and the type, that printing given value calls on that value or on one of
+// its items, fields or map entries, as Str() and the fmt package do
+func printMethod(val reflect.Value, depth int) (string, reflect.Type) {
+	for (val.Kind() == reflect.Interface) || ((val.Kind() == reflect.Ptr) && (depth == 0)) {
+		if val.IsNil() {
+			return "", nil
+		}
+
+		if name := printableMethod(val.Type()); name != "" {
+			return name, val.Type()
+		}
+
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return "", nil
+	}
+
+	if name := printableMethod(val.Type()); name != "" {
+		return name, val.Type()
+	}
+
+	if val.CanAddr() {
+		if name := printableMethod(reflect.PtrTo(val.Type())); name != "" {
+			return name, reflect.PtrTo(val.Type())
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < val.Len(); i++ {
+			if name, typ := printMethod(val.Index(i), depth+1); name != "" {
+				return name, typ
+			}
+		}
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			if name, typ := printMethod(key, depth+1); name != "" {
+				return name, typ
+			}
+
+			if name, typ := printMethod(val.MapIndex(key), depth+1); name != "" {
+				return name, typ
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			// methods of unexported fields are not called
+			if field := val.Field(i); field.CanInterface() {
+				if name, typ := printMethod(field, depth+1); name != "" {
+					return name, typ
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// printableMethod returns the name of the method that prints a value of given type, if any
+func printableMethod(t reflect.Type) string {
+	switch {
+	case t.Implements(fmtStringerType):
+		return "String"
+	case t.Implements(textMarshalerType):
+		return "MarshalText"
+	case t.Implements(errorType):
+		return "Error"
+	}
+
+	return ""
+}
+
+// checkFuncCall panics in safe mode, as functions must not be called
+func (v *evalVisitor) checkFuncCall(name string) {
+	if v.safe != nil {
+		safeViolation(SafeFuncCall, v.curNode, "function '%s' called", name)
+	}
+}
+
+// checkIterations panics in safe mode if given number of iterations exceeds the limit
+func (v *evalVisitor) checkIterations(length int) {
+	if (v.safe == nil) || (length <= v.safe.MaxIterations) {
+		return
+	}
+
+	var node ast.Node = v.curNode
+	if block := v.curBlock(); block != nil {
+		node = block
+	}
+
+	safeViolation(SafeIterationLimit, node, "%d iterations exceed the limit of %d", length, v.safe.MaxIterations)
+}
+
+// checkPartialDepth panics in safe mode if partials nesting exceeds the limit
+func (v *evalVisitor) checkPartialDepth(node ast.Node) {
+	if (v.safe != nil) && (v.partialDepth > v.safe.MaxPartialDepth) {
+		safeViolation(SafeRecursionLimit, node, "partials nesting exceeds the limit of %d", v.safe.MaxPartialDepth)
+	}
+}
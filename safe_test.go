@@ -0,0 +1,250 @@
+package raymond
+
+import (
+	"strings"
+	"testing"
+)
+
+type safeCtx struct {
+	Name string
+}
+
+func (ctx safeCtx) Secret() string {
+	return "secret"
+}
+
+type safeStringer struct {
+	called *bool
+}
+
+func (s safeStringer) String() string {
+	*s.called = true
+	return "secret"
+}
+
+type safeError struct{}
+
+func (e *safeError) Error() string {
+	return "secret"
+}
+
+var safeModeTests = []struct {
+	name      string
+	input     string
+	ctx       interface{}
+	partials  map[string]string
+	violation SafeViolation
+	line      int
+}{
+	{
+		"function context value",
+		`{{foo}}`,
+		map[string]interface{}{"foo": func() string { return "foo" }},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"context method",
+		"{{name}}\n{{secret}}",
+		safeCtx{"foo"},
+		nil,
+		SafeFuncCall, 2,
+	},
+	{
+		"function context value in a sub-expression",
+		`{{#if (foo)}}bar{{/if}}`,
+		map[string]interface{}{"foo": func() bool { return true }},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"function context value as a helper parameter",
+		`{{lookup this "secret"}}`,
+		safeCtx{"foo"},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"function context value in a partial",
+		`{{> foo}}`,
+		map[string]interface{}{"bar": func() string { return "bar" }},
+		map[string]string{"foo": "{{bar}}"},
+		SafeFuncCall, 1,
+	},
+	{
+		"fmt.Stringer context value",
+		`{{foo}}`,
+		map[string]interface{}{"foo": safeStringer{new(bool)}},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"error context value in a list",
+		"\n{{foo}}",
+		map[string]interface{}{"foo": []interface{}{"bar", &safeError{}}},
+		nil,
+		SafeFuncCall, 2,
+	},
+	{
+		"fmt.Stringer context value as a string helper parameter",
+		`{{upper foo}}`,
+		map[string]interface{}{"foo": safeStringer{new(bool)}},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"fmt.Stringer context value compared by #equal",
+		`{{#equal foo "secret"}}yes{{/equal}}`,
+		map[string]interface{}{"foo": safeStringer{new(bool)}},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"fmt.Stringer context value joined by #join",
+		`{{join foo ", "}}`,
+		map[string]interface{}{"foo": []interface{}{"bar", safeStringer{new(bool)}}},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"fmt.Stringer map keys sorted by #each",
+		`{{#each foo}}{{this}}{{/each}}`,
+		map[string]interface{}{"foo": map[safeStringer]int{{new(bool)}: 1, {new(bool)}: 2}},
+		nil,
+		SafeFuncCall, 1,
+	},
+	{
+		"#each iterations",
+		"\n{{#each items}}{{this}}{{/each}}",
+		map[string]interface{}{"items": make([]int, 11)},
+		nil,
+		SafeIterationLimit, 2,
+	},
+	{
+		"#times iterations",
+		`{{#times 11}}{{this}}{{/times}}`,
+		nil,
+		nil,
+		SafeIterationLimit, 1,
+	},
+	{
+		"block iterations",
+		`{{#items}}{{this}}{{/items}}`,
+		map[string]interface{}{"items": make([]int, 11)},
+		nil,
+		SafeIterationLimit, 1,
+	},
+	{
+		"partials recursion",
+		`{{> foo}}`,
+		nil,
+		map[string]string{"foo": "{{> foo}}"},
+		SafeRecursionLimit, 1,
+	},
+}
+
+func TestSafeMode(t *testing.T) {
+	t.Parallel()
+
+	limits := SafeLimits{MaxIterations: 10, MaxPartialDepth: 5}
+
+	for _, test := range safeModeTests {
+		tpl := MustParse(test.input)
+		tpl.RegisterPartials(test.partials)
+
+		// infinite recursion can't be evaluated without safe mode
+		if test.violation != SafeRecursionLimit {
+			if _, err := tpl.Exec(test.ctx); err != nil {
+				t.Errorf("Test '%s' failed without safe mode: %s", test.name, err)
+			}
+		}
+
+		for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+			_, err := tpl.ExecWithOptions(test.ctx, nil, SafeMode(limits))
+
+			safeErr, ok := err.(*SafeModeError)
+			if !ok {
+				t.Errorf("Test '%s' failed - expected a *SafeModeError, got: %v", test.name, err)
+				continue
+			}
+
+			if (safeErr.Violation != test.violation) || (safeErr.Line != test.line) {
+				t.Errorf("Test '%s' failed - expected violation %d on line %d, got: %s", test.name, test.violation, test.line, safeErr)
+			}
+		}
+	}
+}
+
+func TestSafeModeOutput(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each items}}{{upper this}}{{/each}} {{> foo}} {{#times 3}}{{this}}{{/times}}`)
+	tpl.RegisterHelper("upper", strings.ToUpper)
+	tpl.RegisterPartial("foo", "{{name}}")
+
+	ctx := map[string]interface{}{
+		"items": []string{"a", "b"},
+		"name":  "bar",
+	}
+
+	expected := tpl.MustExec(ctx)
+
+	output, err := tpl.ExecWithOptions(ctx, nil, SafeMode(SafeLimits{}))
+	if err != nil {
+		t.Fatalf("Safe mode evaluation failed: %s", err)
+	}
+
+	if output != expected {
+		t.Errorf("Safe mode output differs, expected %q, got %q", expected, output)
+	}
+}
+
+func TestSafeModeStringer(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{foo}}`)
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		called := false
+
+		_, err := tpl.ExecWithOptions(map[string]interface{}{"foo": safeStringer{&called}}, nil, SafeMode(SafeLimits{}))
+		if _, ok := err.(*SafeModeError); !ok {
+			t.Errorf("Expected a *SafeModeError, got: %v", err)
+		}
+
+		if called {
+			t.Errorf("String() method must not be called in safe mode")
+		}
+	}
+}
+
+func TestSafeModeRegistration(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("\n{{register}}{{> foo}}")
+	tpl.RegisterHelper("register", func(options *Options) string {
+		options.RegisterPartial("foo", "foo")
+		return ""
+	})
+
+	_, err := tpl.ExecWithOptions(nil, nil, SafeMode(SafeLimits{}))
+
+	safeErr, ok := err.(*SafeModeError)
+	if !ok || (safeErr.Violation != SafeRegistration) || (safeErr.Line != 2) {
+		t.Fatalf("Expected a registration violation on line 2, got: %v", err)
+	}
+
+	// registration is allowed during an evaluation that is not in safe mode
+	other := MustParse("{{register}}{{> bar}}")
+	other.RegisterHelper("register", func(options *Options) string {
+		options.RegisterPartial("bar", "bar")
+		return ""
+	})
+
+	if output := other.MustExec(nil); output != "bar" {
+		t.Errorf("Expected partial registered during evaluation to be rendered, got: %q", output)
+	}
+
+	// registration is allowed outside evaluation
+	tpl.RegisterHelper("bar", func() string { return "bar" })
+}
@@ -0,0 +1,88 @@
+package raymond
+
+import (
+	"github.com/aymerick/raymond/ast"
+)
+
+// Segment is a range of template output, produced by a statement of that template.
+type Segment struct {
+	// output byte range, End being excluded
+	Start int
+	End   int
+
+	// statement that produced the output
+	Node ast.Node
+
+	// statement position in template source
+	Line int
+	Pos  int
+}
+
+// ExecWithSourceMap evaluates template with given context and private data frame, and returns the output segments
+// produced by each statement of that template.
+//
+// Segments are returned in output order, one for each top level statement, including content and statements that
+// produce an empty output. The whole output of a block or a partial is mapped to that block or partial statement.
+func (tpl *Template) ExecWithSourceMap(ctx interface{}, privData *DataFrame) (result string, segments []Segment, err error) {
+	defer errRecover(&err)
+
+	// parses template if necessary
+	err = tpl.parse()
+	if err != nil {
+		return
+	}
+
+	// setup visitor
+	v := newEvalVisitor(tpl, ctx, privData)
+	defer v.release()
+
+	result, segments = v.sourceMap(tpl.program)
+
+	// named return values
+	return
+}
+
+// sourceMap evaluates given program, and returns its output with the segments produced by its statements
+//
+// Contrary to VisitProgram(), the compiled version of given program is never used, as it does not evaluate statements
+// independently.
+func (v *evalVisitor) sourceMap(node *ast.Program) (string, []Segment) {
+	v.at(node)
+
+	buf := v.pushBuffer()
+
+	segments := make([]Segment, 0, len(node.Body))
+
+	for _, n := range node.Body {
+		start := buf.Len()
+
+		switch n := n.(type) {
+		case *ast.ContentStatement:
+			if _, err := buf.WriteString(n.Value); err != nil {
+				v.errPanic(err)
+			}
+		case *ast.MustacheStatement:
+			v.writeMustache(buf, n)
+		default:
+			if _, err := buf.WriteString(Str(n.Accept(v))); err != nil {
+				v.errPanic(err)
+			}
+		}
+
+		loc := n.Location()
+
+		segments = append(segments, Segment{
+			Start: start,
+			End:   buf.Len(),
+			Node:  n,
+			Line:  loc.Line,
+			Pos:   loc.Pos,
+		})
+	}
+
+	result := buf.String()
+
+	v.popBuffer()
+
+	return result, segments
+}
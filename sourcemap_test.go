@@ -0,0 +1,62 @@
+package raymond
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+func TestExecWithSourceMap(t *testing.T) {
+	t.Parallel()
+
+	source := "Hello {{name}}!\n{{#if admin}}<b>admin</b>{{/if}}{{! comment }}{{> footer}}"
+
+	expected := []string{
+		`*ast.ContentStatement line:1 pos:0 "Hello "`,
+		`*ast.MustacheStatement line:1 pos:6 "&lt;foo&gt;"`,
+		`*ast.ContentStatement line:1 pos:14 "!\n"`,
+		`*ast.BlockStatement line:2 pos:16 "<b>admin</b>"`,
+		`*ast.CommentStatement line:2 pos:48 ""`,
+		`*ast.PartialStatement line:2 pos:62 "[foo]"`,
+	}
+
+	ctx := map[string]interface{}{"name": "<foo>", "admin": true}
+
+	tpl := MustParse(source)
+	tpl.RegisterPartial("footer", "[{{@user}}]")
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		result, segments, err := tpl.ExecWithSourceMap(ctx, NewDataFrameFromMap(map[string]interface{}{"user": "foo"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result != "Hello &lt;foo&gt;!\n<b>admin</b>[foo]" {
+			t.Errorf("Unexpected source map output: %q", result)
+		}
+
+		var got []string
+		for _, segment := range segments {
+			got = append(got, fmt.Sprintf("%T line:%d pos:%d %q", segment.Node, segment.Line, segment.Pos, result[segment.Start:segment.End]))
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Unexpected segments:\n%q\nexpected:\n%q", got, expected)
+		}
+
+		if node, ok := segments[1].Node.(*ast.MustacheStatement); !ok || (node.Expression.Canonical() != "name") {
+			t.Errorf("Segment must reference the node that produced it: %#v", segments[1].Node)
+		}
+	}
+}
+
+func TestExecWithSourceMapError(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{foo}}{{> missing}}")
+
+	if _, _, err := tpl.ExecWithSourceMap(nil, nil); err == nil {
+		t.Errorf("Source map evaluation must fail")
+	}
+}
@@ -1,6 +1,7 @@
 package raymond
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -20,7 +21,14 @@ func isSafeString(value interface{}) bool {
 }
 
 // Str returns string representation of any basic type value.
+//
+// A value implementing fmt.Stringer, encoding.TextMarshaler or error is represented by the result of its String(),
+// MarshalText() or Error() method, in that order of preference.
 func Str(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
 	return strValue(reflect.ValueOf(value))
 }
 
@@ -33,10 +41,31 @@ func strValue(value reflect.Value) string {
 		panic(fmt.Errorf("Can't print value: %q", value))
 	}
 
+	switch v := ival.(type) {
+	case fmt.Stringer:
+		return v.String()
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			panic(fmt.Errorf("Can't marshal value %T: %s", ival, err))
+		}
+
+		return string(text)
+	case error:
+		return v.Error()
+	}
+
 	val := reflect.ValueOf(ival)
 
 	switch val.Kind() {
-	case reflect.Array, reflect.Slice:
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			// bytes, like text columns of database rows
+			return string(val.Bytes())
+		}
+
+		fallthrough
+	case reflect.Array:
 		for i := 0; i < val.Len(); i++ {
 			result += strValue(val.Index(i))
 		}
@@ -45,8 +74,10 @@ func strValue(value reflect.Value) string {
 		if val.Bool() {
 			result = "true"
 		}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		result = fmt.Sprintf("%d", ival)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result = strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result = strconv.FormatUint(val.Uint(), 10)
 	case reflect.Float32, reflect.Float64:
 		result = strconv.FormatFloat(val.Float(), 'f', -1, 64)
 	case reflect.Invalid:
@@ -70,8 +101,8 @@ func printableValue(v reflect.Value) (interface{}, bool) {
 		return "", true
 	}
 
-	if !v.Type().Implements(errorType) && !v.Type().Implements(fmtStringerType) {
-		if v.CanAddr() && (reflect.PtrTo(v.Type()).Implements(errorType) || reflect.PtrTo(v.Type()).Implements(fmtStringerType)) {
+	if !isPrintable(v.Type()) {
+		if v.CanAddr() && isPrintable(reflect.PtrTo(v.Type())) {
 			v = v.Addr()
 		} else {
 			switch v.Kind() {
@@ -82,3 +113,8 @@ func printableValue(v reflect.Value) (interface{}, bool) {
 	}
 	return v.Interface(), true
 }
+
+// isPrintable returns true if given type has a method that returns its string representation
+func isPrintable(t reflect.Type) bool {
+	return t.Implements(errorType) || t.Implements(fmtStringerType) || t.Implements(textMarshalerType)
+}
@@ -1,10 +1,48 @@
 package raymond
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
+// money implements fmt.Stringer
+type money int64
+
+func (m money) String() string {
+	return fmt.Sprintf("$%d.%02d", m/100, m%100)
+}
+
+// uuid implements fmt.Stringer
+type uuid [4]byte
+
+func (id uuid) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// textID implements encoding.TextMarshaler
+type textID [2]byte
+
+func (id textID) MarshalText() ([]byte, error) {
+	if id == (textID{}) {
+		return nil, errors.New("empty id")
+	}
+
+	return []byte(strings.ToUpper(hex.EncodeToString(id[:]))), nil
+}
+
+// ptrMoney implements fmt.Stringer with a pointer receiver
+type ptrMoney struct {
+	Cents int64
+}
+
+func (m *ptrMoney) String() string {
+	return money(m.Cents).String()
+}
+
 type strTest struct {
 	name   string
 	input  interface{}
@@ -16,11 +54,22 @@ var strTests = []strTest{
 	{"Boolean true", true, "true"},
 	{"Boolean false", false, "false"},
 	{"Integer", 25, "25"},
+	{"Negative int64", int64(-9223372036854775808), "-9223372036854775808"},
+	{"Unsigned integer", uint64(18446744073709551615), "18446744073709551615"},
 	{"Float", 25.75, "25.75"},
 	{"Nil", nil, ""},
 	{"[]string", []string{"foo", "bar"}, "foobar"},
 	{"[]interface{} (strings)", []interface{}{"foo", "bar"}, "foobar"},
 	{"[]Boolean", []bool{true, false}, "truefalse"},
+	{"fmt.Stringer", money(1250), "$12.50"},
+	{"fmt.Stringer array", uuid{0xde, 0xad, 0xbe, 0xef}, "deadbeef"},
+	{"[]fmt.Stringer", []money{100, 5}, "$1.00$0.05"},
+	{"encoding.TextMarshaler array", textID{0xca, 0xfe}, "CAFE"},
+	{"pointer to fmt.Stringer with pointer receiver", &ptrMoney{2000}, "$20.00"},
+	{"error", errors.New("failure"), "failure"},
+	{"[]byte", []byte("foo"), "foo"},
+	{"json.RawMessage", json.RawMessage(`{"foo":1}`), `{"foo":1}`},
+	{"json.Number", json.Number("1.50"), "1.50"},
 }
 
 func TestStr(t *testing.T) {
@@ -33,6 +82,38 @@ func TestStr(t *testing.T) {
 	}
 }
 
+func TestStrMarshalError(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); (r == nil) || !strings.Contains(fmt.Sprint(r), "empty id") {
+			t.Errorf("Expected a marshal error, got: %v", r)
+		}
+	}()
+
+	Str(textID{})
+}
+
+func TestStrRender(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("{{price}} {{id}}")
+
+	ctx := map[string]interface{}{
+		"price": money(999),
+		"id":    textID{0xca, 0xfe},
+	}
+
+	if output := tpl.MustExec(ctx); output != "$9.99 CAFE" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	_, err := tpl.Exec(map[string]interface{}{"id": textID{}})
+	if (err == nil) || !strings.Contains(err.Error(), "Can't marshal value raymond.textID: empty id") {
+		t.Errorf("Expected a marshal error, got: %v", err)
+	}
+}
+
 func ExampleStr() {
 	output := Str(3) + " foos are " + Str(true) + " and " + Str(-1.25) + " bars are " + Str(false) + "\n"
 	output += "But you know '" + Str(nil) + "' John Snow\n"
@@ -6,20 +6,90 @@ import (
 	"reflect"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aymerick/raymond/ast"
 	"github.com/aymerick/raymond/parser"
 )
 
+// HelperPrecedence defines how an expression like `{{foo}}` is resolved when both a helper and a context field have that name.
+type HelperPrecedence int
+
+const (
+	// HelperFirst resolves helpers before context fields. This is the default.
+	HelperFirst HelperPrecedence = iota
+
+	// FieldFirst resolves context fields before helpers, for expressions without parameters nor hash arguments.
+	FieldFirst
+)
+
+// FieldMatching defines how template variable names are matched with struct field names.
+type FieldMatching int
+
+const (
+	// StrictFieldMatching matches exported field names, with their first letter upper-cased, and `handlebars` struct
+	// tags. This is the default.
+	StrictFieldMatching FieldMatching = iota
+
+	// LooseFieldMatching also matches field names ignoring case and underscores, when strict matching fails, so that
+	// `first_name` matches `FirstName`.
+	LooseFieldMatching
+)
+
 // Template represents a handlebars template.
 type Template struct {
-	source   string
-	program  *ast.Program
-	helpers  map[string]reflect.Value
-	partials map[string]*partial
-	mutex    sync.RWMutex // protects helpers and partials
+	// size of last rendered output, accessed atomically (first field for 64-bit alignment)
+	lastSize int64
+
+	name             string
+	source           string
+	program          *ast.Program
+	helpers          map[string]reflect.Value
+	partials         map[string]*partial
+	set              *TemplateSet
+	helperPrecedence HelperPrecedence
+	fieldMatching    FieldMatching
+	trimBlocks       bool
+	lstripBlocks     bool
+	lenient          bool
+	translator       Translator
+	compiled         compiledPrograms
+	defaults         map[string]interface{}
+	missingFunc      func(path string) string
+	sizeHint         int
+	traceHook        TraceHook
+	interceptor      *Interceptor
+	warnings         []Warning
+	lintOnce         sync.Once
+	mutex            sync.RWMutex // protects helpers, partials, translator and compiled programs
+}
+
+// ExecOption configures a template evaluation.
+type ExecOption func(*execOptions)
+
+// execOptions stores the options of a template evaluation
+type execOptions struct {
+	safe              *SafeLimits
+	strictBlockParams bool
+}
+
+// StrictBlockParams returns an ExecOption that makes evaluation fail when a block of a builtin helper declares more block
+// params with `as |a b|` than that helper provides, like `{{#with foo as |a b|}}`.
+//
+// Builtin `#each` provides 2 block params, `#with` provides 1, and `#if` and `#unless` provide none. Custom helpers can
+// check their own block params with Options.CheckBlockParams().
+func StrictBlockParams() ExecOption {
+	return func(opts *execOptions) {
+		opts.strictBlockParams = true
+	}
 }
 
+// templates stores all named templates
+var templates = make(map[string]*Template)
+
+// protects named templates
+var templatesMutex sync.RWMutex
+
 // newTemplate instanciate a new template without parsing it
 func newTemplate(source string) *Template {
 	return &Template{
@@ -41,6 +111,22 @@ func Parse(source string) (*Template, error) {
 	return tpl, nil
 }
 
+// ParseLenient instanciates a template by parsing given source, like Parse(), but it accepts duplicate hash keys.
+//
+// The last value of a duplicated key is used, and each duplicated key is reported by the Warnings() method with the
+// WarnDuplicateHashKey code.
+func ParseLenient(source string) (*Template, error) {
+	tpl := newTemplate(source)
+	tpl.lenient = true
+
+	// parse template
+	if err := tpl.parse(); err != nil {
+		return nil, err
+	}
+
+	return tpl, nil
+}
+
 // MustParse instanciates a template by parsing given source. It panics on error.
 func MustParse(source string) *Template {
 	result, err := Parse(source)
@@ -50,6 +136,44 @@ func MustParse(source string) *Template {
 	return result
 }
 
+// NewParsedTemplate instanciates a template with given source and its program, without parsing that source.
+//
+// Given program must be the result of parsing given source, as done by code generated by the precompile package. The
+// source is still needed to report error positions and to apply whitespace options.
+func NewParsedTemplate(source string, program *ast.Program) *Template {
+	tpl := newTemplate(source)
+	tpl.program = program
+
+	return tpl
+}
+
+// ParseNamed instanciates a template by parsing given source, and caches it with given name.
+//
+// The cached template can then be retrieved with the Lookup() function. A template previously cached with the same name is replaced.
+func ParseNamed(name string, source string) (*Template, error) {
+	tpl, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl.name = name
+
+	templatesMutex.Lock()
+	defer templatesMutex.Unlock()
+
+	templates[name] = tpl
+
+	return tpl, nil
+}
+
+// Lookup returns the template cached with given name by ParseNamed(), or nil if not found.
+func Lookup(name string) *Template {
+	templatesMutex.RLock()
+	defer templatesMutex.RUnlock()
+
+	return templates[name]
+}
+
 // ParseFile reads given file and returns parsed template.
 func ParseFile(filePath string) (*Template, error) {
 	b, err := ioutil.ReadFile(filePath)
@@ -65,26 +189,89 @@ func ParseFile(filePath string) (*Template, error) {
 // It can be called several times, the parsing will be done only once.
 func (tpl *Template) parse() error {
 	if tpl.program == nil {
-		var err error
-
-		tpl.program, err = parser.Parse(tpl.source)
+		program, err := tpl.parseProgram()
 		if err != nil {
 			return err
 		}
+
+		tpl.program = program
 	}
 
 	return nil
 }
 
+// parseProgram parses template source, and applies whitespace options
+func (tpl *Template) parseProgram() (*ast.Program, error) {
+	var program *ast.Program
+	var err error
+
+	if tpl.lenient {
+		program, err = parser.ParseLenient(tpl.source)
+	} else {
+		program, err = parser.Parse(tpl.source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tpl.trimBlocks || tpl.lstripBlocks {
+		trimBlocks(program, tpl.source, tpl.trimBlocks, tpl.lstripBlocks)
+	}
+
+	return program, nil
+}
+
+// Compile lowers the template to a tree of closures, that is then used to execute the template instead of walking the AST.
+//
+// Compiling is optional. Results are the same than with a non compiled template, and helpers and partials can still be
+// registered after compilation. It is safe to compile a template while other goroutines execute it, executions that
+// already started keep using the previous version.
+func (tpl *Template) Compile() error {
+	if err := tpl.parse(); err != nil {
+		return err
+	}
+
+	compiled := compile(tpl.program)
+
+	tpl.mutex.Lock()
+	tpl.compiled = compiled
+	tpl.mutex.Unlock()
+
+	return nil
+}
+
+// MustCompile compiles the template. It panics on error.
+func (tpl *Template) MustCompile() *Template {
+	if err := tpl.Compile(); err != nil {
+		panic(err)
+	}
+	return tpl
+}
+
 // Clone returns a copy of that template.
 func (tpl *Template) Clone() *Template {
 	result := newTemplate(tpl.source)
 
+	result.name = tpl.name
+	result.set = tpl.set
+
 	result.program = tpl.program
+	result.helperPrecedence = tpl.helperPrecedence
+	result.fieldMatching = tpl.fieldMatching
+	result.trimBlocks = tpl.trimBlocks
+	result.lstripBlocks = tpl.lstripBlocks
+	result.lenient = tpl.lenient
+	result.defaults = tpl.defaults
+	result.missingFunc = tpl.missingFunc
+	result.sizeHint = tpl.sizeHint
+	result.traceHook = tpl.traceHook
+	result.interceptor = tpl.interceptor
 
 	tpl.mutex.RLock()
 	defer tpl.mutex.RUnlock()
 
+	result.compiled = tpl.compiled
+
 	for name, helper := range tpl.helpers {
 		result.RegisterHelper(name, helper.Interface())
 	}
@@ -93,14 +280,30 @@ func (tpl *Template) Clone() *Template {
 		result.addPartial(name, partial.source, partial.tpl)
 	}
 
+	result.translator = tpl.translator
+
 	return result
 }
 
+// findHelper finds a helper registered on that template, or else on its template set
 func (tpl *Template) findHelper(name string) reflect.Value {
+	tpl.mutex.RLock()
+	h := tpl.helpers[name]
+	tpl.mutex.RUnlock()
+
+	if (h == zero) && (tpl.set != nil) {
+		h = tpl.set.findHelper(name)
+	}
+
+	return h
+}
+
+// HelperNames returns sorted names of helpers registered on that template. Global helpers are not included.
+func (tpl *Template) HelperNames() []string {
 	tpl.mutex.RLock()
 	defer tpl.mutex.RUnlock()
 
-	return tpl.helpers[name]
+	return helperNames(tpl.helpers)
 }
 
 // RegisterHelper registers a helper for that template.
@@ -125,6 +328,181 @@ func (tpl *Template) RegisterHelpers(helpers map[string]interface{}) {
 	}
 }
 
+// RegisterHelperNamespace registers several helpers for that template under given namespace. Those helpers are called
+// with the namespace as a prefix, like `{{str.upper name}}` for the `upper` helper of the `str` namespace.
+func (tpl *Template) RegisterHelperNamespace(namespace string, helpers map[string]interface{}) {
+	for name, helper := range helpers {
+		tpl.RegisterHelper(namespace+"."+name, helper)
+	}
+}
+
+// WithHelper registers a helper for that template, and returns that template. It panics if that helper is already registered.
+//
+// example: raymond.MustParse(source).WithHelper("upper", strings.ToUpper)
+func (tpl *Template) WithHelper(name string, helper interface{}) *Template {
+	tpl.RegisterHelper(name, helper)
+	return tpl
+}
+
+// WithHelpers registers several helpers for that template, and returns that template. It panics if a helper is already registered.
+func (tpl *Template) WithHelpers(helpers map[string]interface{}) *Template {
+	tpl.RegisterHelpers(helpers)
+	return tpl
+}
+
+// Funcs registers the functions of given `text/template` function map as helpers for that template, and returns that
+// template. It panics if a helper is already registered.
+//
+// Contrary to helpers, functions may be variadic, and parameters are converted to function arguments types when
+// possible. A function returns either one value, that is the helper result, or a value and an error: the value is the
+// helper result if the error is nil, and the error is returned by template execution otherwise.
+func (tpl *Template) Funcs(funcMap map[string]interface{}) *Template {
+	for name, fn := range funcMap {
+		tpl.RegisterHelper(name, funcHelper(name, fn))
+	}
+	return tpl
+}
+
+// RegisterHelperMethods registers all exported methods of given receiver as helpers for that template.
+//
+// Each method is registered under its name with a lower-cased first letter (eg: `Translate()` => `translate`). As with
+// any other helper, a method must return exactly one value, and it can take an `*Options` as its last argument. Methods
+// that do not match that signature are ignored.
+func (tpl *Template) RegisterHelperMethods(recv interface{}) {
+	val := reflect.ValueOf(recv)
+
+	for i := 0; i < val.NumMethod(); i++ {
+		method := val.Method(i)
+		if method.Type().NumOut() != 1 {
+			continue
+		}
+
+		tpl.RegisterHelper(lowerFirst(val.Type().Method(i).Name), method.Interface())
+	}
+}
+
+// SetHelperPrecedence sets how an expression is resolved when both a helper and a context field have the same name.
+//
+// Default is HelperFirst. It must be called before executing the template.
+func (tpl *Template) SetHelperPrecedence(precedence HelperPrecedence) {
+	tpl.helperPrecedence = precedence
+}
+
+// SetFieldMatching sets how template variable names are matched with struct field names.
+//
+// Default is StrictFieldMatching. It must be called before executing the template.
+func (tpl *Template) SetFieldMatching(matching FieldMatching) {
+	tpl.fieldMatching = matching
+}
+
+// SetTrimBlocks sets if the first newline after a block tag like `{{#if foo}}`, `{{else}}` or `{{/if}}` is removed.
+//
+// Default is false. It must be called before compiling and executing the template, and it does not apply to partials.
+func (tpl *Template) SetTrimBlocks(trim bool) {
+	tpl.trimBlocks = trim
+	tpl.reparse()
+}
+
+// SetLstripBlocks sets if spaces and tabs from the start of a line to a block tag are removed.
+//
+// Default is false. It must be called before compiling and executing the template, and it does not apply to partials.
+func (tpl *Template) SetLstripBlocks(lstrip bool) {
+	tpl.lstripBlocks = lstrip
+	tpl.reparse()
+}
+
+// reparse parses template again to apply whitespace options, and discards compiled template
+//
+// The template is parsed right away, so that executions never parse it.
+func (tpl *Template) reparse() {
+	program, err := tpl.parseProgram()
+	if err != nil {
+		panic(err)
+	}
+
+	tpl.mutex.Lock()
+	tpl.program = program
+	tpl.compiled = nil
+	tpl.mutex.Unlock()
+}
+
+// SetDefaults sets values that are resolved when they are not found in the rendering context.
+//
+// Defaults have the lowest precedence, and they are distinct from private data. It must be called before executing the template.
+func (tpl *Template) SetDefaults(defaults map[string]interface{}) {
+	tpl.defaults = defaults
+}
+
+// SetMissingFunc sets a function that returns the value rendered by a mustache with a single path, like `{{foo.bar}}`,
+// when that path is not found or resolves to nil. It receives the path as written in template.
+//
+// A path that resolves to an empty string is not missing. Returned value is escaped as any other value. Default is nil,
+// so that a missing path renders nothing. It must be called before executing the template.
+//
+// example: tpl.SetMissingFunc(func(path string) string { return "<missing:" + path + ">" })
+func (tpl *Template) SetMissingFunc(fn func(path string) string) {
+	tpl.missingFunc = fn
+}
+
+// SetOutputSizeHint sets the expected size of rendered output, so that output buffer is allocated once.
+//
+// Without a hint, the size of the previous rendering is used, up to 64KB, or the template source size for the first
+// rendering. It must be called before executing the template.
+func (tpl *Template) SetOutputSizeHint(size int) {
+	tpl.sizeHint = size
+}
+
+// outputSize returns the expected size of rendered output
+//
+// The size of the previous rendering is capped, so that a single huge rendering does not make all following renderings
+// allocate that much.
+func (tpl *Template) outputSize() int {
+	if tpl.sizeHint > 0 {
+		return tpl.sizeHint
+	}
+
+	if size := atomic.LoadInt64(&tpl.lastSize); size > maxPooledBufferSize {
+		return maxPooledBufferSize
+	} else if size > 0 {
+		return int(size)
+	}
+
+	return len(tpl.source)
+}
+
+// SetTraceHook sets a function that receives an event when the evaluation of a mustache, a block or a partial starts
+// and ends, for profiling and debugging purposes.
+//
+// The hook is called synchronously during evaluation. Pass nil to disable tracing. It must be called before executing
+// the template.
+func (tpl *Template) SetTraceHook(hook TraceHook) {
+	tpl.traceHook = hook
+}
+
+// SetInterceptor sets callbacks that are called around the evaluation of each mustache, block and partial, and that can
+// replace their output, for example to wrap some blocks in custom markup.
+//
+// Intercepted nodes are traced inside interceptor callbacks. Pass nil to disable interception. It must be called before
+// executing the template.
+func (tpl *Template) SetInterceptor(interceptor *Interceptor) {
+	tpl.interceptor = interceptor
+}
+
+// RegisterTranslator registers the translator used by the `t` helper for that template, it overrides the global translator.
+func (tpl *Template) RegisterTranslator(fn Translator) {
+	tpl.mutex.Lock()
+	defer tpl.mutex.Unlock()
+
+	tpl.translator = fn
+}
+
+func (tpl *Template) findTranslator() Translator {
+	tpl.mutex.RLock()
+	defer tpl.mutex.RUnlock()
+
+	return tpl.translator
+}
+
 func (tpl *Template) addPartial(name string, source string, template *Template) {
 	tpl.mutex.Lock()
 	defer tpl.mutex.Unlock()
@@ -136,11 +514,25 @@ func (tpl *Template) addPartial(name string, source string, template *Template)
 	tpl.partials[name] = newPartial(name, source, template)
 }
 
+// findPartial finds a partial registered on that template, or else a partial or a template of its template set
 func (tpl *Template) findPartial(name string) *partial {
+	tpl.mutex.RLock()
+	p := tpl.partials[name]
+	tpl.mutex.RUnlock()
+
+	if (p == nil) && (tpl.set != nil) {
+		p = tpl.set.findPartial(name)
+	}
+
+	return p
+}
+
+// PartialNames returns sorted names of partials registered on that template. Global partials are not included.
+func (tpl *Template) PartialNames() []string {
 	tpl.mutex.RLock()
 	defer tpl.mutex.RUnlock()
 
-	return tpl.partials[name]
+	return partialNames(tpl.partials)
 }
 
 // RegisterPartial registers a partial for that template.
@@ -155,6 +547,12 @@ func (tpl *Template) RegisterPartials(partials map[string]string) {
 	}
 }
 
+// WithPartial registers a partial for that template, and returns that template. It panics if that partial is already registered.
+func (tpl *Template) WithPartial(name string, source string) *Template {
+	tpl.RegisterPartial(name, source)
+	return tpl
+}
+
 // RegisterPartialFile reads given file and registers its content as a partial with given name.
 func (tpl *Template) RegisterPartialFile(filePath string, name string) error {
 	b, err := ioutil.ReadFile(filePath)
@@ -205,24 +603,105 @@ func (tpl *Template) MustExec(ctx interface{}) string {
 
 // ExecWith evaluates template with given context and private data frame.
 func (tpl *Template) ExecWith(ctx interface{}, privData *DataFrame) (result string, err error) {
+	return tpl.ExecWithOptions(ctx, privData)
+}
+
+// ExecWithData evaluates template with given context and private data values, that are accessible with `@` paths.
+func (tpl *Template) ExecWithData(ctx interface{}, data map[string]interface{}) (result string, err error) {
+	return tpl.ExecWith(ctx, NewDataFrameFromMap(data))
+}
+
+// ExecWithOptions evaluates template with given context, private data frame and options.
+func (tpl *Template) ExecWithOptions(ctx interface{}, privData *DataFrame, opts ...ExecOption) (result string, err error) {
 	defer errRecover(&err)
 
+	var options execOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// parses template if necessary
 	err = tpl.parse()
 	if err != nil {
 		return
 	}
 
+	// template without any mustache
+	if content, ok := tpl.contentOnly(); ok {
+		return content, nil
+	}
+
 	// setup visitor
 	v := newEvalVisitor(tpl, ctx, privData)
+	defer v.release()
+
+	v.strictBlockParams = options.strictBlockParams
+
+	if options.safe != nil {
+		v.safe = options.safe
+
+		defer v.locateSafeError()
+	}
 
 	// visit AST
 	result, _ = tpl.program.Accept(v).(string)
 
+	atomic.StoreInt64(&tpl.lastSize, int64(len(result)))
+
 	// named return values
 	return
 }
 
+// contentOnly returns template content if it does not contain any statement other than content
+func (tpl *Template) contentOnly() (string, bool) {
+	switch len(tpl.program.Body) {
+	case 0:
+		return "", true
+	case 1:
+		if node, ok := tpl.program.Body[0].(*ast.ContentStatement); ok {
+			return node.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// ExecAll evaluates template with each given context independently.
+//
+// Results and errors are returned at the same index as their context, with a nil error on success. Contrary to Exec(),
+// any panic occurring during evaluation, for example in a helper, is recovered into an error so that remaining contexts
+// are still evaluated.
+func (tpl *Template) ExecAll(ctxList []interface{}) ([]string, []error) {
+	results := make([]string, len(ctxList))
+	errs := make([]error, len(ctxList))
+
+	for i, ctx := range ctxList {
+		results[i], errs[i] = tpl.execRecover(ctx)
+	}
+
+	return results, errs
+}
+
+// execRecover evaluates template with given context, and recovers any panic into an error
+func (tpl *Template) execRecover(ctx interface{}) (result string, err error) {
+	defer panicRecover(&err)
+
+	return tpl.Exec(ctx)
+}
+
+// panicRecover recovers any panic
+func panicRecover(errp *error) {
+	e := recover()
+	if e != nil {
+		switch err := e.(type) {
+		case error:
+			*errp = err
+		default:
+			*errp = fmt.Errorf("%v", e)
+		}
+	}
+}
+
 // errRecover recovers evaluation panic
 func errRecover(errp *error) {
 	e := recover()
@@ -239,10 +718,16 @@ func errRecover(errp *error) {
 }
 
 // PrintAST returns string representation of parsed template.
-func (tpl *Template) PrintAST() string {
+//
+// The multi-line ast.FormatVerbose format is used by default, another one can be given, like ast.FormatCompact.
+func (tpl *Template) PrintAST(format ...ast.PrintFormat) string {
 	if err := tpl.parse(); err != nil {
 		return fmt.Sprintf("PARSER ERROR: %s", err)
 	}
 
+	if len(format) > 0 {
+		return ast.PrintFormatted(tpl.program, format[0])
+	}
+
 	return ast.Print(tpl.program)
 }
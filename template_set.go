@@ -0,0 +1,288 @@
+package raymond
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TemplateSet is a collection of named templates that share helpers and partials.
+//
+// Templates of a set can reference each other as partials by name, like `{{> layouts/header}}`.
+type TemplateSet struct {
+	templates map[string]*Template
+
+	// templates of the set, as partials
+	members map[string]*partial
+
+	helpers  map[string]reflect.Value
+	partials map[string]*partial
+
+	// source files of templates parsed from files
+	files  map[string]*templateFile
+	reload bool
+
+	mutex       sync.RWMutex // protects templates, files, helpers and partials
+	reloadMutex sync.Mutex   // serializes reloads
+}
+
+// templateFile is the source file of a template of a set
+type templateFile struct {
+	modTime time.Time
+	size    int64
+
+	stat func() (os.FileInfo, error)
+	read func() ([]byte, error)
+}
+
+// NewTemplateSet instanciates a new empty template set.
+func NewTemplateSet() *TemplateSet {
+	return &TemplateSet{
+		templates: make(map[string]*Template),
+		members:   make(map[string]*partial),
+		helpers:   make(map[string]reflect.Value),
+		partials:  make(map[string]*partial),
+		files:     make(map[string]*templateFile),
+	}
+}
+
+// Parse parses given source and adds resulting template to the set with given name.
+//
+// A template previously added with the same name is replaced.
+func (set *TemplateSet) Parse(name string, source string) (*Template, error) {
+	return set.parse(name, source, nil)
+}
+
+// ParseFile reads given file, parses it, and adds resulting template to the set with given name.
+//
+// A template previously added with the same name is replaced.
+func (set *TemplateSet) ParseFile(name string, filePath string) (*Template, error) {
+	return set.parseFile(name, &templateFile{
+		stat: func() (os.FileInfo, error) { return os.Stat(filePath) },
+		read: func() ([]byte, error) { return ioutil.ReadFile(filePath) },
+	})
+}
+
+// parseFile reads and parses given template file, and adds resulting template to the set with given name
+func (set *TemplateSet) parseFile(name string, file *templateFile) (*Template, error) {
+	info, err := file.stat()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := file.read()
+	if err != nil {
+		return nil, err
+	}
+
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	return set.parse(name, string(b), file)
+}
+
+// parse parses given source and adds resulting template to the set with given name, with given source file if any
+func (set *TemplateSet) parse(name string, source string, file *templateFile) (*Template, error) {
+	tpl, err := Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse template %s: %s", name, err)
+	}
+
+	set.add(name, tpl, file)
+
+	return tpl, nil
+}
+
+// Add adds given template to the set with given name.
+//
+// A template previously added with the same name is replaced. Given template must not belong to another set.
+func (set *TemplateSet) Add(name string, tpl *Template) {
+	set.add(name, tpl, nil)
+}
+
+// add adds given template to the set with given name, with given source file if any
+func (set *TemplateSet) add(name string, tpl *Template, file *templateFile) {
+	tpl.name = name
+	tpl.set = set
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	set.templates[name] = tpl
+	set.members[name] = newPartial(name, "", tpl)
+
+	if file != nil {
+		set.files[name] = file
+	} else {
+		delete(set.files, name)
+	}
+}
+
+// MustParse parses given source and adds resulting template to the set with given name. It panics on error.
+func (set *TemplateSet) MustParse(name string, source string) *Template {
+	result, err := set.Parse(name, source)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// Lookup returns the template of the set with given name, or nil if not found.
+func (set *TemplateSet) Lookup(name string) *Template {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+
+	return set.templates[name]
+}
+
+// Names returns sorted names of templates of the set.
+func (set *TemplateSet) Names() []string {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+
+	return partialNames(set.members)
+}
+
+// RegisterHelper registers a helper that is available to all templates of the set.
+func (set *TemplateSet) RegisterHelper(name string, helper interface{}) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	if set.helpers[name] != zero {
+		panic(fmt.Errorf("Helper already registered: %s", name))
+	}
+
+	val := reflect.ValueOf(helper)
+	ensureValidHelper(name, val)
+
+	set.helpers[name] = val
+}
+
+// RegisterHelpers registers several helpers that are available to all templates of the set.
+func (set *TemplateSet) RegisterHelpers(helpers map[string]interface{}) {
+	for name, helper := range helpers {
+		set.RegisterHelper(name, helper)
+	}
+}
+
+// RegisterPartial registers a partial that is available to all templates of the set.
+func (set *TemplateSet) RegisterPartial(name string, source string) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	if set.partials[name] != nil {
+		panic(fmt.Errorf("Partial already registered: %s", name))
+	}
+
+	set.partials[name] = newPartial(name, source, nil)
+}
+
+// RegisterPartials registers several partials that are available to all templates of the set.
+func (set *TemplateSet) RegisterPartials(partials map[string]string) {
+	for name, source := range partials {
+		set.RegisterPartial(name, source)
+	}
+}
+
+// SetAutoReload sets if templates parsed from files are parsed again when their file changed, before executing a
+// template of the set.
+//
+// All files of the set are checked before each execution, as templates include each other as partials, and an error is
+// returned if a changed file fails to be parsed again. Helpers and partials must be registered on the set, as those
+// registered on a reloaded template are lost. Default is false, so that files are never checked. It is meant for
+// development, and it must be called before executing templates.
+func (set *TemplateSet) SetAutoReload(reload bool) {
+	set.reload = reload
+}
+
+// Exec evaluates the template of the set with given name, with given context.
+func (set *TemplateSet) Exec(name string, ctx interface{}) (string, error) {
+	if set.reload {
+		if err := set.reloadFiles(); err != nil {
+			return "", err
+		}
+	}
+
+	tpl := set.Lookup(name)
+	if tpl == nil {
+		return "", fmt.Errorf("Template not found: %s", name)
+	}
+
+	return tpl.Exec(ctx)
+}
+
+// ExecTo evaluates the template of the set with given name, with given context, and writes the result to given writer.
+func (set *TemplateSet) ExecTo(w io.Writer, name string, ctx interface{}) error {
+	result, err := set.Exec(name, ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, result)
+
+	return err
+}
+
+// reloadFiles parses again templates whose source file changed
+func (set *TemplateSet) reloadFiles() error {
+	set.reloadMutex.Lock()
+	defer set.reloadMutex.Unlock()
+
+	set.mutex.RLock()
+	files := make(map[string]*templateFile, len(set.files))
+	for name, file := range set.files {
+		files[name] = file
+	}
+	set.mutex.RUnlock()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		file := files[name]
+
+		info, err := file.stat()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().Equal(file.modTime) && (info.Size() == file.size) {
+			continue
+		}
+
+		// a template that fails to be parsed is not updated, so that it is parsed again on next execution
+		if _, err := set.parseFile(name, &templateFile{stat: file.stat, read: file.read}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findHelper finds a helper registered on the set
+func (set *TemplateSet) findHelper(name string) reflect.Value {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+
+	return set.helpers[name]
+}
+
+// findPartial finds a partial registered on the set, or else a template of the set
+func (set *TemplateSet) findPartial(name string) *partial {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+
+	if p := set.partials[name]; p != nil {
+		return p
+	}
+
+	return set.members[name]
+}
@@ -0,0 +1,45 @@
+//go:build go1.16
+// +build go1.16
+
+package raymond
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ParseFS parses the files of given file system that match given patterns, and adds resulting templates to the set.
+//
+// Patterns use the fs.Glob() syntax. Each template is named with its file path without extension, so that the
+// `pages/home.hbs` file is executed with `set.Exec("pages/home", ctx)` and included with `{{> pages/home}}`.
+//
+// With auto reload, files are checked with their modification time in given file system.
+func (set *TemplateSet) ParseFS(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		filePaths, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+
+		if len(filePaths) == 0 {
+			return fmt.Errorf("Pattern matches no files: %s", pattern)
+		}
+
+		for _, filePath := range filePaths {
+			filePath := filePath
+
+			file := &templateFile{
+				stat: func() (fs.FileInfo, error) { return fs.Stat(fsys, filePath) },
+				read: func() ([]byte, error) { return fs.ReadFile(fsys, filePath) },
+			}
+
+			if _, err := set.parseFile(strings.TrimSuffix(filePath, path.Ext(filePath)), file); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
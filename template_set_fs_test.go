@@ -0,0 +1,36 @@
+//go:build go1.16
+// +build go1.16
+
+package raymond
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplateSetParseFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"templates/layouts/header.hbs": {Data: []byte(`<h1>{{title}}</h1>`)},
+		"templates/pages/home.hbs":     {Data: []byte(`{{> templates/layouts/header}}<p>home</p>`)},
+		"templates/README.md":          {Data: []byte(`not a template`)},
+	}
+
+	set := NewTemplateSet()
+	if err := set.ParseFS(fsys, "templates/*/*.hbs"); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := set.Exec("templates/pages/home", map[string]string{"title": "Home"}); (err != nil) || (result != "<h1>Home</h1><p>home</p>") {
+		t.Errorf("Failed to execute template parsed from file system: %q %v", result, err)
+	}
+
+	if set.Lookup("templates/README") != nil {
+		t.Errorf("Files that do not match patterns must not be parsed")
+	}
+
+	if err := set.ParseFS(fsys, "*.hbs"); err == nil {
+		t.Errorf("A pattern that matches no files must fail")
+	}
+}
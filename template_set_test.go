@@ -0,0 +1,187 @@
+package raymond
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTemplateSet(t *testing.T) {
+	t.Parallel()
+
+	set := NewTemplateSet()
+	set.MustParse("layouts/header", `<h1>{{upper title}}</h1>`)
+	set.MustParse("pages/home", `{{> layouts/header}}{{#each posts}}{{> post}}{{/each}}{{> footer}}`)
+	set.RegisterPartial("post", `<p>{{shout this}}</p>`)
+	set.RegisterPartial("footer", `<footer>{{year}}</footer>`)
+
+	// helpers registered after parsing apply to all templates
+	set.RegisterHelpers(map[string]interface{}{
+		"upper": strings.ToUpper,
+		"shout": func(str string) string { return str + "!" },
+	})
+
+	ctx := map[string]interface{}{
+		"title": "home",
+		"posts": []string{"foo", "bar"},
+		"year":  2016,
+	}
+
+	expected := "<h1>HOME</h1><p>foo!</p><p>bar!</p><footer>2016</footer>"
+
+	if result, err := set.Exec("pages/home", ctx); (err != nil) || (result != expected) {
+		t.Errorf("Failed to execute template of set: %q %v", result, err)
+	}
+
+	var buf bytes.Buffer
+	if err := set.ExecTo(&buf, "layouts/header", ctx); (err != nil) || (buf.String() != "<h1>HOME</h1>") {
+		t.Errorf("Failed to execute template of set to writer: %q %v", buf.String(), err)
+	}
+
+	if names := set.Names(); fmt.Sprint(names) != "[layouts/header pages/home]" {
+		t.Errorf("Failed to list template names: %q", names)
+	}
+
+	if (set.Lookup("pages/home") == nil) || (set.Lookup("post") != nil) {
+		t.Errorf("Failed to lookup templates of set")
+	}
+
+	if _, err := set.Exec("unknown", ctx); (err == nil) || (err.Error() != "Template not found: unknown") {
+		t.Errorf("Executing an unknown template must fail: %v", err)
+	}
+
+	// templates can be replaced
+	set.MustParse("layouts/header", `<h2>{{title}}</h2>`)
+
+	if result, err := set.Exec("pages/home", ctx); (err != nil) || (result != "<h2>home</h2><p>foo!</p><p>bar!</p><footer>2016</footer>") {
+		t.Errorf("Failed to replace template of set: %q %v", result, err)
+	}
+}
+
+func TestTemplateSetPrecedence(t *testing.T) {
+	t.Parallel()
+
+	set := NewTemplateSet()
+	set.RegisterHelper("name", func() string { return "set" })
+	set.RegisterPartial("item", "set partial")
+	set.MustParse("item", "set template")
+
+	tpl := set.MustParse("page", `{{name}} - {{> item}}`)
+
+	if result := tpl.MustExec(nil); result != "set - set partial" {
+		t.Errorf("Set partials must have precedence over set templates: %q", result)
+	}
+
+	tpl.RegisterHelper("name", func() string { return "template" })
+	tpl.RegisterPartial("item", "template partial")
+
+	if result := tpl.MustExec(nil); result != "template - template partial" {
+		t.Errorf("Template helpers and partials must have precedence over set ones: %q", result)
+	}
+
+	if result := tpl.Clone().MustExec(nil); result != "template - template partial" {
+		t.Errorf("Cloned template must keep its set: %q", result)
+	}
+}
+
+func TestTemplateSetParseError(t *testing.T) {
+	t.Parallel()
+
+	set := NewTemplateSet()
+
+	if _, err := set.Parse("broken", "{{foo"); (err == nil) || !strings.HasPrefix(err.Error(), "Failed to parse template broken: ") {
+		t.Errorf("Parsing an invalid template must fail: %v", err)
+	}
+
+	if set.Lookup("broken") != nil {
+		t.Errorf("Invalid template must not be added to set")
+	}
+}
+
+func TestTemplateSetAutoReload(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "raymond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	modTime := time.Now().Add(-time.Hour)
+
+	// writeFile writes given content to a file of the set, with a new modification time
+	writeFile := func(name string, content string) string {
+		filePath := filepath.Join(dir, name+".hbs")
+		if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		return filePath
+	}
+
+	set := NewTemplateSet()
+	set.MustParse("page", `<p>{{> header}}</p>`)
+	if _, err := set.ParseFile("header", writeFile("header", "{{title}}")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := map[string]string{"title": "foo"}
+
+	// reload disabled
+	writeFile("header", "<b>{{title}}</b>")
+
+	if result, err := set.Exec("page", ctx); (err != nil) || (result != "<p>foo</p>") {
+		t.Errorf("Template must not be reloaded when auto reload is disabled: %q %v", result, err)
+	}
+
+	// reload enabled
+	set.SetAutoReload(true)
+
+	if result, err := set.Exec("page", ctx); (err != nil) || (result != "<p><b>foo</b></p>") {
+		t.Errorf("Failed to reload template included as a partial: %q %v", result, err)
+	}
+
+	// parse error
+	writeFile("header", "{{title")
+
+	for i := 0; i < 2; i++ {
+		if _, err := set.Exec("page", ctx); (err == nil) || !strings.HasPrefix(err.Error(), "Failed to parse template header: ") {
+			t.Errorf("Reload parse error must be returned: %v", err)
+		}
+	}
+
+	// fixed
+	writeFile("header", "<i>{{title}}</i>")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if result, err := set.Exec("page", ctx); (err != nil) || (result != "<p><i>foo</i></p>") {
+				t.Errorf("Failed to reload fixed template: %q %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a template parsed from a source is not reloaded anymore
+	set.MustParse("header", "{{title}}!")
+	writeFile("header", "changed")
+
+	if result, err := set.Exec("page", ctx); (err != nil) || (result != "<p>foo!</p>") {
+		t.Errorf("Template parsed from a source must not be reloaded: %q %v", result, err)
+	}
+}
@@ -2,7 +2,11 @@ package raymond
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/aymerick/raymond/ast"
 )
 
 var sourceBasic = `<div class="entry">
@@ -46,6 +50,36 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseLenient(t *testing.T) {
+	t.Parallel()
+
+	source := `{{link a=1 a=2}}`
+
+	if _, err := Parse(source); err == nil {
+		t.Errorf("Duplicate hash keys must be rejected")
+	}
+
+	tpl, err := ParseLenient(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl.RegisterHelper("link", func(options *Options) string {
+		return options.HashStr("a")
+	})
+
+	// last value wins
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if output := tpl.MustExec(nil); output != "2" {
+			t.Errorf("Unexpected output: %q", output)
+		}
+	}
+
+	if warnings := tpl.Warnings(); (len(warnings) != 1) || (warnings[0].Code != WarnDuplicateHashKey) {
+		t.Errorf("Duplicate hash key must be reported as a warning: %v", warnings)
+	}
+}
+
 func TestClone(t *testing.T) {
 	t.Parallel()
 
@@ -76,6 +110,384 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestParseNamed(t *testing.T) {
+	t.Parallel()
+
+	tpl, err := ParseNamed("test_parse_named", sourceBasic)
+	if err != nil {
+		t.Fatalf("Failed to parse named template: %s", err)
+	}
+
+	if Lookup("test_parse_named") != tpl {
+		t.Errorf("Failed to lookup named template")
+	}
+
+	if Lookup("test_parse_named_unknown") != nil {
+		t.Errorf("Lookup of an unknown template must return nil")
+	}
+
+	if _, err := ParseNamed("test_parse_named_error", "{{#foo}}"); err == nil {
+		t.Errorf("Parsing an erroneous named template must fail")
+	}
+
+	if Lookup("test_parse_named_error") != nil {
+		t.Errorf("An erroneous named template must not be cached")
+	}
+}
+
+func TestParseNamedConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("test_parse_named_%d", i)
+			if _, err := ParseNamed(name, sourceBasic); err != nil {
+				t.Errorf("Failed to parse named template: %s", err)
+			}
+
+			if Lookup(name) == nil {
+				t.Errorf("Failed to lookup named template: %s", name)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestSetHelperPrecedence(t *testing.T) {
+	t.Parallel()
+
+	source := `{{foo}} {{#foo}}{{bar}}{{/foo}}`
+
+	ctx := map[string]interface{}{
+		"foo": map[string]string{"bar": "field"},
+	}
+
+	helper := func(options *Options) string {
+		return "helper"
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterHelper("foo", helper)
+
+	if result := tpl.MustExec(ctx); result != "helper helper" {
+		t.Errorf("Helper must be resolved first by default: %q", result)
+	}
+
+	tpl.SetHelperPrecedence(FieldFirst)
+
+	if result := tpl.MustExec(ctx); result != "map[bar:field] field" {
+		t.Errorf("Field must be resolved first with FieldFirst precedence: %q", result)
+	}
+
+	if result := tpl.MustExec(nil); result != "helper helper" {
+		t.Errorf("Helper must be resolved if field is missing with FieldFirst precedence: %q", result)
+	}
+
+	tpl.SetHelperPrecedence(HelperFirst)
+
+	if result := tpl.MustExec(ctx); result != "helper helper" {
+		t.Errorf("Helper must be resolved first with HelperFirst precedence: %q", result)
+	}
+}
+
+var helperPrecedenceTests = []struct {
+	name       string
+	input      string
+	precedence HelperPrecedence
+	output     string
+}{
+	{"helper wins by default", `{{title}}`, HelperFirst, "helper"},
+	{"scoped path is a field", `{{./title}}`, HelperFirst, "field"},
+	{"this path is a field", `{{this.title}}`, HelperFirst, "field"},
+	{"helper with params", `{{title "foo"}}`, HelperFirst, "helper foo"},
+	{"field wins with FieldFirst", `{{title}}`, FieldFirst, "field"},
+	{"helper with params and FieldFirst", `{{title "foo"}}`, FieldFirst, "helper foo"},
+	{"helper with hash and FieldFirst", `{{title suffix="foo"}}`, FieldFirst, "helper foo"},
+	{"block helper with params and FieldFirst", `{{#title "foo"}}{{/title}}`, FieldFirst, "helper foo"},
+	{"scoped path is a field with FieldFirst", `{{./title}}`, FieldFirst, "field"},
+}
+
+func TestHelperPrecedenceResolution(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]string{"title": "field"}
+
+	helper := VariadicHelper(func(options *Options) interface{} {
+		suffix := options.ParamStr(0)
+		if suffix == "" {
+			suffix = options.HashStr("suffix")
+		}
+
+		if suffix == "" {
+			return "helper"
+		}
+
+		return "helper " + suffix
+	})
+
+	for _, test := range helperPrecedenceTests {
+		tpl := MustParse(test.input)
+		tpl.RegisterHelper("title", helper)
+		tpl.SetHelperPrecedence(test.precedence)
+
+		for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+			if output := tpl.MustExec(ctx); output != test.output {
+				t.Errorf("Test '%s' failed\ninput:\n\t%s\nexpected\n\t%q\ngot\n\t%q", test.name, test.input, test.output, output)
+			}
+		}
+	}
+}
+
+func TestExecAll(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{name}}: {{check name}}`)
+	tpl.RegisterHelper("check", func(name string) string {
+		switch name {
+		case "error":
+			panic(fmt.Errorf("check failed"))
+		case "panic":
+			panic("check panicked")
+		case "runtime":
+			var m map[string]string
+			m["foo"] = "bar"
+		}
+		return "ok"
+	})
+
+	ctxList := []interface{}{
+		map[string]string{"name": "foo"},
+		map[string]string{"name": "error"},
+		map[string]string{"name": "panic"},
+		map[string]string{"name": "runtime"},
+		map[string]string{"name": "bar"},
+	}
+
+	results, errs := tpl.ExecAll(ctxList)
+
+	if (len(results) != len(ctxList)) || (len(errs) != len(ctxList)) {
+		t.Fatalf("ExecAll must return a result and an error per context: %d results, %d errors", len(results), len(errs))
+	}
+
+	if (results[0] != "foo: ok") || (errs[0] != nil) {
+		t.Errorf("Failed to render first context: %q, %v", results[0], errs[0])
+	}
+
+	if (results[4] != "bar: ok") || (errs[4] != nil) {
+		t.Errorf("Failed to render last context: %q, %v", results[4], errs[4])
+	}
+
+	for i, expected := range []string{"check failed", "check panicked", "assignment to entry in nil map"} {
+		if err := errs[i+1]; (err == nil) || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Context %d must fail with error %q: %v", i+1, expected, err)
+		}
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{title}} - {{siteName}} {{year}}{{#each posts}} [{{title}} - {{siteName}}]{{/each}}`)
+	tpl.SetDefaults(map[string]interface{}{
+		"siteName": "My Site",
+		"year":     2015,
+		"title":    "Default Title",
+	})
+
+	ctx := map[string]interface{}{
+		"title": "Home",
+		"year":  2016,
+		"posts": []map[string]string{{"title": "Post"}},
+	}
+
+	if result := tpl.MustExec(ctx); result != "Home - My Site 2016 [Post - My Site]" {
+		t.Errorf("Failed to resolve defaults: %q", result)
+	}
+
+	if result := tpl.MustExec(nil); result != "Default Title - My Site 2015" {
+		t.Errorf("Failed to resolve defaults without context: %q", result)
+	}
+}
+
+func TestSetMissingFunc(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{title}} {{empty}} {{author.name}} {{{raw}}} {{@unknown}} {{upper missing}} {{#each posts}}{{title}}{{/each}}`)
+	tpl.RegisterHelper("upper", strings.ToUpper)
+	tpl.SetMissingFunc(func(path string) string {
+		return "<missing:" + path + ">"
+	})
+
+	ctx := map[string]interface{}{
+		"empty": "",
+		"posts": []map[string]string{{"title": "Post"}, {}},
+	}
+
+	expected := "&lt;missing:title&gt;  &lt;missing:author.name&gt; <missing:raw> &lt;missing:@unknown&gt;  Post&lt;missing:title&gt;"
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		if result := tpl.MustExec(ctx); result != expected {
+			t.Errorf("Failed to render missing values\nexpected\n\t%q\ngot\n\t%q", expected, result)
+		}
+	}
+}
+
+func TestOutputSize(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each items}}{{this}}{{/each}}`)
+
+	if size := tpl.outputSize(); size != len(tpl.source) {
+		t.Errorf("Output size must be source size before first rendering: %d", size)
+	}
+
+	result := tpl.MustExec(map[string]interface{}{"items": []string{"foo", "bar", "baz"}})
+	if result != "foobarbaz" {
+		t.Errorf("Failed to render template: %q", result)
+	}
+
+	if size := tpl.outputSize(); size != len(result) {
+		t.Errorf("Output size must be last rendering size: %d", size)
+	}
+
+	huge := make([]string, maxPooledBufferSize)
+	for i := range huge {
+		huge[i] = "a"
+	}
+
+	if result := tpl.MustExec(map[string]interface{}{"items": huge}); len(result) != len(huge) {
+		t.Errorf("Failed to render huge template: %d", len(result))
+	}
+
+	if size := tpl.outputSize(); size != maxPooledBufferSize {
+		t.Errorf("Output size must be capped after a huge rendering: %d", size)
+	}
+
+	tpl.SetOutputSizeHint(1)
+
+	if size := tpl.outputSize(); size != 1 {
+		t.Errorf("Output size must be size hint: %d", size)
+	}
+
+	if result := tpl.MustExec(map[string]interface{}{"items": []string{"foo", "bar"}}); result != "foobar" {
+		t.Errorf("Failed to render template with a small size hint: %q", result)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	source := `{{#if items}}{{#each items}}{{@index}}:{{name}} {{/each}}{{else}}none{{/if}}{{#with author}} by {{name}}{{/with}}`
+
+	ctx := map[string]interface{}{
+		"items":  []map[string]string{{"name": "foo"}, {"name": "<bar>"}},
+		"author": map[string]string{"name": "Jean"},
+	}
+
+	tpl := MustParse(source)
+	expected := tpl.MustExec(ctx)
+
+	compiled := tpl.Clone().MustCompile()
+
+	if result := compiled.MustExec(ctx); result != expected {
+		t.Errorf("Compiled template must render %q, got %q", expected, result)
+	}
+
+	if result := compiled.MustExec(nil); result != "none" {
+		t.Errorf("Compiled template must render else block: %q", result)
+	}
+
+	// helpers are resolved at execution time
+	compiled.RegisterHelper("each", func(context interface{}, options *Options) string {
+		return "each"
+	})
+
+	if result := compiled.MustExec(ctx); result != "each by Jean" {
+		t.Errorf("Compiled template must use helpers registered after compilation: %q", result)
+	}
+}
+
+func TestCompileConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{#each items}}{{this}}{{/each}}`)
+	ctx := map[string][]int{"items": {1, 2, 3}}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			if output := tpl.MustExec(ctx); output != "123" {
+				t.Errorf("Unexpected output: %q", output)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			tpl.MustCompile()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestFluentRegistration(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse(`{{upper title}} {{> byline}} {{#bold}}{{name}}{{/bold}}`).
+		WithHelper("upper", strings.ToUpper).
+		WithHelpers(map[string]interface{}{
+			"bold": func(options *Options) SafeString {
+				return SafeString("<b>" + options.Fn() + "</b>")
+			},
+		}).
+		WithPartial("byline", "by {{name}}")
+
+	ctx := map[string]string{"title": "foo", "name": "bar"}
+
+	if result := tpl.MustExec(ctx); result != "FOO by bar <b>bar</b>" {
+		t.Errorf("Failed to render template with fluent registration: %q", result)
+	}
+
+	// duplicate names panic
+	for name, register := range map[string]func(){
+		"helper":  func() { tpl.WithHelper("upper", strings.ToLower) },
+		"helpers": func() { tpl.WithHelpers(map[string]interface{}{"bold": strings.ToLower}) },
+		"partial": func() { tpl.WithPartial("byline", "foo") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Registering a duplicate %s must panic", name)
+				}
+			}()
+
+			register()
+		}()
+	}
+}
+
+func ExampleTemplate_WithHelper() {
+	tpl := MustParse(`{{upper title}} {{> byline}}`).
+		WithHelper("upper", strings.ToUpper).
+		WithPartial("byline", "by {{author}}")
+
+	fmt.Print(tpl.MustExec(map[string]string{"title": "foo", "author": "bar"}))
+	// Output: FOO by bar
+}
+
 func ExampleTemplate_Exec() {
 	source := "<h1>{{title}}</h1><p>{{body.content}}</p>"
 
@@ -164,3 +576,16 @@ func ExampleTemplate_PrintAST() {
 	//   CONTENT[ '</p>' ]
 	//
 }
+
+func ExampleTemplate_PrintAST_compact() {
+	source := "<h1>{{title}}</h1>{{#each items as |item|}}{{link item.url class=\"big\"}}{{/each}}"
+
+	// parse template
+	tpl := MustParse(source)
+
+	// print AST on a single line
+	output := tpl.PrintAST(ast.FormatCompact)
+
+	fmt.Print(output)
+	// Output: (program (content "<h1>") (mustache (title)) (content "</h1>") (block (each items) (program |item| (mustache (link item.url class="big")))))
+}
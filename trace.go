@@ -0,0 +1,79 @@
+package raymond
+
+import (
+	"time"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// TracePhase tells if a trace event is reported when a node evaluation starts or ends.
+type TracePhase int
+
+const (
+	// TraceEnter is reported before a node is evaluated.
+	TraceEnter TracePhase = iota
+
+	// TraceExit is reported after a node was evaluated.
+	TraceExit
+)
+
+// TraceEvent is reported to the trace hook of a template when the evaluation of a mustache, a block or a partial starts
+// and ends.
+type TraceEvent struct {
+	Phase TracePhase
+
+	// evaluated node: ast.NodeMustache, ast.NodeBlock or ast.NodePartial
+	Node ast.NodeType
+
+	// helper or path name of a mustache or a block, or partial name
+	Name string
+
+	// node position in template source
+	Line int
+	Pos  int
+
+	// nesting of traced nodes, starting at 0
+	Depth int
+
+	// wall time of event
+	Time time.Time
+
+	// evaluation time of node, for TraceExit events only
+	Duration time.Duration
+}
+
+// TraceHook is a function that receives trace events.
+type TraceHook func(event TraceEvent)
+
+// traceEnter reports the start of given node evaluation, and returns the event to be reported by traceExit()
+func (v *evalVisitor) traceEnter(node ast.Node, name string) TraceEvent {
+	loc := node.Location()
+
+	event := TraceEvent{
+		Phase: TraceEnter,
+		Node:  node.Type(),
+		Name:  name,
+		Line:  loc.Line,
+		Pos:   loc.Pos,
+		Depth: v.traceDepth,
+		Time:  time.Now(),
+	}
+
+	v.traceDepth++
+	v.trace(event)
+
+	return event
+}
+
+// traceExit reports the end of a node evaluation started with given event
+func (v *evalVisitor) traceExit(event TraceEvent) {
+	v.traceDepth--
+
+	now := time.Now()
+
+	event.Phase = TraceExit
+	event.Duration = now.Sub(event.Time)
+	event.Time = now
+
+	v.trace(event)
+}
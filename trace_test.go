@@ -0,0 +1,115 @@
+package raymond
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+func TestTraceHook(t *testing.T) {
+	t.Parallel()
+
+	source := "{{title}}\n{{#each items}}{{> item}}{{/each}}"
+
+	expected := []string{
+		"enter mustache title line:1 depth:0",
+		"exit mustache title line:1 depth:0",
+		"enter block each line:2 depth:0",
+		"enter partial item line:2 depth:1",
+		"enter mustache name line:1 depth:2",
+		"exit mustache name line:1 depth:2",
+		"exit partial item line:2 depth:1",
+		"enter partial item line:2 depth:1",
+		"enter mustache name line:1 depth:2",
+		"exit mustache name line:1 depth:2",
+		"exit partial item line:2 depth:1",
+		"exit block each line:2 depth:0",
+	}
+
+	ctx := map[string]interface{}{
+		"title": "foo",
+		"items": []map[string]string{{"name": "bar"}, {"name": "baz"}},
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterPartial("item", "{{name}}")
+
+	for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+		var events []TraceEvent
+
+		tpl.SetTraceHook(func(event TraceEvent) {
+			events = append(events, event)
+		})
+
+		if output := tpl.MustExec(ctx); output != "foo\nbarbaz" {
+			t.Errorf("Traced template output is wrong: %q", output)
+		}
+
+		var traced []string
+		for _, event := range events {
+			traced = append(traced, traceEventStr(event))
+		}
+
+		if fmt.Sprint(traced) != fmt.Sprint(expected) {
+			t.Errorf("Wrong trace events\nexpected:\n\t%q\ngot:\n\t%q", expected, traced)
+			continue
+		}
+
+		// check timing fields
+		var stack []TraceEvent
+		for _, event := range events {
+			if event.Time.IsZero() {
+				t.Errorf("Trace event time is not set: %+v", event)
+			}
+
+			if event.Phase == TraceEnter {
+				stack = append(stack, event)
+				continue
+			}
+
+			enter := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if event.Time.Before(enter.Time) || (event.Duration != event.Time.Sub(enter.Time)) {
+				t.Errorf("Trace exit event timing is wrong: %+v, enter event: %+v", event, enter)
+			}
+		}
+	}
+}
+
+func TestTraceHookDisabled(t *testing.T) {
+	t.Parallel()
+
+	called := false
+
+	tpl := MustParse("{{foo}}")
+	tpl.SetTraceHook(func(event TraceEvent) { called = true })
+	tpl.SetTraceHook(nil)
+
+	tpl.MustExec(map[string]string{"foo": "bar"})
+
+	if called {
+		t.Errorf("Trace hook was called after being disabled")
+	}
+}
+
+// traceEventStr returns a short string representation of given trace event
+func traceEventStr(event TraceEvent) string {
+	phase := "enter"
+	if event.Phase == TraceExit {
+		phase = "exit"
+	}
+
+	node := ""
+	switch event.Node {
+	case ast.NodeMustache:
+		node = "mustache"
+	case ast.NodeBlock:
+		node = "block"
+	case ast.NodePartial:
+		node = "partial"
+	}
+
+	return fmt.Sprintf("%s %s %s line:%d depth:%d", phase, node, event.Name, event.Line, event.Depth)
+}
@@ -0,0 +1,28 @@
+package raymond
+
+import "sync"
+
+// Translator translates given key, with given interpolation params. It is called by the `t` helper.
+type Translator func(key string, params map[string]interface{}) string
+
+// translator is the globally registered translator
+var translator Translator
+
+// protects global translator
+var translatorMutex sync.RWMutex
+
+// RegisterTranslator registers the global translator used by the `t` helper. Registering a nil translator removes it.
+func RegisterTranslator(fn Translator) {
+	translatorMutex.Lock()
+	defer translatorMutex.Unlock()
+
+	translator = fn
+}
+
+// findTranslator returns the globally registered translator
+func findTranslator() Translator {
+	translatorMutex.RLock()
+	defer translatorMutex.RUnlock()
+
+	return translator
+}
@@ -0,0 +1,60 @@
+package raymond
+
+import (
+	"fmt"
+	"testing"
+)
+
+func frenchTranslator(key string, params map[string]interface{}) string {
+	switch key {
+	case "cart.items":
+		return fmt.Sprintf("%s articles", Str(params["count"]))
+	case "link.title":
+		return "Voir le panier"
+	}
+
+	return key
+}
+
+func TestTranslateHelper(t *testing.T) {
+	t.Parallel()
+
+	source := `{{t "cart.items" count=cart.len}} <a title="{{t "link.title"}}">{{t (concat "link." "title")}}</a>`
+
+	ctx := map[string]interface{}{
+		"cart": map[string]int{"len": 3},
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterHelper("concat", func(a, b string) string { return a + b })
+
+	if result := tpl.MustExec(ctx); result != `cart.items <a title="link.title">link.title</a>` {
+		t.Errorf("Translate helper must return the key without translator: %q", result)
+	}
+
+	tpl.RegisterTranslator(frenchTranslator)
+
+	if result := tpl.MustExec(ctx); result != `3 articles <a title="Voir le panier">Voir le panier</a>` {
+		t.Errorf("Failed to translate with template translator: %q", result)
+	}
+}
+
+func TestRegisterTranslator(t *testing.T) {
+	RegisterTranslator(frenchTranslator)
+	defer RegisterTranslator(nil)
+
+	source := `{{t "link.title"}}`
+
+	if result := MustRender(source, nil); result != "Voir le panier" {
+		t.Errorf("Failed to translate with global translator: %q", result)
+	}
+
+	tpl := MustParse(source)
+	tpl.RegisterTranslator(func(key string, params map[string]interface{}) string {
+		return "View cart"
+	})
+
+	if result := tpl.MustExec(nil); result != "View cart" {
+		t.Errorf("Template translator must override global translator: %q", result)
+	}
+}
@@ -0,0 +1,135 @@
+package raymond
+
+import (
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+// blockTrimmer strips whitespaces around block tags, like the `trim_blocks` and `lstrip_blocks` options of Jinja
+type blockTrimmer struct {
+	source string
+
+	// lstrip pass if true, trim pass otherwise
+	lstrip bool
+}
+
+// trimBlocks strips whitespaces around block tags of given program
+//
+// With trim, the first newline after a block tag is removed. With lstrip, spaces and tabs from the start of a line to a
+// block tag are removed.
+//
+// WARNING: It must be called only once on AST.
+func trimBlocks(program *ast.Program, source string, trim bool, lstrip bool) {
+	// lstrip must see contents before their leading newline is trimmed
+	if lstrip {
+		t := &blockTrimmer{source: source, lstrip: true}
+		t.program(program, true)
+	}
+
+	if trim {
+		t := &blockTrimmer{source: source}
+		t.program(program, true)
+	}
+}
+
+// program processes tags of all blocks in given program
+func (t *blockTrimmer) program(program *ast.Program, root bool) {
+	if program == nil {
+		return
+	}
+
+	for i, node := range program.Body {
+		block, ok := node.(*ast.BlockStatement)
+		if !ok {
+			continue
+		}
+
+		// the open tag of a chained block is the `{{else if}}` tag of its parent
+		if !program.Chained || (i > 0) {
+			if i > 0 {
+				// before open tag
+				t.before(program.Body[i-1], root && (i == 1))
+			}
+
+			if i+1 < len(program.Body) {
+				// after close tag
+				t.after(program.Body[i+1])
+			}
+		}
+
+		t.block(block)
+	}
+}
+
+// block processes inner tags of given block
+func (t *blockTrimmer) block(block *ast.BlockStatement) {
+	// raw block content is not processed
+	if strings.HasPrefix(t.source[block.Loc.Pos:], "{{{{") {
+		return
+	}
+
+	if block.Program != nil {
+		// after open tag, and before `{{else}}` or close tag
+		t.after(firstNode(block.Program))
+		t.before(lastNode(block.Program), false)
+	}
+
+	if (block.Inverse != nil) && !block.Inverse.Chained {
+		// after `{{else}}` tag, and before close tag
+		t.after(firstNode(block.Inverse))
+		t.before(lastNode(block.Inverse), false)
+	}
+
+	t.program(block.Program, false)
+	t.program(block.Inverse, false)
+}
+
+// before processes given node that precedes a block tag, and that is at start of template if lineStart is true
+func (t *blockTrimmer) before(node ast.Node, lineStart bool) {
+	content, ok := node.(*ast.ContentStatement)
+	if !ok || !t.lstrip {
+		return
+	}
+
+	i := strings.LastIndexByte(content.Value, '\n')
+	if (i == -1) && !lineStart {
+		return
+	}
+
+	if strings.Trim(content.Value[i+1:], " \t") == "" {
+		content.Value = content.Value[:i+1]
+	}
+}
+
+// after processes given node that follows a block tag
+func (t *blockTrimmer) after(node ast.Node) {
+	content, ok := node.(*ast.ContentStatement)
+	if !ok || t.lstrip {
+		return
+	}
+
+	if strings.HasPrefix(content.Value, "\r\n") {
+		content.Value = content.Value[2:]
+	} else if strings.HasPrefix(content.Value, "\n") {
+		content.Value = content.Value[1:]
+	}
+}
+
+// firstNode returns the first statement of given program, or nil if program is empty
+func firstNode(program *ast.Program) ast.Node {
+	if len(program.Body) == 0 {
+		return nil
+	}
+
+	return program.Body[0]
+}
+
+// lastNode returns the last statement of given program, or nil if program is empty
+func lastNode(program *ast.Program) ast.Node {
+	if len(program.Body) == 0 {
+		return nil
+	}
+
+	return program.Body[len(program.Body)-1]
+}
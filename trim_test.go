@@ -0,0 +1,203 @@
+package raymond
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+var trimBlocksTests = []struct {
+	name   string
+	input  string
+	trim   bool
+	lstrip bool
+	output string
+}{
+	{
+		"no trimming",
+		"<ul>{{#each items}}\n  <li>{{this}}</li>\n{{/each}}</ul>\n",
+		false, false,
+		"<ul>\n  <li>a</li>\n\n  <li>b</li>\n</ul>\n",
+	},
+	{
+		"trim blocks",
+		"<ul>{{#each items}}\n  <li>{{this}}</li>\n{{/each}}</ul>\n",
+		true, false,
+		"<ul>  <li>a</li>\n  <li>b</li>\n</ul>\n",
+	},
+	{
+		"trim blocks removes only one newline",
+		"<p>{{#if ok}}\n\nyes{{/if}}\n\nno",
+		true, false,
+		"<p>\nyes\nno",
+	},
+	{
+		"trim blocks with else",
+		"{{#if ok}}yes{{else}}\nno\n{{/if}}!",
+		true, false,
+		"yes!",
+	},
+	{
+		"trim blocks with else if",
+		"{{#if ko}}a{{else if ok}}\nb{{else}}\nc{{/if}}\n!",
+		true, false,
+		"b!",
+	},
+	{
+		"trim blocks with CRLF",
+		"{{#if ok}}\r\nyes{{/if}}",
+		true, false,
+		"yes",
+	},
+	{
+		"lstrip blocks",
+		"<div>\n  {{#if ok}}yes  {{/if}}\n  {{ok}} {{#if ok}}yes{{/if}}</div>",
+		false, true,
+		"<div>\nyes  \n  true yes</div>",
+	},
+	{
+		"lstrip blocks at start of template",
+		"  \t{{#if ok}}yes{{/if}}",
+		false, true,
+		"yes",
+	},
+	{
+		"lstrip blocks with else",
+		"{{#if ko}}no\n  {{else}}yes\n\t{{/if}}",
+		false, true,
+		"yes\n",
+	},
+	{
+		"trim and lstrip blocks",
+		"<ul>{{#each items}}\n  <li>{{this}}</li>\n  {{/each}}\n</ul>",
+		true, true,
+		"<ul>  <li>a</li>\n  <li>b</li>\n</ul>",
+	},
+	{
+		"trim and lstrip blocks between blocks",
+		"{{#if ok}}a{{/if}}\n  {{#if ok}}b{{/if}}",
+		true, true,
+		"ab",
+	},
+	{
+		"standalone blocks",
+		"{{#each items}}\n  {{this}}\n{{/each}}\n",
+		true, true,
+		"  a\n  b\n",
+	},
+	{
+		"raw blocks content is not trimmed",
+		"<p>{{{{raw}}}}\n  {{#if ok}}\n  {{{{/raw}}}}</p>",
+		true, true,
+		"<p>\n  {{#if ok}}\n  </p>",
+	},
+	{
+		"mustaches are not trimmed",
+		"  {{ok}}\n!",
+		true, true,
+		"  true\n!",
+	},
+}
+
+func TestTrimBlocks(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]interface{}{
+		"ok":    true,
+		"ko":    false,
+		"items": []string{"a", "b"},
+	}
+
+	for _, test := range trimBlocksTests {
+		tpl := MustParse(test.input)
+		tpl.RegisterHelper("raw", func(options *Options) string { return options.Fn() })
+		tpl.SetTrimBlocks(test.trim)
+		tpl.SetLstripBlocks(test.lstrip)
+
+		for _, tpl := range []*Template{tpl, tpl.Clone().MustCompile()} {
+			if output := tpl.MustExec(ctx); output != test.output {
+				t.Errorf("Test '%s' failed\ninput:\n\t%q\nexpected\n\t%q\ngot\n\t%q", test.name, test.input, test.output, output)
+			}
+		}
+	}
+}
+
+func TestTrimBlocksReset(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("<p>{{#if ok}}\nyes{{/if}}")
+
+	tpl.SetTrimBlocks(true)
+	if output := tpl.MustExec(map[string]bool{"ok": true}); output != "<p>yes" {
+		t.Errorf("Failed to trim blocks: %q", output)
+	}
+
+	tpl.SetTrimBlocks(false)
+	if output := tpl.MustExec(map[string]bool{"ok": true}); output != "<p>\nyes" {
+		t.Errorf("Failed to disable blocks trimming: %q", output)
+	}
+}
+
+func TestTrimBlocksConcurrentExec(t *testing.T) {
+	t.Parallel()
+
+	tpl := MustParse("<p>{{#if ok}}\nyes{{/if}}")
+	tpl.SetTrimBlocks(true)
+	tpl.SetLstripBlocks(true)
+
+	// template is parsed by the setters, not by concurrent executions
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if output := tpl.MustExec(map[string]bool{"ok": true}); output != "<p>yes" {
+				t.Errorf("Failed to trim blocks: %q", output)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+var crlfTests = []string{
+	"{{#if ok}}\n  yes\n{{else}}\n  no\n{{/if}}\n",
+	"<ul>\n  {{#each items}}\n  <li>{{.}}</li>\n  {{/each}}\n</ul>\n",
+	"a\n  {{~#if ok~}}\n  b\n  {{~/if~}}\n c\n",
+	"{{! comment }}\nfoo\n  {{> item}}\nbar\n",
+	"{{#if ko}}\na\n{{else if ok}}\nb\n{{/if}}\n",
+	"  {{!-- comment --}}  \ntext",
+	"{{#if\n  ok}}\nyes\n{{/if}}\n",
+}
+
+func TestCRLFLineEndings(t *testing.T) {
+	t.Parallel()
+
+	ctx := map[string]interface{}{
+		"ok":    true,
+		"ko":    false,
+		"items": []string{"a", "b"},
+	}
+
+	for _, input := range crlfTests {
+		for _, trim := range []bool{false, true} {
+			lfTpl := MustParse(input)
+			lfTpl.RegisterPartial("item", "<p>\n{{ok}}</p>\n")
+			lfTpl.SetTrimBlocks(trim)
+			lfTpl.SetLstripBlocks(trim)
+
+			crlfTpl := MustParse(strings.Replace(input, "\n", "\r\n", -1))
+			crlfTpl.RegisterPartial("item", "<p>\r\n{{ok}}</p>\r\n")
+			crlfTpl.SetTrimBlocks(trim)
+			crlfTpl.SetLstripBlocks(trim)
+
+			expected := strings.Replace(lfTpl.MustExec(ctx), "\n", "\r\n", -1)
+
+			for _, tpl := range []*Template{crlfTpl, crlfTpl.Clone().MustCompile()} {
+				if output := tpl.MustExec(ctx); output != expected {
+					t.Errorf("CRLF template must render as LF template\ninput:\n\t%q\ntrim:\n\t%v\nexpected\n\t%q\ngot\n\t%q", input, trim, expected, output)
+				}
+			}
+		}
+	}
+}
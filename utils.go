@@ -3,6 +3,10 @@ package raymond
 import (
 	"path"
 	"reflect"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
 )
 
 // indirect returns the item at the end of indirection, and a bool to indicate if it's nil.
@@ -40,6 +44,14 @@ func isTrueValue(val reflect.Value) (truth, ok bool) {
 		// Something like var x interface{}, never set. It's a form of nil.
 		return false, true
 	}
+	if val.Type() == jsonNumberType {
+		if f, ok := numberFloat(val); ok {
+			return f != 0, true
+		}
+	}
+	if m, ok := asOrderedMap(val); ok {
+		return len(m.Keys()) > 0, true
+	}
 	switch val.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		truth = val.Len() > 0
@@ -63,6 +75,75 @@ func isTrueValue(val reflect.Value) (truth, ok bool) {
 	return truth, true
 }
 
+// numberFloat returns the float value of given json.Number or numeric value, with a boolean set to false if given value
+// is not a number
+func numberFloat(val reflect.Value) (float64, bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.String:
+		if val.Type() == jsonNumberType {
+			if f, err := strconv.ParseFloat(val.String(), 64); err == nil {
+				return f, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// integersEqual returns true if given integers are equal, with a boolean set to false if one of given values is not an
+// integer
+func integersEqual(a reflect.Value, b reflect.Value) (bool, bool) {
+	switch {
+	case isIntKind(a.Kind()) && isIntKind(b.Kind()):
+		return a.Int() == b.Int(), true
+	case isUintKind(a.Kind()) && isUintKind(b.Kind()):
+		return a.Uint() == b.Uint(), true
+	case isIntKind(a.Kind()) && isUintKind(b.Kind()):
+		return (a.Int() >= 0) && (uint64(a.Int()) == b.Uint()), true
+	case isUintKind(a.Kind()) && isIntKind(b.Kind()):
+		return (b.Int() >= 0) && (a.Uint() == uint64(b.Int())), true
+	}
+
+	return false, false
+}
+
+// jsonInteger returns the int64 value of given json.Number if it is an integer, and given value otherwise
+func jsonInteger(val reflect.Value) reflect.Value {
+	if val.IsValid() && (val.Type() == jsonNumberType) {
+		if i, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
+			return reflect.ValueOf(i)
+		}
+	}
+
+	return val
+}
+
+// isIntKind returns true if given kind is a signed integer kind
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+
+	return false
+}
+
+// isUintKind returns true if given kind is an unsigned integer kind
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return false
+}
+
 // canBeNil reports whether an untyped nil can be assigned to the type. See reflect.Zero.
 //
 // NOTE: borrowed from https://github.com/golang/go/tree/master/src/text/template/exec.go
@@ -74,6 +155,60 @@ func canBeNil(typ reflect.Type) bool {
 	return false
 }
 
+// lowerFirst returns given string with its first letter lower-cased
+//
+// example: FormatDate => formatDate
+func lowerFirst(str string) string {
+	r, size := utf8.DecodeRuneInString(str)
+	if r == utf8.RuneError {
+		return str
+	}
+
+	return string(unicode.ToLower(r)) + str[size:]
+}
+
+// sortedMapKeys returns the keys of given map, sorted numerically if they are integers and by their string representation otherwise
+func sortedMapKeys(val reflect.Value) []reflect.Value {
+	keys := val.MapKeys()
+
+	sort.Slice(keys, func(i, j int) bool {
+		switch keys[i].Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return keys[i].Int() < keys[j].Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return keys[i].Uint() < keys[j].Uint()
+		case reflect.Float32, reflect.Float64:
+			return keys[i].Float() < keys[j].Float()
+		}
+
+		return strValue(keys[i]) < strValue(keys[j])
+	})
+
+	return keys
+}
+
+// clamp returns given integer bounded by min and max
+func clamp(i int, min int, max int) int {
+	if i < min {
+		return min
+	}
+
+	if i > max {
+		return max
+	}
+
+	return i
+}
+
+// sign returns -1 if given integer is negative, and 1 otherwise
+func sign(i int) int {
+	if i < 0 {
+		return -1
+	}
+
+	return 1
+}
+
 // fileBase returns base file name
 //
 // example: /foo/bar/baz.png => baz
@@ -0,0 +1,425 @@
+package raymond
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/lexer"
+)
+
+// ValidateOptions configures template validation.
+type ValidateOptions struct {
+	// Helpers are names of helpers that are not registered yet, or of context functions called with parameters
+	Helpers []string
+
+	// Partials are names of partials that are not registered yet
+	Partials []string
+}
+
+// ValidationError is a problem found by template validation.
+type ValidationError struct {
+	Message string
+
+	// position in template source
+	Line   int
+	Column int
+	Pos    int
+}
+
+// Error implements the error interface.
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("Validation error on line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+// Validate checks the template against registered helpers and partials, without evaluating it.
+//
+// It reports calls with parameters to unknown helpers, blocks with parameters whose helper is unknown, references to
+// unknown partials, parent paths like `../foo` deeper than enclosing blocks, empty `{{else}}` blocks and duplicate hash
+// keys. Expressions without parameters are not reported, as they may be context lookups.
+//
+// When the template fails to be parsed because of unmatched blocks, all unmatched blocks are reported. Otherwise the
+// parsing error is returned.
+//
+// It returns nil if no problem was found.
+func (tpl *Template) Validate(opts ValidateOptions) []error {
+	if err := tpl.parse(); err != nil {
+		if errs := blockErrors(tpl.source); len(errs) > 0 {
+			return errs
+		}
+
+		return []error{err}
+	}
+
+	v := newValidator(tpl, opts)
+	tpl.program.Accept(v)
+
+	return v.errs
+}
+
+// Validate parses given template source and validates it against global helpers and partials, as Template.Validate()
+// does.
+//
+// Contrary to Parse(), it reports all unmatched blocks of a source that fails to be parsed because of them.
+func Validate(source string, opts ValidateOptions) []error {
+	return newTemplate(source).Validate(opts)
+}
+
+// validator implements the ast.Visitor interface to validate a template
+type validator struct {
+	tpl      *Template
+	helpers  map[string]bool
+	partials map[string]bool
+
+	// number of enclosing block programs
+	depth int
+
+	errs []error
+
+	// warnings are reported by Template.Warnings()
+	warnings []Warning
+}
+
+// newValidator instanciates a new validator
+func newValidator(tpl *Template, opts ValidateOptions) *validator {
+	v := &validator{
+		tpl:      tpl,
+		helpers:  make(map[string]bool),
+		partials: make(map[string]bool),
+	}
+
+	for _, name := range opts.Helpers {
+		v.helpers[name] = true
+	}
+
+	for _, name := range opts.Partials {
+		v.partials[name] = true
+	}
+
+	return v
+}
+
+// errorf records a validation error at given node position
+func (v *validator) errorf(node ast.Node, format string, args ...interface{}) {
+	loc := node.Location()
+
+	v.errs = append(v.errs, &ValidationError{
+		Message: fmt.Sprintf(format, args...),
+		Line:    loc.Line,
+		Column:  lexer.Column(v.tpl.source, loc.Pos),
+		Pos:     loc.Pos,
+	})
+}
+
+// checkHelper records an error if given expression calls an unknown helper with parameters
+func (v *validator) checkHelper(node ast.Node, expr *ast.Expression, kind string) {
+	name := expr.HelperName()
+	if (name == "") || ((len(expr.Params) == 0) && (expr.Hash == nil)) {
+		return
+	}
+
+	if v.helpers[name] || (v.tpl.findHelper(name) != zero) || (findHelper(name) != zero) {
+		return
+	}
+
+	v.errorf(node, "%s not found: %s", kind, name)
+}
+
+// openBlock is a block open tag found by blockErrors()
+type openBlock struct {
+	name  string
+	token lexer.Token
+}
+
+// blockErrors scans given template source, and returns an error for each block that is not closed, or that is closed
+// by a tag with another name, or that is closed but never opened
+func blockErrors(source string) []error {
+	var errs []error
+	var stack []openBlock
+
+	errorf := func(token lexer.Token, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{
+			Message: fmt.Sprintf(format, args...),
+			Line:    token.Line,
+			Column:  lexer.Column(source, token.Pos),
+			Pos:     token.Pos,
+		})
+	}
+
+	tokens, err := lexer.Tokenize(source)
+	if err != nil {
+		// lexer error is reported by parser
+		return nil
+	}
+
+	for i, token := range tokens {
+		switch token.Kind {
+		case lexer.TokenOpenBlock, lexer.TokenOpenInverse, lexer.TokenOpenRawBlock:
+			if name := blockName(tokens[i+1:]); name != "" {
+				stack = append(stack, openBlock{name, token})
+			}
+		case lexer.TokenOpenEndBlock, lexer.TokenOpenEndRawBlock:
+			name := blockName(tokens[i+1:])
+
+			// find matching open tag, so that blocks opened inside it are reported as not closed
+			match := len(stack) - 1
+			for (match >= 0) && (stack[match].name != name) {
+				match--
+			}
+
+			if match < 0 {
+				if len(stack) > 0 {
+					last := stack[len(stack)-1]
+					errorf(token, "Block %s opened on line %d is closed by %s", last.name, last.token.Line, name)
+					stack = stack[:len(stack)-1]
+				} else {
+					errorf(token, "Block %s is closed but never opened", name)
+				}
+				continue
+			}
+
+			for _, block := range stack[match+1:] {
+				errorf(block.token, "Block %s is never closed", block.name)
+			}
+
+			stack = stack[:match]
+		}
+	}
+
+	for _, block := range stack {
+		errorf(block.token, "Block %s is never closed", block.name)
+	}
+
+	return errs
+}
+
+// blockName returns the helper name of a block tag, given the tokens that follow its open token
+func blockName(tokens []lexer.Token) string {
+	var result string
+
+	// a path is a sequence of identifiers separated by separators
+	expectID := true
+
+	for _, token := range tokens {
+		switch {
+		case (token.Kind == lexer.TokenData) && (result == ""):
+			result += token.Val
+		case (token.Kind == lexer.TokenID) && expectID:
+			result += token.Val
+			expectID = false
+		case (token.Kind == lexer.TokenSep) && !expectID:
+			result += token.Val
+			expectID = true
+		default:
+			return result
+		}
+	}
+
+	return result
+}
+
+// builtinBlockParams stores the number of block params provided by builtin block helpers
+var builtinBlockParams = map[string]int{
+	"if":     0,
+	"unless": 0,
+	"with":   1,
+	"each":   2,
+}
+
+// checkBlockParams records a warning if given block of a builtin helper declares more block params than that helper
+// provides
+func (v *validator) checkBlockParams(node *ast.BlockStatement) {
+	if node.Program == nil {
+		return
+	}
+
+	name := node.Expression.HelperName()
+
+	n, ok := builtinBlockParams[name]
+	if !ok || !IsBuiltinHelper(name) || (v.tpl.findHelper(name) != zero) {
+		return
+	}
+
+	if declared := len(node.Program.BlockParams); declared > n {
+		v.warnf(node, WarnBlockParams, "Helper #%s provides %d block params, but %d are declared", name, n, declared)
+	}
+}
+
+// emptyProgram returns true if given program only contains whitespaces
+func emptyProgram(program *ast.Program) bool {
+	for _, node := range program.Body {
+		content, ok := node.(*ast.ContentStatement)
+		if !ok || (strings.TrimSpace(content.Value) != "") {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Visitor interface
+//
+
+// Statements
+
+// VisitProgram implements corresponding Visitor interface method
+func (v *validator) VisitProgram(node *ast.Program) interface{} {
+	for _, n := range node.Body {
+		n.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitMustache implements corresponding Visitor interface method
+func (v *validator) VisitMustache(node *ast.MustacheStatement) interface{} {
+	v.checkHelper(node, node.Expression, "Helper")
+
+	node.Expression.Accept(v)
+
+	return nil
+}
+
+// VisitBlock implements corresponding Visitor interface method
+func (v *validator) VisitBlock(node *ast.BlockStatement) interface{} {
+	v.checkHelper(node, node.Expression, "Block helper")
+
+	switch name := node.Expression.HelperName(); name {
+	case "if", "unless":
+		if len(node.Expression.Params) > 1 {
+			v.warnf(node, WarnConditionParams, "Helper #%s called with %d parameters, only the first one is evaluated", name, len(node.Expression.Params))
+		}
+	}
+
+	v.checkBlockParams(node)
+
+	node.Expression.Accept(v)
+
+	if node.Program != nil {
+		// block program may be evaluated with a new context
+		v.depth++
+		node.Program.Accept(v)
+		v.depth--
+	}
+
+	if node.Inverse != nil {
+		if emptyProgram(node.Inverse) {
+			v.errorf(node, "Empty else block in block: %s", node.Expression.Canonical())
+		}
+
+		node.Inverse.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitPartial implements corresponding Visitor interface method
+func (v *validator) VisitPartial(node *ast.PartialStatement) interface{} {
+	if name, ok := ast.HelperNameStr(node.Name); ok {
+		if !v.partials[name] && (v.tpl.findPartial(name) == nil) && (findPartial(name) == nil) {
+			v.errorf(node, "Partial not found: %s", name)
+		}
+	} else {
+		// dynamic partial
+		node.Name.Accept(v)
+	}
+
+	for _, param := range node.Params {
+		param.Accept(v)
+	}
+
+	if node.Hash != nil {
+		node.Hash.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitContent implements corresponding Visitor interface method
+func (v *validator) VisitContent(node *ast.ContentStatement) interface{} {
+	return nil
+}
+
+// VisitComment implements corresponding Visitor interface method
+func (v *validator) VisitComment(node *ast.CommentStatement) interface{} {
+	return nil
+}
+
+// Expressions
+
+// VisitExpression implements corresponding Visitor interface method
+func (v *validator) VisitExpression(node *ast.Expression) interface{} {
+	node.Path.Accept(v)
+
+	for _, param := range node.Params {
+		param.Accept(v)
+	}
+
+	if node.Hash != nil {
+		node.Hash.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitSubExpression implements corresponding Visitor interface method
+func (v *validator) VisitSubExpression(node *ast.SubExpression) interface{} {
+	v.checkHelper(node, node.Expression, "Helper")
+
+	node.Expression.Accept(v)
+
+	return nil
+}
+
+// VisitPath implements corresponding Visitor interface method
+func (v *validator) VisitPath(node *ast.PathExpression) interface{} {
+	if node.Depth > v.depth {
+		v.errorf(node, "Path %s goes up %d levels but it is only nested in %d blocks", node.Original, node.Depth, v.depth)
+	}
+
+	return nil
+}
+
+// Literals
+
+// VisitString implements corresponding Visitor interface method
+func (v *validator) VisitString(node *ast.StringLiteral) interface{} {
+	return nil
+}
+
+// VisitBoolean implements corresponding Visitor interface method
+func (v *validator) VisitBoolean(node *ast.BooleanLiteral) interface{} {
+	return nil
+}
+
+// VisitNumber implements corresponding Visitor interface method
+func (v *validator) VisitNumber(node *ast.NumberLiteral) interface{} {
+	return nil
+}
+
+// Miscellaneous
+
+// VisitHash implements corresponding Visitor interface method
+func (v *validator) VisitHash(node *ast.Hash) interface{} {
+	seen := make(map[string]bool, len(node.Pairs))
+
+	for _, pair := range node.Pairs {
+		if seen[pair.Key] {
+			v.errorf(pair, "Duplicate hash key: %s", pair.Key)
+			v.warnf(pair, WarnDuplicateHashKey, "Duplicate hash key: %s", pair.Key)
+		}
+		seen[pair.Key] = true
+
+		pair.Accept(v)
+	}
+
+	return nil
+}
+
+// VisitHashPair implements corresponding Visitor interface method
+func (v *validator) VisitHashPair(node *ast.HashPair) interface{} {
+	node.Val.Accept(v)
+
+	return nil
+}
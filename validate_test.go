@@ -0,0 +1,171 @@
+package raymond
+
+import (
+	"fmt"
+	"testing"
+)
+
+var validateTests = []struct {
+	name   string
+	input  string
+	opts   ValidateOptions
+	errors []string
+}{
+	{
+		"valid template",
+		`{{foo}} {{#each items}}{{upper name}} {{../title}}{{else}}none{{/each}} {{> item}} {{#if (equal a b)}}x{{/if}}`,
+		ValidateOptions{},
+		nil,
+	},
+	{
+		"expressions without parameters may be context lookups",
+		`{{unknown}} {{#unknown}}{{/unknown}}`,
+		ValidateOptions{},
+		nil,
+	},
+	{
+		"unknown helper",
+		"foo\n  {{unknown bar}}",
+		ValidateOptions{},
+		[]string{"Validation error on line 2, column 3: Helper not found: unknown"},
+	},
+	{
+		"unknown helper with hash",
+		`{{unknown bar=baz}}`,
+		ValidateOptions{},
+		[]string{"Validation error on line 1, column 1: Helper not found: unknown"},
+	},
+	{
+		"unknown helper listed in options",
+		`{{unknown bar}} {{> later}}`,
+		ValidateOptions{Helpers: []string{"unknown"}, Partials: []string{"later"}},
+		nil,
+	},
+	{
+		"unknown helper in a sub-expression",
+		`{{#if (unknown foo)}}bar{{/if}}`,
+		ValidateOptions{},
+		[]string{"Validation error on line 1, column 7: Helper not found: unknown"},
+	},
+	{
+		"unknown block helper",
+		`{{#unknown foo}}bar{{/unknown}}`,
+		ValidateOptions{},
+		[]string{"Validation error on line 1, column 1: Block helper not found: unknown"},
+	},
+	{
+		"unknown partial",
+		`{{> item}} {{> unknown}} {{> (dynamic)}}`,
+		ValidateOptions{},
+		[]string{"Validation error on line 1, column 12: Partial not found: unknown"},
+	},
+	{
+		"parent path beyond depth",
+		`{{../foo}} {{#each items}}{{../bar}} {{../../baz}}{{/each}} {{#each ../items}}{{/each}}`,
+		ValidateOptions{},
+		[]string{
+			"Validation error on line 1, column 3: Path ../foo goes up 1 levels but it is only nested in 0 blocks",
+			"Validation error on line 1, column 40: Path ../../baz goes up 2 levels but it is only nested in 1 blocks",
+			"Validation error on line 1, column 69: Path ../items goes up 1 levels but it is only nested in 0 blocks",
+		},
+	},
+	{
+		"empty else block",
+		"{{#if foo}}bar{{else}}{{/if}} {{#if foo}}bar{{else}}  \n{{/if}} {{#if foo}}bar{{else if baz}}{{/if}}",
+		ValidateOptions{},
+		[]string{
+			"Validation error on line 1, column 1: Empty else block in block: if",
+			"Validation error on line 1, column 31: Empty else block in block: if",
+		},
+	},
+	{
+		"multiple problems",
+		"{{#each items}}\n  {{format date}} {{../../title}}\n{{else}}\n{{/each}}\n{{> missing}} {{upper x=1 y=2}}",
+		ValidateOptions{},
+		[]string{
+			"Validation error on line 2, column 3: Helper not found: format",
+			"Validation error on line 2, column 21: Path ../../title goes up 2 levels but it is only nested in 1 blocks",
+			"Validation error on line 1, column 1: Empty else block in block: each",
+			"Validation error on line 5, column 1: Partial not found: missing",
+		},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range validateTests {
+		tpl := MustParse(test.input)
+		tpl.RegisterHelper("upper", func(str string) string { return str })
+		tpl.RegisterPartial("item", "{{name}}")
+
+		var errs []string
+		for _, err := range tpl.Validate(test.opts) {
+			errs = append(errs, err.Error())
+		}
+
+		if fmt.Sprint(errs) != fmt.Sprint(test.errors) {
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\nexpected\n\t%q\ngot\n\t%q", test.name, test.input, test.errors, errs)
+		}
+	}
+}
+
+var validateSourceTests = []struct {
+	name   string
+	input  string
+	errors []string
+}{
+	{
+		"valid source",
+		`{{#if foo}}{{#each items}}{{.}}{{/each}}{{/if}}`,
+		nil,
+	},
+	{
+		"unmatched blocks",
+		"{{#if a}}\n  {{#each b}}\n{{/if}}{{/with}}\n{{#unless c}}{{^foo.bar}}{{/foo.bar}}",
+		[]string{
+			"Validation error on line 2, column 3: Block each is never closed",
+			"Validation error on line 3, column 8: Block with is closed but never opened",
+			"Validation error on line 4, column 1: Block unless is never closed",
+		},
+	},
+	{
+		"mismatched block",
+		`{{#if a}}{{/each}}`,
+		[]string{"Validation error on line 1, column 10: Block if opened on line 1 is closed by each"},
+	},
+	{
+		"duplicate hash keys",
+		`{{upper a=1 b=2 a=3}}`,
+		[]string{"Parse error on line 1, column 17:\nDuplicate hash key: a\nNode: a=Number{Value:3, Pos:18}"},
+	},
+	{
+		"parse error",
+		`{{foo}`,
+		[]string{"Parse error on line 1, column 6:\nLexer error\nToken: Error{\"Unexpected character in expression: '}'\"}"},
+	},
+}
+
+func TestValidateSource(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range validateSourceTests {
+		var errs []string
+		for _, err := range Validate(test.input, ValidateOptions{}) {
+			errs = append(errs, err.Error())
+		}
+
+		if fmt.Sprint(errs) != fmt.Sprint(test.errors) {
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\nexpected\n\t%q\ngot\n\t%q", test.name, test.input, test.errors, errs)
+		}
+	}
+}
+
+func TestValidateDuplicateHashKey(t *testing.T) {
+	t.Parallel()
+
+	errs := duplicateHashKeyTemplate("{{upper a=1 b=2}}").Validate(ValidateOptions{})
+	if fmt.Sprint(errs) != "[Validation error on line 1, column 13: Duplicate hash key: a]" {
+		t.Errorf("Duplicate hash keys of a program built by hand must be reported: %v", errs)
+	}
+}
@@ -0,0 +1,132 @@
+package raymond
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/lexer"
+)
+
+// WarningCode identifies the kind of a template warning.
+type WarningCode int
+
+const (
+	// WarnDuplicateHashKey is reported when a hash key is repeated in an expression. Only the last value is used.
+	//
+	// Template sources with duplicate hash keys fail to be parsed, so it is only reported for templates parsed with
+	// ParseLenient(), and for programs built by hand.
+	WarnDuplicateHashKey WarningCode = iota + 1
+
+	// WarnConditionParams is reported when an #if or #unless block has more than one parameter. Only the first one is
+	// evaluated.
+	WarnConditionParams
+
+	// WarnCommentMustache is reported when a {{! comment contains a mustache, as the comment ends at the first `}}`.
+	WarnCommentMustache
+
+	// WarnUselessStrip is reported when a `~` whitespace control character has no whitespace to strip.
+	WarnUselessStrip
+
+	// WarnBlockParams is reported when a block of a builtin helper declares more block params than that helper
+	// provides. Extra block params are never set.
+	WarnBlockParams
+)
+
+// Warning is a construct that parses fine but that is probably a mistake.
+type Warning struct {
+	Code    WarningCode
+	Message string
+
+	// position in template source
+	Line   int
+	Column int
+	Pos    int
+}
+
+// String returns a string representation of the warning.
+func (w Warning) String() string {
+	return fmt.Sprintf("Warning on line %d, column %d: %s", w.Line, w.Column, w.Message)
+}
+
+// Warnings returns the warnings found in template source, sorted by position.
+//
+// Warnings are computed on first call. They don't prevent the template from being evaluated.
+func (tpl *Template) Warnings() []Warning {
+	tpl.lintOnce.Do(func() {
+		if err := tpl.parse(); err == nil {
+			tpl.warnings = tpl.lint()
+		}
+	})
+
+	return tpl.warnings
+}
+
+// lint returns the warnings found in template source and AST
+func (tpl *Template) lint() []Warning {
+	v := newValidator(tpl, ValidateOptions{})
+	tpl.program.Accept(v)
+
+	result := append(v.warnings, lintTokens(tpl.source)...)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Pos < result[j].Pos
+	})
+
+	return result
+}
+
+// lintTokens returns the warnings found in the tokens of given template source
+func lintTokens(source string) []Warning {
+	var result []Warning
+
+	warnf := func(token lexer.Token, pos int, code WarningCode, format string, args ...interface{}) {
+		result = append(result, Warning{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+			Line:    token.Line + strings.Count(source[token.Pos:pos], "\n"),
+			Column:  lexer.Column(source, pos),
+			Pos:     pos,
+		})
+	}
+
+	for _, token := range lexer.Collect(source) {
+		switch token.Kind {
+		case lexer.TokenContent, lexer.TokenString, lexer.TokenEOF, lexer.TokenError:
+			continue
+		case lexer.TokenComment:
+			if !strings.Contains(token.Val, "!--") && strings.Contains(token.Val[2:], "{{") {
+				warnf(token, token.Pos, WarnCommentMustache, "Comment contains a mustache, use {{!-- --}} to comment it out")
+			}
+		}
+
+		if strings.HasPrefix(token.Val, "{{~") && ((token.Pos == 0) || !isWhitespace(source[token.Pos-1])) {
+			warnf(token, token.Pos, WarnUselessStrip, "Nothing to strip before %s", token.Val[:3])
+		}
+
+		if end := token.Pos + len(token.Val); strings.HasSuffix(token.Val, "~}}") && ((end == len(source)) || !isWhitespace(source[end])) {
+			warnf(token, end-3, WarnUselessStrip, "Nothing to strip after ~}}")
+		}
+	}
+
+	return result
+}
+
+// isWhitespace returns true if given character can be stripped by whitespace control
+func isWhitespace(c byte) bool {
+	return strings.IndexByte("\t\n\f\r ", c) >= 0
+}
+
+// warnf records a warning at given node position
+func (v *validator) warnf(node ast.Node, code WarningCode, format string, args ...interface{}) {
+	loc := node.Location()
+
+	v.warnings = append(v.warnings, Warning{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Line:    loc.Line,
+		Column:  lexer.Column(v.tpl.source, loc.Pos),
+		Pos:     loc.Pos,
+	})
+}
@@ -0,0 +1,111 @@
+package raymond
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aymerick/raymond/ast"
+)
+
+var warningTests = []struct {
+	name     string
+	input    string
+	warnings []string
+}{
+	{
+		"no warning",
+		"foo {{~#if foo}}\n  {{bar a=1 b=2}}\n{{~else~}}\n  {{! comment }}{{!-- {{baz}} --}}\n{{~/if}}",
+		nil,
+	},
+	{
+		"#if with several params",
+		"{{#if foo bar}}baz{{/if}}\n{{#unless foo bar}}baz{{else if foo bar}}{{/unless}}",
+		[]string{
+			"Warning on line 1, column 1: Helper #if called with 2 parameters, only the first one is evaluated",
+			"Warning on line 2, column 1: Helper #unless called with 2 parameters, only the first one is evaluated",
+			"Warning on line 2, column 23: Helper #if called with 2 parameters, only the first one is evaluated",
+		},
+	},
+	{
+		"mustache in a comment",
+		"foo\n{{! bar {{baz}} }}",
+		[]string{"Warning on line 2, column 1: Comment contains a mustache, use {{!-- --}} to comment it out"},
+	},
+	{
+		"nothing to strip",
+		"{{~foo}}bar{{~baz~}}\n{{#if a~}}{{/if~}}",
+		[]string{
+			"Warning on line 1, column 1: Nothing to strip before {{~",
+			"Warning on line 1, column 12: Nothing to strip before {{~",
+			"Warning on line 2, column 8: Nothing to strip after ~}}",
+			"Warning on line 2, column 16: Nothing to strip after ~}}",
+		},
+	},
+	{
+		"duplicate hash keys",
+		"{{foo a=1 b=2 a=3}}\n{{> bar a=1 a=2}}{{foo (baz b=1 c=2 b=3) d=4}}",
+		[]string{
+			"Warning on line 1, column 15: Duplicate hash key: a",
+			"Warning on line 2, column 13: Duplicate hash key: a",
+			"Warning on line 2, column 37: Duplicate hash key: b",
+		},
+	},
+	{
+		"too many block params",
+		"{{#each items as |item idx|}}{{/each}}{{#each items as |item idx extra|}}{{/each}}\n{{#with foo as |a|}}{{/with}}{{#with foo as |a b|}}{{/with}}{{#if foo as |a|}}{{/if}}",
+		[]string{
+			"Warning on line 1, column 39: Helper #each provides 2 block params, but 3 are declared",
+			"Warning on line 2, column 30: Helper #with provides 1 block params, but 2 are declared",
+			"Warning on line 2, column 61: Helper #if provides 0 block params, but 1 are declared",
+		},
+	},
+}
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range warningTests {
+		tpl, err := ParseLenient(test.input)
+		if err != nil {
+			t.Fatalf("Test '%s' failed: %s", test.name, err)
+		}
+
+		var warnings []string
+		for _, warning := range tpl.Warnings() {
+			warnings = append(warnings, warning.String())
+		}
+
+		if fmt.Sprint(warnings) != fmt.Sprint(test.warnings) {
+			t.Errorf("Test '%s' failed\ninput:\n\t%q\nexpected\n\t%q\ngot\n\t%q", test.name, test.input, test.warnings, warnings)
+		}
+	}
+}
+
+func TestWarningsCode(t *testing.T) {
+	t.Parallel()
+
+	tpl := duplicateHashKeyTemplate("{{foo a=1 b=2}}")
+
+	warnings := tpl.Warnings()
+	if (len(warnings) != 1) || (warnings[0].Code != WarnDuplicateHashKey) || (warnings[0].Pos != 10) || (warnings[0].Message != "Duplicate hash key: a") {
+		t.Fatalf("Unexpected warnings: %v", warnings)
+	}
+
+	// warnings don't prevent evaluation
+	if output := tpl.MustExec(nil); output != "" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+}
+
+// duplicateHashKeyTemplate returns a template of given source with a single mustache, whose last hash key is renamed as
+// its first one
+//
+// The parser rejects duplicate hash keys, but a program built by hand may contain some.
+func duplicateHashKeyTemplate(source string) *Template {
+	tpl := MustParse(source)
+
+	pairs := tpl.program.Body[0].(*ast.MustacheStatement).Expression.Hash.Pairs
+	pairs[len(pairs)-1].Key = pairs[0].Key
+
+	return NewParsedTemplate(source, tpl.program)
+}